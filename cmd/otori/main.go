@@ -1,7 +1,12 @@
 package main
 
-import "github.com/otori-lab/otori-cli/internal/commands"
+import (
+	"os"
+
+	"github.com/otori-lab/otori-cli/internal/commands"
+)
 
 func main() {
-	commands.RootCmd.Execute()
+	// Errors are printed by Execute, only the exit code is left to set
+	os.Exit(commands.Execute())
 }
@@ -0,0 +1,153 @@
+package alerts
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/events"
+)
+
+// Alert is raised when an event (or a series of events) matches a rule
+type Alert struct {
+	Rule        string       `json:"rule"`
+	Description string       `json:"description,omitempty"`
+	Severity    string       `json:"severity,omitempty"`
+	Profile     string       `json:"profile,omitempty"`
+	Key         string       `json:"key"`
+	Time        time.Time    `json:"time"`
+	Count       int          `json:"count"`                // matching events that led to the alert
+	Suppressed  int          `json:"suppressed,omitempty"` // identical alerts throttled since the last one
+	Event       events.Event `json:"event"`                // last matching event
+
+	notify []string
+}
+
+// Summary returns a one-line description of the alert
+func (a Alert) Summary() string {
+	var parts []string
+	if a.Profile != "" {
+		parts = append(parts, "profile="+a.Profile)
+	}
+	if a.Event.SrcIP != "" {
+		parts = append(parts, "ip="+a.Event.SrcIP)
+	}
+	if a.Event.Country != "" {
+		parts = append(parts, "country="+a.Event.Country)
+	}
+	if a.Event.Username != "" {
+		parts = append(parts, "user="+a.Event.Username)
+	}
+	if a.Event.Input != "" {
+		parts = append(parts, fmt.Sprintf("input=%q", a.Event.Input))
+	}
+	if a.Event.URL != "" {
+		parts = append(parts, "url="+a.Event.URL)
+	}
+	if a.Count > 1 {
+		parts = append(parts, fmt.Sprintf("count=%d", a.Count))
+	}
+	if a.Suppressed > 0 {
+		parts = append(parts, fmt.Sprintf("suppressed=%d", a.Suppressed))
+	}
+	return fmt.Sprintf("[%s] %s", a.Rule, strings.Join(parts, " "))
+}
+
+// Engine evaluates rules over a stream of events.
+// Time is taken from the events themselves so that recorded logs replay identically.
+type Engine struct {
+	rules      []Rule
+	windows    map[string][]time.Time
+	lastSent   map[string]time.Time
+	suppressed map[string]int
+}
+
+// NewEngine creates an engine for the rules of a rules file
+func NewEngine(rf *RulesFile) *Engine {
+	return &Engine{
+		rules:      rf.Rules,
+		windows:    make(map[string][]time.Time),
+		lastSent:   make(map[string]time.Time),
+		suppressed: make(map[string]int),
+	}
+}
+
+// Process evaluates an event against every rule and returns the alerts to send
+func (e *Engine) Process(ev events.Event) []Alert {
+	now := ev.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var alerts []Alert
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if !rule.matches(ev) {
+			continue
+		}
+
+		count := 1
+		if rule.Threshold != nil {
+			windowKey := rule.Name + "|" + ev.Profile + "|" + EventField(ev, rule.Threshold.By)
+			count = e.record(windowKey, now, time.Duration(rule.Threshold.Window))
+			if count < rule.Threshold.Count {
+				continue
+			}
+			// Start a new window once the threshold is reached
+			delete(e.windows, windowKey)
+		}
+
+		key := dedupKey(rule, ev)
+		if last, ok := e.lastSent[key]; ok && rule.Throttle > 0 && now.Sub(last) < time.Duration(rule.Throttle) {
+			e.suppressed[key]++
+			continue
+		}
+
+		alerts = append(alerts, Alert{
+			Rule:        rule.Name,
+			Description: rule.Description,
+			Severity:    rule.Severity,
+			Profile:     ev.Profile,
+			Key:         key,
+			Time:        now,
+			Count:       count,
+			Suppressed:  e.suppressed[key],
+			Event:       ev,
+			notify:      rule.Notify,
+		})
+		e.lastSent[key] = now
+		delete(e.suppressed, key)
+	}
+
+	return alerts
+}
+
+// record adds a timestamp to a sliding window and returns its size
+func (e *Engine) record(key string, now time.Time, window time.Duration) int {
+	times := append(e.windows[key], now)
+	start := 0
+	for start < len(times) && now.Sub(times[start]) > window {
+		start++
+	}
+	times = times[start:]
+	e.windows[key] = times
+	return len(times)
+}
+
+// dedupKey identifies identical alerts for throttling
+func dedupKey(rule *Rule, ev events.Event) string {
+	fields := rule.DedupBy
+	if len(fields) == 0 {
+		if rule.Threshold != nil {
+			fields = []string{"profile", rule.Threshold.By}
+		} else {
+			fields = []string{"profile", "src_ip"}
+		}
+	}
+
+	parts := []string{rule.Name}
+	for _, f := range fields {
+		parts = append(parts, EventField(ev, f))
+	}
+	return strings.Join(parts, "|")
+}
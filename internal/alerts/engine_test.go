@@ -0,0 +1,185 @@
+package alerts_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/alerts"
+	"github.com/otori-lab/otori-cli/internal/events"
+)
+
+// loadRules reads rules from YAML
+func loadRules(t *testing.T, rules string) *alerts.RulesFile {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "alerts.yaml")
+	if err := os.WriteFile(path, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rf, err := alerts.LoadRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rf
+}
+
+// failedLogin is a failed login at a number of seconds from the start of the test
+func failedLogin(seconds int, ip, username string) events.Event {
+	return events.Event{
+		EventID:   events.EventLoginFailed,
+		Timestamp: time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC).Add(time.Duration(seconds) * time.Second),
+		Profile:   "web",
+		SrcIP:     ip,
+		Username:  username,
+	}
+}
+
+func TestEngineThreshold(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		rule   string
+		events []events.Event
+		alerts []int // events raising an alert
+		counts []int // counts of these alerts
+	}{
+		{
+			name: "fires at the count",
+			rule: "threshold: {count: 3, window: 1m}",
+			events: []events.Event{
+				failedLogin(0, "198.51.100.1", "root"),
+				failedLogin(10, "198.51.100.1", "root"),
+				failedLogin(20, "198.51.100.1", "root"),
+				failedLogin(30, "198.51.100.1", "root"),
+			},
+			alerts: []int{2},
+			counts: []int{3},
+		},
+		{
+			name: "new window after an alert",
+			rule: "threshold: {count: 2, window: 1m}",
+			events: []events.Event{
+				failedLogin(0, "198.51.100.1", "root"),
+				failedLogin(1, "198.51.100.1", "root"),
+				failedLogin(2, "198.51.100.1", "root"),
+				failedLogin(3, "198.51.100.1", "root"),
+			},
+			alerts: []int{1, 3},
+			counts: []int{2, 2},
+		},
+		{
+			name: "old events leave the window",
+			rule: "threshold: {count: 3, window: 1m}",
+			events: []events.Event{
+				failedLogin(0, "198.51.100.1", "root"),
+				failedLogin(40, "198.51.100.1", "root"),
+				failedLogin(80, "198.51.100.1", "root"),
+				failedLogin(90, "198.51.100.1", "root"),
+			},
+			alerts: []int{3},
+			counts: []int{3},
+		},
+		{
+			name: "the window end is included",
+			rule: "threshold: {count: 2, window: 1m}",
+			events: []events.Event{
+				failedLogin(0, "198.51.100.1", "root"),
+				failedLogin(60, "198.51.100.1", "root"),
+			},
+			alerts: []int{1},
+			counts: []int{2},
+		},
+		{
+			name: "counted by source IP by default",
+			rule: "threshold: {count: 2, window: 1m}",
+			events: []events.Event{
+				failedLogin(0, "198.51.100.1", "root"),
+				failedLogin(1, "198.51.100.2", "root"),
+				failedLogin(2, "198.51.100.3", "root"),
+				failedLogin(3, "198.51.100.2", "admin"),
+			},
+			alerts: []int{3},
+			counts: []int{2},
+		},
+		{
+			name: "counted by username",
+			rule: "threshold: {count: 2, window: 1m, by: username}",
+			events: []events.Event{
+				failedLogin(0, "198.51.100.1", "root"),
+				failedLogin(1, "198.51.100.2", "admin"),
+				failedLogin(2, "198.51.100.3", "root"),
+			},
+			alerts: []int{2},
+			counts: []int{2},
+		},
+		{
+			name: "throttled",
+			rule: "threshold: {count: 1, window: 1m}\n    throttle: 10m",
+			events: []events.Event{
+				failedLogin(0, "198.51.100.1", "root"),
+				failedLogin(60, "198.51.100.1", "root"),
+				failedLogin(120, "198.51.100.2", "root"),
+				failedLogin(600, "198.51.100.1", "root"),
+			},
+			alerts: []int{0, 2, 3},
+			counts: []int{1, 1, 1},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := alerts.NewEngine(loadRules(t, "rules:\n  - name: bruteforce\n    events: [login_failed]\n    "+tt.rule+"\n"))
+
+			var raised, counts []int
+			for i, ev := range tt.events {
+				for _, alert := range engine.Process(ev) {
+					raised = append(raised, i)
+					counts = append(counts, alert.Count)
+				}
+			}
+			if !slices.Equal(raised, tt.alerts) || !slices.Equal(counts, tt.counts) {
+				t.Errorf("alerts on events %v with counts %v, want %v with %v", raised, counts, tt.alerts, tt.counts)
+			}
+		})
+	}
+}
+
+func TestEngineSuppressed(t *testing.T) {
+	engine := alerts.NewEngine(loadRules(t, `
+rules:
+  - name: root-login
+    events: [login_failed]
+    match:
+      username: root
+    throttle: 10m
+`))
+
+	var sent []alerts.Alert
+	for _, ev := range []events.Event{
+		failedLogin(0, "198.51.100.1", "root"),
+		failedLogin(1, "198.51.100.1", "admin"),
+		failedLogin(2, "198.51.100.1", "root"),
+		failedLogin(3, "198.51.100.1", "root"),
+		failedLogin(601, "198.51.100.1", "root"),
+	} {
+		sent = append(sent, engine.Process(ev)...)
+	}
+	if len(sent) != 2 || sent[0].Suppressed != 0 || sent[1].Suppressed != 2 {
+		t.Errorf("alerts: %+v", sent)
+	}
+}
+
+func TestExampleRules(t *testing.T) {
+	rf := loadRules(t, alerts.ExampleRules)
+	engine := alerts.NewEngine(rf)
+
+	// 20 failed logins or more, as described
+	for i := 0; i < 19; i++ {
+		if raised := engine.Process(failedLogin(i, "198.51.100.1", "root")); len(raised) != 0 {
+			t.Fatalf("alert after %d failed logins: %+v", i+1, raised)
+		}
+	}
+	if raised := engine.Process(failedLogin(19, "198.51.100.1", "root")); len(raised) != 1 || raised[0].Rule != "bruteforce" {
+		t.Errorf("20th failed login: %+v", raised)
+	}
+}
@@ -0,0 +1,332 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultNotifyTimeout bounds the delivery of an alert by a notifier without timeout
+const defaultNotifyTimeout = 10 * time.Second
+
+// queueSize is the number of alerts waiting for the notifiers (see Worker)
+const queueSize = 64
+
+// NotifierConfig describes a notification channel
+type NotifierConfig struct {
+	Name    string   `yaml:"name"`
+	Type    string   `yaml:"type"`              // webhook, smtp, command, desktop
+	Timeout Duration `yaml:"timeout,omitempty"` // max delivery time of an alert (default: 10s)
+
+	// webhook
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// smtp
+	Host     string   `yaml:"host,omitempty"`
+	Port     int      `yaml:"port,omitempty"`
+	Username string   `yaml:"username,omitempty"`
+	Password string   `yaml:"password,omitempty"`
+	From     string   `yaml:"from,omitempty"`
+	To       []string `yaml:"to,omitempty"`
+
+	// command (the alert is passed as JSON on stdin)
+	Command []string `yaml:"command,omitempty"`
+}
+
+// Notifier delivers alerts to a destination, giving up when ctx is done
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// Dispatcher routes alerts to the notifiers selected by each rule
+type Dispatcher struct {
+	notifiers map[string]Notifier
+	timeouts  map[string]time.Duration
+	order     []string
+}
+
+// NewDispatcher creates the notifiers declared in the rules file
+func NewDispatcher(cfgs []NotifierConfig) (*Dispatcher, error) {
+	d := &Dispatcher{notifiers: make(map[string]Notifier), timeouts: make(map[string]time.Duration)}
+
+	for _, c := range cfgs {
+		var n Notifier
+		switch strings.ToLower(c.Type) {
+		case "webhook":
+			if c.URL == "" {
+				return nil, fmt.Errorf("notifier '%s': url is required", c.Name)
+			}
+			n = &webhookNotifier{cfg: c, client: &http.Client{}}
+		case "smtp":
+			if c.Host == "" || c.From == "" || len(c.To) == 0 {
+				return nil, fmt.Errorf("notifier '%s': host, from and to are required", c.Name)
+			}
+			n = &smtpNotifier{cfg: c}
+		case "command":
+			if len(c.Command) == 0 {
+				return nil, fmt.Errorf("notifier '%s': command is required", c.Name)
+			}
+			n = &commandNotifier{cfg: c}
+		case "desktop":
+			n = &desktopNotifier{}
+		default:
+			return nil, fmt.Errorf("notifier '%s': unsupported type '%s' (use: webhook, smtp, command, desktop)", c.Name, c.Type)
+		}
+		d.notifiers[c.Name] = n
+		d.timeouts[c.Name] = defaultNotifyTimeout
+		if c.Timeout > 0 {
+			d.timeouts[c.Name] = time.Duration(c.Timeout)
+		}
+		d.order = append(d.order, c.Name)
+	}
+
+	return d, nil
+}
+
+// Send delivers an alert to its notifiers (all notifiers if the rule names none).
+// Each notifier has its timeout: a hung server or command only delays the alert.
+func (d *Dispatcher) Send(a Alert) []error {
+	targets := a.notify
+	if len(targets) == 0 {
+		targets = d.order
+	}
+
+	var errs []error
+	for _, name := range targets {
+		n, ok := d.notifiers[name]
+		if !ok {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), d.timeouts[name])
+		err := n.Notify(ctx, a)
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("no answer after %s: %w", d.timeouts[name], err)
+		}
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("notifier '%s': %w", name, err))
+		}
+	}
+	return errs
+}
+
+// Worker sends alerts in the background, so that slow notifiers never hold
+// up the evaluation of events. Its queue is bounded: when the notifiers
+// cannot keep up, new alerts are dropped.
+type Worker struct {
+	dispatcher *Dispatcher
+	queue      chan Alert
+	onError    func(error)
+	wg         sync.WaitGroup
+}
+
+// StartWorker starts a worker sending alerts with the dispatcher; onError
+// receives the delivery errors
+func (d *Dispatcher) StartWorker(onError func(error)) *Worker {
+	w := &Worker{dispatcher: d, queue: make(chan Alert, queueSize), onError: onError}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for a := range w.queue {
+			for _, err := range w.dispatcher.Send(a) {
+				w.onError(err)
+			}
+		}
+	}()
+	return w
+}
+
+// Enqueue queues an alert, false if the queue is full and the alert dropped
+func (w *Worker) Enqueue(a Alert) bool {
+	select {
+	case w.queue <- a:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close sends the queued alerts and stops the worker
+func (w *Worker) Close() {
+	close(w.queue)
+	w.wg.Wait()
+}
+
+// webhookNotifier POSTs the alert as JSON
+type webhookNotifier struct {
+	cfg    NotifierConfig
+	client *http.Client
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, a Alert) error {
+	payload := struct {
+		Alert
+		Text string `json:"text"` // Slack/Mattermost compatible
+	}{a, a.Summary()}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// smtpNotifier sends the alert by email
+type smtpNotifier struct {
+	cfg NotifierConfig
+}
+
+func (n *smtpNotifier) Notify(ctx context.Context, a Alert) error {
+	port := n.cfg.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+
+	details, _ := json.MarshalIndent(a, "", "  ")
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: [otori] %s\r\n", a.Rule)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(a.Summary() + "\r\n\r\n")
+	msg.Write(details)
+	msg.WriteString("\r\n")
+
+	return sendMail(ctx, addr, n.cfg.Host, auth, n.cfg.From, n.cfg.To, []byte(msg.String()))
+}
+
+// sendMail is smtp.SendMail with a connection bounded by ctx: the dial and
+// every exchange with the server fail once ctx is done
+func sendMail(ctx context.Context, addr, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("server does not support AUTH")
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// commandNotifier runs a local command with the alert as JSON on stdin
+type commandNotifier struct {
+	cfg NotifierConfig
+}
+
+func (n *commandNotifier) Notify(ctx context.Context, a Alert) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, n.cfg.Command[0], n.cfg.Command[1:]...)
+	cmd.WaitDelay = time.Second // children keeping stdout open
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"OTORI_ALERT_RULE="+a.Rule,
+		"OTORI_ALERT_PROFILE="+a.Profile,
+		"OTORI_ALERT_SRC_IP="+a.Event.SrcIP,
+		"OTORI_ALERT_SUMMARY="+a.Summary(),
+	)
+	return cmd.Run()
+}
+
+// desktopNotifier shows a desktop notification (notify-send or osascript)
+type desktopNotifier struct{}
+
+func (n *desktopNotifier) Notify(ctx context.Context, a Alert) error {
+	title := "Otori: " + a.Rule
+	body := a.Summary()
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", body, title)
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	default:
+		cmd = exec.CommandContext(ctx, "notify-send", title, body)
+	}
+	return cmd.Run()
+}
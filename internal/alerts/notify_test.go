@@ -0,0 +1,85 @@
+package alerts_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/alerts"
+)
+
+// dispatcher creates the notifiers of a rules file
+func dispatcher(t *testing.T, rules string) *alerts.Dispatcher {
+	t.Helper()
+
+	d, err := alerts.NewDispatcher(loadRules(t, rules).Notifiers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestNotifierTimeout(t *testing.T) {
+	// An SMTP server accepting connections and never answering
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	for name, notifier := range map[string]string{
+		"smtp":    "type: smtp\n    host: " + host + "\n    port: " + port + "\n    from: otori@example.com\n    to: [soc@example.com]",
+		"command": "type: command\n    command: [sleep, '30']",
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := dispatcher(t, "notifiers:\n  - name: hung\n    timeout: 200ms\n    "+notifier+"\n")
+
+			start := time.Now()
+			errs := d.Send(alerts.Alert{Rule: "bruteforce"})
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("notifier gave up after %s", elapsed)
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), "no answer after 200ms") {
+				t.Errorf("errors: %v", errs)
+			}
+		})
+	}
+}
+
+func TestWorker(t *testing.T) {
+	d := dispatcher(t, "notifiers:\n  - name: slow\n    type: command\n    timeout: 10ms\n    command: [sleep, '30']\n")
+
+	var errs []error
+	worker := d.StartWorker(func(err error) { errs = append(errs, err) })
+
+	// A slow notifier never blocks the caller, the alerts it cannot take are dropped
+	start := time.Now()
+	queued := 0
+	for i := 0; i < 100; i++ {
+		if worker.Enqueue(alerts.Alert{Rule: "bruteforce"}) {
+			queued++
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("queueing took %s", elapsed)
+	}
+	if queued == 100 || queued == 0 {
+		t.Errorf("%d alerts of 100 queued", queued)
+	}
+
+	worker.Close()
+	if len(errs) != queued {
+		t.Errorf("%d errors for %d queued alerts", len(errs), queued)
+	}
+}
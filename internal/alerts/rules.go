@@ -0,0 +1,295 @@
+package alerts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/events"
+	"gopkg.in/yaml.v3"
+)
+
+// RulesFile is the alerting configuration (~/.otori/alerts.yaml)
+type RulesFile struct {
+	Notifiers []NotifierConfig `yaml:"notifiers"`
+	Rules     []Rule           `yaml:"rules"`
+}
+
+// Rule describes when an alert is raised (see ExampleRules for the YAML format)
+type Rule struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description,omitempty"`
+	Events      []string          `yaml:"events"`              // Cowrie event IDs (empty = any)
+	Match       map[string]string `yaml:"match,omitempty"`     // field: exact value
+	Regex       map[string]string `yaml:"regex,omitempty"`     // field: regular expression
+	Threshold   *Threshold        `yaml:"threshold,omitempty"` // fire after N matches in a window
+	Notify      []string          `yaml:"notify"`              // notifier names (empty = all)
+	Throttle    Duration          `yaml:"throttle,omitempty"`  // min delay between identical alerts
+	DedupBy     []string          `yaml:"dedup_by,omitempty"`  // fields identifying identical alerts
+	Severity    string            `yaml:"severity,omitempty"`
+
+	compiled map[string]*regexp.Regexp
+}
+
+// Threshold fires a rule on the Count-th matching event within Window (at least
+// Count events), counted separately for each value of By (default: src_ip)
+type Threshold struct {
+	Count  int      `yaml:"count"`
+	Window Duration `yaml:"window"`
+	By     string   `yaml:"by,omitempty"`
+}
+
+// Duration is a time.Duration read from strings like "10m" or "1h30m"
+type Duration time.Duration
+
+// UnmarshalYAML parses a Go duration string
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	if s == "" {
+		*d = 0
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration '%s': %w", s, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalYAML writes the duration as a string
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// eventAliases allows short names in rules instead of full Cowrie event IDs
+var eventAliases = map[string]string{
+	"connect":       events.EventSessionConnect,
+	"closed":        events.EventSessionClosed,
+	"login_success": events.EventLoginSuccess,
+	"login_failed":  events.EventLoginFailed,
+	"command":       events.EventCommandInput,
+	"download":      events.EventFileDownload,
+	"upload":        events.EventFileUpload,
+}
+
+// GetRulesPath returns the default rules file path (~/.otori/alerts.yaml)
+func GetRulesPath() string {
+	return filepath.Join(config.GetOtoriDir(), "alerts.yaml")
+}
+
+// LoadRules reads and validates a rules file
+func LoadRules(path string) (*RulesFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rules: %w", err)
+	}
+
+	var rf RulesFile
+	if err := yaml.Unmarshal(data, &rf); err != nil {
+		return nil, fmt.Errorf("error decoding YAML: %w", err)
+	}
+
+	if err := rf.compile(); err != nil {
+		return nil, err
+	}
+	return &rf, nil
+}
+
+// compile validates the rules and prepares regular expressions
+func (rf *RulesFile) compile() error {
+	notifiers := make(map[string]bool)
+	for _, n := range rf.Notifiers {
+		if n.Name == "" {
+			return fmt.Errorf("notifier without name")
+		}
+		if notifiers[n.Name] {
+			return fmt.Errorf("duplicate notifier '%s'", n.Name)
+		}
+		notifiers[n.Name] = true
+	}
+
+	names := make(map[string]bool)
+	for i := range rf.Rules {
+		rule := &rf.Rules[i]
+		if rule.Name == "" {
+			return fmt.Errorf("rule #%d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate rule '%s'", rule.Name)
+		}
+		names[rule.Name] = true
+
+		for j, ev := range rule.Events {
+			if full, ok := eventAliases[ev]; ok {
+				rule.Events[j] = full
+			}
+		}
+
+		rule.compiled = make(map[string]*regexp.Regexp)
+		for field, expr := range rule.Regex {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return fmt.Errorf("rule '%s': invalid regex for %s: %w", rule.Name, field, err)
+			}
+			rule.compiled[field] = re
+		}
+
+		if rule.Threshold != nil {
+			if rule.Threshold.Count < 1 || rule.Threshold.Window <= 0 {
+				return fmt.Errorf("rule '%s': threshold needs a count and a window", rule.Name)
+			}
+			if rule.Threshold.By == "" {
+				rule.Threshold.By = "src_ip"
+			}
+		}
+
+		for _, name := range rule.Notify {
+			if !notifiers[name] {
+				return fmt.Errorf("rule '%s': unknown notifier '%s'", rule.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// matches returns true if the event satisfies the rule filters (threshold excluded)
+func (r *Rule) matches(ev events.Event) bool {
+	if len(r.Events) > 0 {
+		found := false
+		for _, id := range r.Events {
+			if id == ev.EventID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for field, want := range r.Match {
+		if EventField(ev, field) != want {
+			return false
+		}
+	}
+
+	for field, re := range r.compiled {
+		if !re.MatchString(EventField(ev, field)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// EventField returns the value of a named event field as a string
+func EventField(ev events.Event, field string) string {
+	switch strings.ToLower(field) {
+	case "eventid", "event":
+		return ev.EventID
+	case "profile":
+		return ev.Profile
+	case "session":
+		return ev.Session
+	case "src_ip", "ip":
+		return ev.SrcIP
+	case "username":
+		return ev.Username
+	case "password":
+		return ev.Password
+	case "input":
+		return ev.Input
+	case "url":
+		return ev.URL
+	case "filename":
+		return ev.Filename
+	case "shasum":
+		return ev.Shasum
+	case "message":
+		return ev.Message
+	case "sensor":
+		return ev.Sensor
+	case "country":
+		return ev.Country
+	case "asn":
+		if ev.ASN == 0 {
+			return ""
+		}
+		return strconv.FormatUint(uint64(ev.ASN), 10)
+	}
+	return ""
+}
+
+// ExampleRules is written by "otori alerts init"
+const ExampleRules = `# Otori alerting rules
+# Generated by Otori CLI
+#
+# Rules are evaluated on every Cowrie event of the running honeypots.
+# Fields usable in match/regex: eventid, profile, session, src_ip, username,
+# password, input, url, filename, shasum, message, country, asn
+
+notifiers:
+  - name: desktop
+    type: desktop
+  # - name: ops
+  #   type: webhook
+  #   url: https://hooks.example.com/otori
+  # - name: mail
+  #   type: smtp
+  #   host: smtp.example.com
+  #   port: 587
+  #   username: otori
+  #   password: secret
+  #   from: otori@example.com
+  #   to: [soc@example.com]
+  # - name: pager
+  #   type: command
+  #   command: ["/usr/local/bin/page-oncall"]
+
+rules:
+  - name: admin-login
+    description: Successful login as the fake admin account
+    events: [login_success]
+    match:
+      username: admin
+    throttle: 10m
+
+  - name: honeytoken-read
+    description: Bait file read
+    events: [command]
+    regex:
+      input: 'secret\.txt'
+    throttle: 10m
+
+  - name: download
+    description: Payload download attempt
+    events: [command]
+    regex:
+      input: '\b(wget|curl)\b'
+    throttle: 10m
+
+  - name: payload-captured
+    description: File downloaded by the attacker and captured by Cowrie
+    events: [download]
+    dedup_by: [shasum]
+    throttle: 1h
+
+  - name: bruteforce
+    description: 20 failed logins or more from one IP within 5 minutes
+    events: [login_failed]
+    threshold:
+      count: 20
+      window: 5m
+      by: src_ip
+    throttle: 30m
+`
@@ -0,0 +1,310 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/alerts"
+	"github.com/otori-lab/otori-cli/internal/events"
+	"github.com/otori-lab/otori-cli/internal/geoip"
+	"github.com/otori-lab/otori-cli/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+var alertsRulesPath string
+var alertsProfiles []string
+var alertsTestNotify bool
+var alertsTestProfile string
+
+// alertsCmd is the parent command for alerting
+var alertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "Real-time alerting on honeypot activity",
+	Long:  "Evaluate alerting rules (~/.otori/alerts.yaml) over the Cowrie event stream",
+}
+
+// alertsInitCmd writes an example rules file
+var alertsInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create an example rules file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAlertsInit()
+	},
+}
+
+// alertsListCmd lists the configured rules
+var alertsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List alerting rules",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAlertsList()
+	},
+}
+
+// alertsRunCmd evaluates rules over the live event stream
+var alertsRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Watch running honeypots and send alerts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runAlertsRun()
+	},
+}
+
+// alertsTestCmd replays a recorded log file through the rules
+var alertsTestCmd = &cobra.Command{
+	Use:   "test [cowrie.json]",
+	Short: "Test rules against a recorded Cowrie log file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAlertsTest(args[0])
+	},
+}
+
+func init() {
+	alertsCmd.PersistentFlags().StringVarP(&alertsRulesPath, "rules", "r", "", "Rules file (default: ~/.otori/alerts.yaml)")
+
+	alertsRunCmd.Flags().StringSliceVarP(&alertsProfiles, "profile", "p", []string{}, "Profiles to watch (default: all running)")
+
+	alertsTestCmd.Flags().BoolVar(&alertsTestNotify, "notify", false, "Actually send notifications (default: dry run)")
+	alertsTestCmd.Flags().StringVarP(&alertsTestProfile, "profile", "p", "", "Profile name to attach to replayed events")
+
+	alertsCmd.AddCommand(alertsInitCmd)
+	alertsCmd.AddCommand(alertsListCmd)
+	alertsCmd.AddCommand(alertsRunCmd)
+	alertsCmd.AddCommand(alertsTestCmd)
+	RootCmd.AddCommand(alertsCmd)
+}
+
+// rulesPath returns the rules file selected by --rules or the default one
+func rulesPath() string {
+	if alertsRulesPath != "" {
+		return alertsRulesPath
+	}
+	return alerts.GetRulesPath()
+}
+
+func runAlertsInit() error {
+	path := rulesPath()
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("rules file already exists: %s", path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(alerts.ExampleRules), 0600); err != nil {
+		return fmt.Errorf("error writing rules: %w", err)
+	}
+
+	fmt.Fprintf(out, "✓ Example rules written to %s\n", path)
+	return nil
+}
+
+func runAlertsList() error {
+	rf, err := alerts.LoadRules(rulesPath())
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RULE\tEVENTS\tTHRESHOLD\tTHROTTLE\tNOTIFY")
+	for _, r := range rf.Rules {
+		threshold := "-"
+		if r.Threshold != nil {
+			threshold = fmt.Sprintf("%d/%s by %s", r.Threshold.Count, time.Duration(r.Threshold.Window), r.Threshold.By)
+		}
+		notify := "all"
+		if len(r.Notify) > 0 {
+			notify = fmt.Sprint(r.Notify)
+		}
+		evs := "any"
+		if len(r.Events) > 0 {
+			evs = fmt.Sprint(r.Events)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Name, evs, threshold, time.Duration(r.Throttle), notify)
+	}
+	w.Flush()
+
+	return nil
+}
+
+func runAlertsRun() error {
+	rf, err := alerts.LoadRules(rulesPath())
+	if err != nil {
+		return err
+	}
+	dispatcher, err := alerts.NewDispatcher(rf.Notifiers)
+	if err != nil {
+		return err
+	}
+
+	db, err := geoip.OpenDefault()
+	if err != nil {
+		fmt.Fprintf(errOut, "Warning: %v (GeoIP enrichment disabled)\n", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Merge the event streams of every profile. Honeypots deployed or
+	// restarted after the start are followed at the next scan.
+	stream := make(chan events.Event, 256)
+	followers := &alertFollowers{followed: make(map[string]bool)}
+	watched := followers.follow(ctx, stream)
+	go func() {
+		ticker := time.NewTicker(alertsScanInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				followers.wg.Wait()
+				close(stream)
+				return
+			case <-ticker.C:
+				followers.follow(ctx, stream)
+			}
+		}
+	}()
+
+	if watched == 0 {
+		fmt.Fprintln(out, "No running honeypot to watch yet, waiting for one to be deployed...")
+	}
+	fmt.Fprintf(out, "Watching %d honeypot(s) with %d rule(s). Press Ctrl+C to stop.\n\n", watched, len(rf.Rules))
+
+	// Notifications are sent in the background, a hung notifier never stops the rules
+	worker := dispatcher.StartWorker(func(err error) {
+		fmt.Fprintf(errOut, "Warning: %v\n", err)
+	})
+	defer worker.Close()
+
+	engine := alerts.NewEngine(rf)
+	for ev := range stream {
+		db.AnnotateEvent(&ev)
+		for _, a := range engine.Process(ev) {
+			fmt.Fprintf(out, "%s %s\n", a.Time.Format(time.RFC3339), a.Summary())
+			if !worker.Enqueue(a) {
+				fmt.Fprintf(errOut, "Warning: notifiers too slow, alert %s not sent\n", a.Rule)
+			}
+		}
+	}
+
+	return nil
+}
+
+// alertsScanInterval is the delay between two looks for honeypots to follow
+var alertsScanInterval = 10 * time.Second
+
+// alertFollowers follows the event streams of the watched honeypots
+type alertFollowers struct {
+	mu       sync.Mutex
+	followed map[string]bool
+	wg       sync.WaitGroup
+}
+
+// follow starts following the running honeypots of the watched profiles
+// (--profile, or all) that are not followed yet, and returns how many are
+// followed. A follower ends with its container: a restarted honeypot is
+// followed again by the next call.
+func (f *alertFollowers) follow(ctx context.Context, stream chan<- events.Event) int {
+	for _, hp := range tui.GetHoneypots() {
+		if hp.Status != tui.StatusActive || (len(alertsProfiles) > 0 && !slices.Contains(alertsProfiles, hp.Profile)) {
+			continue
+		}
+
+		f.mu.Lock()
+		if f.followed[hp.Profile] || ctx.Err() != nil {
+			f.mu.Unlock()
+			continue
+		}
+		f.followed[hp.Profile] = true
+		f.wg.Add(1)
+		f.mu.Unlock()
+
+		go func(profileName string) {
+			defer f.wg.Done()
+			err := events.FollowProfile(ctx, profileName, func(ev events.Event) {
+				select {
+				case stream <- ev:
+				case <-ctx.Done():
+				}
+			})
+			if err != nil {
+				fmt.Fprintf(errOut, "Warning: %v\n", err)
+			}
+			f.mu.Lock()
+			delete(f.followed, profileName)
+			f.mu.Unlock()
+		}(hp.Profile)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.followed)
+}
+
+func runAlertsTest(logPath string) error {
+	rf, err := alerts.LoadRules(rulesPath())
+	if err != nil {
+		return err
+	}
+
+	var dispatcher *alerts.Dispatcher
+	if alertsTestNotify {
+		dispatcher, err = alerts.NewDispatcher(rf.Notifiers)
+		if err != nil {
+			return err
+		}
+	}
+
+	evs, err := events.ReadFileEvents(logPath)
+	if err != nil {
+		return err
+	}
+
+	db, err := geoip.OpenDefault()
+	if err != nil {
+		fmt.Fprintf(errOut, "Warning: %v (GeoIP enrichment disabled)\n", err)
+	}
+	defer db.Close()
+	db.Annotate(evs)
+
+	engine := alerts.NewEngine(rf)
+	perRule := make(map[string]int)
+	total := 0
+	for _, ev := range evs {
+		if alertsTestProfile != "" {
+			ev.Profile = alertsTestProfile
+		}
+		for _, a := range engine.Process(ev) {
+			fmt.Fprintf(out, "%s %s\n", a.Time.Format(time.RFC3339), a.Summary())
+			perRule[a.Rule]++
+			total++
+			if dispatcher != nil {
+				for _, err := range dispatcher.Send(a) {
+					fmt.Fprintf(errOut, "Warning: %v\n", err)
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "\n%d event(s) replayed, %d alert(s) raised\n", len(evs), total)
+	for _, r := range rf.Rules {
+		fmt.Fprintf(out, "  %-24s %d\n", r.Name, perRule[r.Name])
+	}
+	if !alertsTestNotify {
+		fmt.Fprintln(out, "\nDry run: no notification sent (use --notify to send them)")
+	}
+
+	return nil
+}
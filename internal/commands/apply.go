@@ -0,0 +1,300 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var applyFile string
+var applyPrune bool
+var applyDryRun bool
+
+// applyCmd reconciles profiles and containers with a manifest
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply a declarative manifest (otori.yaml)",
+	Long: "Reconcile ~/.otori/profiles and the honeypot containers with a manifest:\n" +
+		"create missing profiles, re-render and redeploy changed ones and, with --prune,\n" +
+		"remove the profiles absent from the manifest. The plan is printed first.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runApply()
+	},
+}
+
+// Profile changes of an apply plan
+const (
+	changeNone   = ""
+	changeCreate = "create"
+	changeUpdate = "update"
+	changePrune  = "prune"
+)
+
+// Container operations of an apply plan
+const (
+	opNone     = ""
+	opDeploy   = "deploy"
+	opRedeploy = "redeploy"
+	opStop     = "stop"
+)
+
+// applyStep is the planned reconciliation of one profile
+type applyStep struct {
+	profile   string
+	desired   *models.Config // nil for pruned profiles
+	change    string
+	operation string
+	reason    string
+}
+
+func runApply() error {
+	manifest, err := config.LoadManifest(applyFile)
+	if err != nil {
+		return err
+	}
+
+	// Validate every profile before touching anything
+	configs := manifest.Configs()
+	invalid := 0
+	for _, cfg := range configs {
+		effective, _, err := config.ResolveConfig(cfg)
+		if err != nil {
+			fmt.Fprintf(out, "✗ %s: %v\n", cfg.ProfileName, err)
+			invalid++
+			continue
+		}
+		effective.ProfileName = cfg.ProfileName
+		for _, verr := range config.ValidateConfig(effective) {
+			fmt.Fprintf(out, "✗ %s: %s: %s\n", cfg.ProfileName, verr.Field, verr.Message)
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return withCode(exitInvalid, fmt.Errorf("manifest has %d validation error(s), nothing was applied", invalid))
+	}
+
+	existing, err := config.ListConfigs()
+	if err != nil {
+		return fmt.Errorf("error reading profiles: %w", err)
+	}
+
+	containers, runtimeErr := container.ListContainers(append(existing, manifestNames(configs)...))
+	if runtimeErr != nil {
+		fmt.Fprintf(out, "Warning: container runtime unavailable, containers will not be reconciled: %v\n\n", runtimeErr)
+	}
+	byProfile := make(map[string]container.Container)
+	for _, ct := range containers {
+		byProfile[ct.Profile()] = ct
+	}
+
+	steps, err := planApply(configs, existing, byProfile, runtimeErr == nil)
+	if err != nil {
+		return err
+	}
+
+	unmanaged := printPlan(steps)
+	if unmanaged > 0 && !applyPrune {
+		fmt.Fprintf(out, "\n%d profile(s) are not in the manifest (use --prune to remove them)\n", unmanaged)
+	}
+
+	pending := 0
+	for _, step := range steps {
+		if step.change != changeNone || step.operation != opNone {
+			pending++
+		}
+	}
+	if pending == 0 {
+		fmt.Fprintln(out, "\nNothing to do, profiles match the manifest")
+		return nil
+	}
+	if applyDryRun {
+		fmt.Fprintln(out, "\nDry run: nothing was applied")
+		return nil
+	}
+
+	fmt.Fprintln(out)
+	failed := 0
+	for _, step := range steps {
+		if err := applyStepRun(step); err != nil {
+			fmt.Fprintf(out, "✗ %s: %v\n", step.profile, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d change(s) failed", failed, pending)
+	}
+	fmt.Fprintf(out, "\n✓ Manifest applied (%d change(s))\n", pending)
+	return nil
+}
+
+// planApply compares the manifest with the profiles on disk and the containers
+func planApply(configs []*models.Config, existing []string, containers map[string]container.Container, runtimeUp bool) ([]applyStep, error) {
+	onDisk := make(map[string]bool)
+	for _, name := range existing {
+		onDisk[name] = true
+	}
+
+	var steps []applyStep
+	declared := make(map[string]bool)
+	for _, cfg := range configs {
+		declared[cfg.ProfileName] = true
+		step := applyStep{profile: cfg.ProfileName, desired: cfg}
+
+		if !onDisk[cfg.ProfileName] {
+			step.change = changeCreate
+		} else {
+			current, err := config.ReadConfig(cfg.ProfileName)
+			if err != nil {
+				return nil, fmt.Errorf("profile '%s': %w", cfg.ProfileName, err)
+			}
+			cfg.CreatedAt = current.CreatedAt
+			if cfg.Seed == "" {
+				cfg.Seed = current.Seed
+			}
+			if !config.SameConfig(current, cfg) {
+				step.change = changeUpdate
+			}
+		}
+
+		if runtimeUp {
+			effective, _, err := config.ResolveConfig(cfg)
+			if err != nil {
+				return nil, err
+			}
+			ct, deployed := containers[cfg.ProfileName]
+
+			switch {
+			case effective.Type != "classic":
+				if deployed {
+					step.operation, step.reason = opStop, "not a classic profile"
+				}
+			case !deployed || ct.State != "running":
+				step.operation = opDeploy
+				if step.change != changeCreate {
+					step.reason = "not running"
+				}
+			case step.change == changeUpdate:
+				step.operation, step.reason = opRedeploy, "configuration changed"
+			case ct.Labels[container.LabelConfigHash] != config.ConfigHash(effective):
+				step.operation, step.reason = opRedeploy, "config drift"
+			}
+		}
+
+		steps = append(steps, step)
+	}
+
+	for _, name := range existing {
+		if declared[name] {
+			continue
+		}
+		step := applyStep{profile: name, reason: "not in manifest"}
+		if applyPrune {
+			step.change = changePrune
+			if _, deployed := containers[name]; deployed {
+				step.operation = opStop
+			}
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// printPlan displays the plan and returns the number of unmanaged profiles
+func printPlan(steps []applyStep) int {
+	fmt.Fprintln(out, "Plan:")
+	unmanaged := 0
+	for _, step := range steps {
+		var symbol, action string
+		switch {
+		case step.change == changeCreate:
+			symbol, action = "+", "create"
+		case step.change == changeUpdate:
+			symbol, action = "~", "update"
+		case step.change == changePrune:
+			symbol, action = "-", "prune"
+		case step.operation != opNone:
+			symbol, action = "↻", step.operation
+		case step.desired == nil:
+			symbol, action = "?", "unmanaged"
+			unmanaged++
+		default:
+			symbol, action = "=", "unchanged"
+		}
+
+		var details []string
+		if step.change != changeNone && step.operation != opNone {
+			details = append(details, step.operation)
+		}
+		if step.reason != "" {
+			details = append(details, step.reason)
+		}
+		suffix := ""
+		if len(details) > 0 {
+			suffix = " (" + strings.Join(details, ", ") + ")"
+		}
+		fmt.Fprintf(out, "  %s %-10s %s%s\n", symbol, action, step.profile, suffix)
+	}
+	return unmanaged
+}
+
+// applyStepRun performs the changes of a step: profile files first, then the container
+func applyStepRun(step applyStep) error {
+	if step.operation == opStop {
+		if err := stopHoneypot(step.profile, false); err != nil {
+			return err
+		}
+	}
+
+	switch step.change {
+	case changeCreate:
+		if err := config.WriteConfig(step.desired); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "✓ Profile '%s' created\n", step.profile)
+	case changeUpdate:
+		if err := config.WriteConfigWithName(step.profile, step.desired); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "✓ Profile '%s' updated\n", step.profile)
+		printRenderConflicts(step.profile)
+	case changePrune:
+		// Pruned profiles are archived, their volumes are kept
+		if err := deleteProfile(step.profile, DeleteOptions{KeepData: true}); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch step.operation {
+	case opDeploy:
+		_, err = deployHoneypot(step.profile, false)
+	case opRedeploy:
+		_, err = deployHoneypot(step.profile, true)
+	}
+	return err
+}
+
+// manifestNames returns the profile names declared by the manifest
+func manifestNames(configs []*models.Config) []string {
+	names := make([]string, 0, len(configs))
+	for _, cfg := range configs {
+		names = append(names, cfg.ProfileName)
+	}
+	return names
+}
+
+func init() {
+	applyCmd.Flags().StringVarP(&applyFile, "file", "f", "otori.yaml", "Manifest file (- for stdin)")
+	applyCmd.Flags().BoolVar(&applyPrune, "prune", false, "Remove profiles (and containers) absent from the manifest")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Print the plan without applying it")
+
+	RootCmd.AddCommand(applyCmd)
+}
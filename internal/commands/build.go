@@ -0,0 +1,217 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/spf13/cobra"
+)
+
+var buildProfile string
+var buildContext string
+var buildNoBuild bool
+var buildUse bool
+
+var buildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build an image with the Cowrie files of a profile baked in",
+	Long: `Build an image holding cowrie.cfg, userdb.txt, honeyfs, txtcmds and a pre-built
+fs.pickle of a profile, for immutable deployments without bind mounts.
+The image is tagged otori/<profile>:<hash of the build context and base image>.
+With --use the profile is marked as baked: its docker-compose.yml runs this
+image and mounts no files. deploy refuses a build older than the profile files.`,
+	Args:        cobra.NoArgs,
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runBuild()
+	},
+}
+
+// buildResult is the JSON/YAML result of build (kind BuildResult)
+type buildResult struct {
+	Profile   string `json:"profile"`
+	Image     string `json:"image"`             // otori/<profile>:<context hash>
+	ImageID   string `json:"imageId,omitempty"` // empty with --no-build
+	BaseImage string `json:"baseImage"`
+	Context   string `json:"context,omitempty"` // kept build context (--context)
+	FSEntries int    `json:"fsEntries"`         // entries added to fs.pickle
+	Built     bool   `json:"built"`
+	Baked     bool   `json:"baked"` // the profile deploys the built image
+}
+
+func runBuild() error {
+	if buildNoBuild && buildContext == "" {
+		return usageError("--no-build needs --context to keep the generated files")
+	}
+
+	profileName := buildProfile
+	if profileName == "" {
+		profileName = config.DefaultProfile()
+	}
+
+	result, err := buildProfileImage(profileName)
+	if err != nil {
+		return err
+	}
+
+	if buildUse {
+		if err := bakeProfile(profileName); err != nil {
+			return err
+		}
+		result.Baked = true
+	}
+
+	if structuredOutput() {
+		return printResult("BuildResult", result)
+	}
+	return nil
+}
+
+// buildProfileImage writes the build context of a profile and builds its image
+func buildProfileImage(profileName string) (*buildResult, error) {
+	lock, err := config.LockProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	cfg, err := config.ReadEffectiveConfig(profileName)
+	if err != nil {
+		return nil, fmt.Errorf("profile '%s' not found: %w", profileName, err)
+	}
+	if cfg.Type != "classic" {
+		return nil, fmt.Errorf("profile '%s' is of type '%s', only 'classic' profiles can be built", profileName, cfg.Type)
+	}
+
+	result := &buildResult{
+		Profile:   profileName,
+		BaseImage: config.ProfileImage(cfg),
+		Context:   buildContext,
+		Baked:     cfg.Baked,
+	}
+
+	// Never mix a build context with other files
+	contextDir := buildContext
+	if contextDir != "" {
+		if entries, err := os.ReadDir(contextDir); err == nil && len(entries) > 0 {
+			return nil, fmt.Errorf("directory %s is not empty", contextDir)
+		}
+	} else {
+		if contextDir, err = os.MkdirTemp("", "otori-build-"); err != nil {
+			return nil, fmt.Errorf("error creating build context: %w", err)
+		}
+		defer os.RemoveAll(contextDir)
+	}
+
+	// Same fs.pickle entries as deploy adds to a running container
+	profileDir := filepath.Join(config.GetConfigDir(), profileName)
+	fsctlCommands := profileFsctlCommands(profileDir)
+	if err := config.WriteBuildContext(contextDir, profileDir, cfg, fsctlCommands); err != nil {
+		return nil, err
+	}
+	result.FSEntries = len(fsctlCommands)
+
+	// The name of the image covers the context and the base image it starts from
+	baseID, err := baseImageID(result.BaseImage)
+	if err != nil {
+		return nil, err
+	}
+	if result.Image, err = config.BuildImage(profileName, contextDir, baseID); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(out, "Building image of profile '%s'...\n", profileName)
+	fmt.Fprintf(out, "  Base image: %s\n", result.BaseImage)
+	fmt.Fprintf(out, "  Image:      %s\n", result.Image)
+	fmt.Fprintf(out, "  Filesystem: %d custom entries\n", result.FSEntries)
+	printRenderConflicts(profileName)
+	fmt.Fprintln(out)
+
+	if buildNoBuild {
+		fmt.Fprintf(out, "✓ Build context written to %s\n", contextDir)
+		fmt.Fprintf(out, "Build it with: docker build -t %s %s\n", result.Image, contextDir)
+		return result, nil
+	}
+
+	// A pinned base image must be the expected one before it is built upon
+	if _, err := ensureImage(result.BaseImage); err != nil {
+		return nil, err
+	}
+
+	dockerCmd := container.Docker("build", "-t", result.Image, contextDir)
+	dockerCmd.Stdout = out
+	dockerCmd.Stderr = errOut
+	if _, err := container.Run(dockerCmd); err != nil {
+		return nil, fmt.Errorf("failed to build image: %w", err)
+	}
+	result.Built = true
+
+	img, err := container.InspectImage(result.Image)
+	if err != nil {
+		return nil, err
+	}
+	if img != nil {
+		result.ImageID = img.ID
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "✓ Image %s built (%s)\n", result.Image, container.ShortID(result.ImageID))
+	if buildContext != "" {
+		fmt.Fprintf(out, "  Build context kept in %s\n", buildContext)
+	}
+	if !cfg.Baked && !buildUse {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "To deploy it instead of the mounted files: otori build -p", profileName, "--use")
+	}
+
+	return result, nil
+}
+
+// baseImageID returns the ID of the base image of a build: the pinned ID, or
+// the one of the local image, checked against its digest or pulled by tag
+func baseImageID(base string) (string, error) {
+	ref, err := container.ParseImageRef(base)
+	if err != nil || ref.ID != "" {
+		return ref.ID, err
+	}
+	if ref.Pinned() {
+		img, err := ensureImage(base)
+		if err != nil {
+			return "", err
+		}
+		return img.ID, nil
+	}
+	pinned, err := container.PinImage(ref)
+	return pinned.ID, err
+}
+
+// bakeProfile marks a profile as baked, so that its docker-compose.yml runs the built image
+func bakeProfile(profileName string) error {
+	cfg, err := config.ReadConfig(profileName)
+	if err != nil {
+		return fmt.Errorf("profile '%s' not found: %w", profileName, err)
+	}
+	if !cfg.Baked {
+		cfg.Baked = true
+		if err := config.WriteConfigWithName(profileName, cfg); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, "✓ Profile '%s' now deploys its built image (no mounted files)\n", profileName)
+	fmt.Fprintln(out, "To apply: otori deploy -p", profileName)
+	return nil
+}
+
+func init() {
+	buildCmd.Flags().StringVarP(&buildProfile, "profile", "p", "", "Profile to build (default: defaultProfile setting)")
+	buildCmd.Flags().StringVar(&buildContext, "context", "", "Keep the build context (Dockerfile and files) in this directory")
+	buildCmd.Flags().BoolVar(&buildNoBuild, "no-build", false, "Only write the build context (needs --context)")
+	buildCmd.Flags().BoolVar(&buildUse, "use", false, "Make the profile deploy the built image instead of mounting its files")
+	RootCmd.AddCommand(buildCmd)
+}
@@ -3,49 +3,81 @@ package commands
 import (
 	"fmt"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/otori-lab/otori-cli/internal/config"
-	"github.com/otori-lab/otori-cli/internal/ui"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/otori-lab/otori-cli/internal/models"
 	"github.com/spf13/cobra"
 )
 
 var deployProfile string
 var deployForce bool
 
+// containerStartDelay is the time given to Cowrie to start before fs.pickle is updated
+var containerStartDelay = 3 * time.Second
+
 var deployCmd = &cobra.Command{
-	Use:   "deploy",
-	Short: "Deploy a honeypot",
-	Long:  "Deploy a honeypot using Docker Compose from a profile configuration",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(ui.GetLogo())
-
-		if err := runDeploy(); err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
-		}
+	Use:         "deploy",
+	Short:       "Deploy a honeypot",
+	Long:        "Deploy a honeypot using Docker Compose from a profile configuration",
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runDeploy()
 	},
 }
 
+// deployResult is the JSON/YAML result of deploy (kind DeployResult)
+type deployResult struct {
+	Profile    string                  `json:"profile"`
+	Container  string                  `json:"container"`
+	Type       string                  `json:"type"`
+	ServerName string                  `json:"serverName"`
+	Ports      models.Ports            `json:"ports"`
+	Image      string                  `json:"image"`             // image reference, digest included
+	ImageID    string                  `json:"imageId,omitempty"` // local image checked against the pinned digest
+	FSEntries  int                     `json:"fsEntries"`         // entries added to fs.pickle
+	Warnings   []string                `json:"warnings"`
+	Conflicts  []config.RenderConflict `json:"conflicts"`
+}
+
 func runDeploy() error {
-	// Use default profile if not specified
+	// Use the default profile (defaultProfile setting) if not specified
 	profileName := deployProfile
 	if profileName == "" {
-		profileName = "default"
+		profileName = config.DefaultProfile()
 	}
 
+	result, err := deployHoneypot(profileName, deployForce)
+	if err != nil || !structuredOutput() {
+		return err
+	}
+	return printResult("DeployResult", result)
+}
+
+// deployHoneypot starts the honeypot of a profile with Docker Compose
+func deployHoneypot(profileName string, force bool) (*deployResult, error) {
+	// Serialize with other otori processes working on this profile (edit, stop, delete...)
+	lock, err := config.LockProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
 	// Read profile configuration
-	cfg, err := config.ReadConfig(profileName)
+	cfg, err := config.ReadEffectiveConfig(profileName)
 	if err != nil {
-		return fmt.Errorf("profile '%s' not found: %w", profileName, err)
+		return nil, fmt.Errorf("profile '%s' not found: %w", profileName, err)
 	}
 
 	// Check if profile is classic type
 	if cfg.Type != "classic" {
-		return fmt.Errorf("profile '%s' is of type '%s', only 'classic' profiles can be deployed with Docker", profileName, cfg.Type)
+		return nil, fmt.Errorf("profile '%s' is of type '%s', only 'classic' profiles can be deployed with Docker", profileName, cfg.Type)
 	}
 
 	// Get profile directory
@@ -54,45 +86,91 @@ func runDeploy() error {
 	// Check if docker-compose.yml exists
 	dockerComposePath := filepath.Join(profileDir, "docker-compose.yml")
 	if _, err := os.Stat(dockerComposePath); os.IsNotExist(err) {
-		return fmt.Errorf("docker-compose.yml not found in profile '%s'", profileName)
+		return nil, fmt.Errorf("docker-compose.yml %w in profile '%s'", config.ErrNotFound, profileName)
+	}
+
+	_, image := config.DeployImage(cfg)
+	result := &deployResult{
+		Profile:    profileName,
+		Container:  "otori-" + profileName,
+		Type:       cfg.Type,
+		ServerName: cfg.ServerName,
+		Ports:      models.Ports{SSH: cfg.SSHPort(), Telnet: cfg.TelnetPort()},
+		Image:      image,
+		Warnings:   []string{},
+		Conflicts:  renderConflicts(profileName),
 	}
 
-	fmt.Printf("Deploying honeypot from profile '%s'...\n", profileName)
-	fmt.Printf("  Server: %s\n", cfg.ServerName)
-	fmt.Printf("  Type: %s\n", cfg.Type)
-	fmt.Println()
+	fmt.Fprintf(out, "Deploying honeypot from profile '%s'...\n", profileName)
+	fmt.Fprintf(out, "  Server: %s\n", cfg.ServerName)
+	fmt.Fprintf(out, "  Type: %s\n", cfg.Type)
+	fmt.Fprintf(out, "  Image: %s\n", result.Image)
+	printRenderConflicts(profileName)
+	fmt.Fprintln(out)
+
+	// A pinned image must be the expected one before anything starts,
+	// a baked image must have been built for the current configuration
+	var img *container.Image
+	if cfg.Baked {
+		result.Image, img, err = builtImage(cfg)
+	} else {
+		img, err = ensureImage(result.Image)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if img != nil {
+		result.ImageID = img.ID
+	}
 
 	// Build docker compose command
-	var dockerCmd *exec.Cmd
-	if deployForce {
-		fmt.Println("Force recreating containers...")
-		dockerCmd = exec.Command("docker", "compose", "up", "-d", "--force-recreate")
+	var dockerCmd container.Command
+	if force {
+		fmt.Fprintln(out, "Force recreating containers...")
+		dockerCmd = container.Docker("compose", "up", "-d", "--force-recreate")
 	} else {
-		dockerCmd = exec.Command("docker", "compose", "up", "-d")
+		dockerCmd = container.Docker("compose", "up", "-d")
 	}
 
 	// Set working directory to profile directory
 	dockerCmd.Dir = profileDir
-	dockerCmd.Stdout = os.Stdout
-	dockerCmd.Stderr = os.Stderr
+	dockerCmd.Stdout = out
+	dockerCmd.Stderr = errOut
 
 	// Run docker compose
-	if err := dockerCmd.Run(); err != nil {
-		return fmt.Errorf("failed to start containers: %w", err)
+	if _, err := container.Run(dockerCmd); err != nil {
+		return nil, fmt.Errorf("failed to start containers: %w", err)
 	}
 
-	// Update fs.pickle with custom honeyfs entries
-	containerName := "otori-" + profileName
-	fmt.Println()
-	fmt.Println("Updating filesystem structure...")
+	// A baked image already has its fs.pickle
+	if !cfg.Baked {
+		updateFilesystem(profileDir, result)
+	}
 
-	// Wait for container to be fully ready
-	time.Sleep(3 * time.Second)
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "✓ Honeypot '%s' deployed successfully!\n", profileName)
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Honeypot is listening on:")
+	fmt.Fprintf(out, "  SSH:    localhost:%d\n", cfg.SSHPort())
+	fmt.Fprintf(out, "  Telnet: localhost:%d\n", cfg.TelnetPort())
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "To check status: otori status")
+	fmt.Fprintln(out, "To stop:         otori stop -p", profileName)
+
+	return result, nil
+}
 
-	// Get custom paths from honeyfs that need to be added to fs.pickle
-	honeyfsDir := filepath.Join(profileDir, "honeyfs")
-	fsctlCommands := generateFsctlCommands(honeyfsDir)
+// updateFilesystem adds the custom honeyfs entries and txtcmds of a profile
+// to the fs.pickle of its running container, then restarts it
+func updateFilesystem(profileDir string, result *deployResult) {
+	containerName := result.Container
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Updating filesystem structure...")
 
+	// Wait for container to be fully ready
+	time.Sleep(containerStartDelay)
+
+	fsctlCommands := profileFsctlCommands(profileDir)
 	if len(fsctlCommands) > 0 {
 		// Build fsctl command string (one command per line, ending with exit)
 		fsctlInput := strings.Join(fsctlCommands, "\n") + "\nexit\n"
@@ -101,7 +179,7 @@ func runDeploy() error {
 		// Command: docker exec -i -e PYTHONPATH=/cowrie/cowrie-git/src <container>
 		//          /cowrie/cowrie-env/bin/python3 -m cowrie.scripts.fsctl
 		//          /cowrie/cowrie-git/src/cowrie/data/fs.pickle
-		fsctlCmd := exec.Command("docker", "exec", "-i",
+		fsctlCmd := container.Docker("exec", "-i",
 			"-e", "PYTHONPATH=/cowrie/cowrie-git/src",
 			containerName,
 			"/cowrie/cowrie-env/bin/python3", "-m", "cowrie.scripts.fsctl",
@@ -109,38 +187,106 @@ func runDeploy() error {
 
 		// Pipe the commands to fsctl stdin
 		fsctlCmd.Stdin = strings.NewReader(fsctlInput)
-		fsctlCmd.Stdout = os.Stdout
-		fsctlCmd.Stderr = os.Stderr
+		fsctlCmd.Stdout = out
+		fsctlCmd.Stderr = errOut
 
-		if err := fsctlCmd.Run(); err != nil {
-			fmt.Printf("Warning: failed to update fs.pickle: %v\n", err)
+		if _, err := container.Run(fsctlCmd); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("failed to update fs.pickle: %v", err))
+			fmt.Fprintf(out, "Warning: failed to update fs.pickle: %v\n", err)
 		} else {
-			fmt.Printf("  Added %d custom entries to filesystem\n", len(fsctlCommands))
+			result.FSEntries = len(fsctlCommands)
+			fmt.Fprintf(out, "  Added %d custom entries to filesystem\n", len(fsctlCommands))
 
 			// Restart container to reload fs.pickle
-			fmt.Println("Restarting honeypot to apply changes...")
-			restartCmd := exec.Command("docker", "compose", "restart")
+			fmt.Fprintln(out, "Restarting honeypot to apply changes...")
+			restartCmd := container.Docker("compose", "restart")
 			restartCmd.Dir = profileDir
-			restartCmd.Stdout = os.Stdout
-			restartCmd.Stderr = os.Stderr
+			restartCmd.Stdout = out
+			restartCmd.Stderr = errOut
 
-			if err := restartCmd.Run(); err != nil {
-				fmt.Printf("Warning: failed to restart container: %v\n", err)
+			if _, err := container.Run(restartCmd); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("failed to restart container: %v", err))
+				fmt.Fprintf(out, "Warning: failed to restart container: %v\n", err)
 			}
 		}
 	}
+}
+
+// profileFsctlCommands returns the fsctl commands adding the custom entries of a profile to fs.pickle
+func profileFsctlCommands(profileDir string) []string {
+	// Get custom paths from honeyfs that need to be added to fs.pickle
+	fsctlCommands := generateFsctlCommands(filepath.Join(profileDir, "honeyfs"))
+
+	// Commands with a txtcmds output must exist in fs.pickle to be found
+	if commandPaths, err := config.TxtCmdPaths(profileDir); err == nil {
+		fsctlCommands = append(fsctlCommands, generateTxtCmdsFsctlCommands(commandPaths)...)
+	}
+	return fsctlCommands
+}
+
+// builtImage returns the image built by "otori build" from the current files
+// of a baked profile, tagged with the name its docker-compose.yml runs. After a
+// change of the files, of the configuration or of the base image, no such image
+// exists until the profile is built again.
+func builtImage(cfg *models.Config) (string, *container.Image, error) {
+	contextDir, err := os.MkdirTemp("", "otori-build-")
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating build context: %w", err)
+	}
+	defer os.RemoveAll(contextDir)
+
+	profileDir := filepath.Join(config.GetConfigDir(), cfg.ProfileName)
+	if err := config.WriteBuildContext(contextDir, profileDir, cfg, profileFsctlCommands(profileDir)); err != nil {
+		return "", nil, err
+	}
+	baseID, err := baseImageID(config.ProfileImage(cfg))
+	if err != nil {
+		return "", nil, err
+	}
+	image, err := config.BuildImage(cfg.ProfileName, contextDir, baseID)
+	if err != nil {
+		return "", nil, err
+	}
 
-	fmt.Println()
-	fmt.Printf("✓ Honeypot '%s' deployed successfully!\n", profileName)
-	fmt.Println()
-	fmt.Println("Honeypot is listening on:")
-	fmt.Println("  SSH:    localhost:2222")
-	fmt.Println("  Telnet: localhost:2223")
-	fmt.Println()
-	fmt.Println("To check status: otori status")
-	fmt.Println("To stop:         otori stop -p", profileName)
+	img, err := container.InspectImage(image)
+	if err != nil {
+		return "", nil, err
+	}
+	if img == nil {
+		return "", nil, fmt.Errorf("image %s %w, the profile changed since its last build: otori build -p %s", image, config.ErrNotFound, cfg.ProfileName)
+	}
+	if _, err := container.Run(container.Docker("tag", image, config.BakedImage(cfg.ProfileName))); err != nil {
+		return "", nil, fmt.Errorf("error tagging %s: %w", config.BakedImage(cfg.ProfileName), err)
+	}
+	fmt.Fprintf(out, "✓ Image %s built from the current profile files (%s)\n\n", image, container.ShortID(img.ID))
+	return image, img, nil
+}
+
+// ensureImage pulls the image of a pinned reference when it is missing and
+// checks its digest. Unpinned images are left to docker compose.
+func ensureImage(image string) (*container.Image, error) {
+	ref, err := container.ParseImageRef(image)
+	if err != nil || !ref.Pinned() {
+		return nil, err
+	}
+
+	img, err := container.InspectImage(ref.Tagged())
+	if err != nil {
+		return nil, err
+	}
+	if img == nil {
+		fmt.Fprintf(out, "Pulling %s...\n", ref)
+		if err := container.PullImage(ref); err != nil {
+			return nil, err
+		}
+	}
 
-	return nil
+	img, err = container.VerifyImage(ref)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(out, "✓ Image %s verified (%s)\n\n", ref.Tagged(), container.ShortID(img.ID))
+	return img, nil
 }
 
 // generateFsctlCommands scans the honeyfs directory and generates fsctl commands
@@ -218,13 +364,58 @@ func generateFsctlCommands(honeyfsDir string) []string {
 	return commands
 }
 
+// generateTxtCmdsFsctlCommands generates fsctl commands declaring the txtcmds
+// that don't exist in the base Cowrie fs.pickle
+func generateTxtCmdsFsctlCommands(commandPaths []string) []string {
+	var commands []string
+
+	// Paths that already exist in Cowrie's fs.pickle (Debian 7 filesystem)
+	existingPaths := map[string]bool{
+		"/":                true,
+		"/bin":             true,
+		"/sbin":            true,
+		"/usr":             true,
+		"/usr/bin":         true,
+		"/usr/sbin":        true,
+		"/usr/local":       true,
+		"/usr/local/bin":   true,
+		"/bin/df":          true,
+		"/bin/dmesg":       true,
+		"/bin/mount":       true,
+		"/bin/netstat":     true,
+		"/bin/ps":          true,
+		"/usr/bin/lastlog": true,
+		"/usr/bin/lscpu":   true,
+		"/usr/bin/nproc":   true,
+	}
+
+	for _, commandPath := range commandPaths {
+		if existingPaths[commandPath] {
+			continue
+		}
+
+		// Create missing parent directories, outermost first
+		var missing []string
+		for dir := path.Dir(commandPath); !existingPaths[dir]; dir = path.Dir(dir) {
+			missing = append([]string{dir}, missing...)
+			existingPaths[dir] = true
+		}
+		for _, dir := range missing {
+			commands = append(commands, fmt.Sprintf("mkdir %s", dir))
+		}
+		commands = append(commands, fmt.Sprintf("touch %s", commandPath))
+	}
+
+	return commands
+}
+
 func init() {
 	deployCmd.Flags().StringVarP(
 		&deployProfile,
 		"profile",
 		"p",
 		"",
-		"Profile to deploy (default: 'default')",
+		"Profile to deploy (default: defaultProfile setting)",
 	)
 
 	deployCmd.Flags().BoolVarP(
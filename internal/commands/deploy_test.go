@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/otori-lab/otori-cli/internal/testutil"
+)
+
+func TestGenerateFsctlCommands(t *testing.T) {
+	testutil.OtoriHome(t)
+
+	cfg := models.NewConfig()
+	cfg.Type = "classic"
+	cfg.ServerName = "web42"
+	cfg.ProfileName = "fsctl"
+	cfg.Company = "Acme"
+	cfg.Users = []string{"root", "alice"}
+	cfg.Persona = &models.Persona{
+		Services: []models.Service{{Name: "nginx"}, {Name: "postgres"}},
+	}
+	cfg.BaitFiles = []models.BaitFile{{Path: "/opt/backup/db.conf", Content: "password=hunter2\n"}}
+	cfg.Seed = "0123456789abcdef"
+	cfg.CreatedAt = "2025-03-02T10:00:00Z"
+
+	dir := t.TempDir()
+	if err := config.WriteHoneyFS(dir, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := generateFsctlCommands(filepath.Join(dir, "honeyfs"))
+	testutil.Golden(t, "fsctl.txt", []byte(strings.Join(commands, "\n")+"\n"))
+}
+
+func TestGenerateTxtCmdsFsctlCommands(t *testing.T) {
+	got := generateTxtCmdsFsctlCommands([]string{"/bin/ps", "/usr/bin/uptime", "/opt/tools/bin/check", "/opt/tools/bin/probe"})
+	want := []string{
+		"touch /usr/bin/uptime",
+		"mkdir /opt",
+		"mkdir /opt/tools",
+		"mkdir /opt/tools/bin",
+		"touch /opt/tools/bin/check",
+		"touch /opt/tools/bin/probe",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
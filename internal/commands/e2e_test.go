@@ -0,0 +1,781 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/otori-lab/otori-cli/internal/container/containertest"
+	"github.com/otori-lab/otori-cli/internal/service"
+	"github.com/otori-lab/otori-cli/internal/testutil"
+	"github.com/otori-lab/otori-cli/internal/tui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// setupE2E gives the test an empty otori home and a fake docker runtime
+func setupE2E(t *testing.T) *containertest.Fake {
+	t.Helper()
+
+	testutil.OtoriHome(t)
+	fake := containertest.NewFake()
+	t.Cleanup(fake.Install())
+
+	delay := containerStartDelay
+	containerStartDelay = 0
+	t.Cleanup(func() { containerStartDelay = delay })
+	return fake
+}
+
+// runOtori executes the otori command line and returns what it printed on stdout and stderr
+func runOtori(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	stdout, stderr, err := runOtoriSplit(t, args...)
+	return stdout + stderr, err
+}
+
+// runOtoriSplit executes the otori command line and returns what it printed on stdout and on stderr
+func runOtoriSplit(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	RootCmd.SetOut(&stdout)
+	RootCmd.SetErr(&stderr)
+	resetFlags(RootCmd)
+	RootCmd.SetArgs(args)
+	err := execute()
+	return stdout.String(), stderr.String(), err
+}
+
+// resetFlags gives every flag its default value back: cobra keeps the values
+// of a command line for the next execution
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+func TestInitDeployStatusStop(t *testing.T) {
+	fake := setupE2E(t)
+	profileDir := filepath.Join(config.GetConfigDir(), "e2e")
+
+	// init
+	out, err := runOtori(t, "init", "-t", "classic", "-s", "web01", "-p", "e2e", "-u", "root,admin")
+	if err != nil {
+		t.Fatalf("init: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "Profile 'e2e' created successfully") {
+		t.Errorf("init output:\n%s", out)
+	}
+	for _, file := range []string{"e2e.json", "cowrie.cfg", "userdb.txt", "docker-compose.yml", "honeyfs/etc/hostname"} {
+		if _, err := os.Stat(filepath.Join(profileDir, file)); err != nil {
+			t.Errorf("init did not write %s: %v", file, err)
+		}
+	}
+	// The moving tag of the image setting is pinned at creation
+	cfg, err := config.ReadConfig("e2e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref, err := container.ParseImageRef(cfg.Image); err != nil || ref.Tagged() != "cowrie/cowrie:latest" ||
+		ref.Digest != containertest.ManifestDigest("cowrie/cowrie:latest") || ref.ID == "" {
+		t.Errorf("image of the new profile: %s (%v)", cfg.Image, err)
+	}
+	fake.Reset()
+
+	// deploy
+	out, err = runOtori(t, "deploy", "-p", "e2e")
+	if err != nil {
+		t.Fatalf("deploy: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "Honeypot 'e2e' deployed successfully") {
+		t.Errorf("deploy output:\n%s", out)
+	}
+	calls := fake.Calls()
+	want := []string{
+		"image ls -q cowrie/cowrie:latest",
+		"image inspect cowrie/cowrie:latest",
+		"image ls -q cowrie/cowrie:latest",
+		"image inspect cowrie/cowrie:latest",
+		"compose up -d",
+		"exec -i -e PYTHONPATH=/cowrie/cowrie-git/src otori-e2e /cowrie/cowrie-env/bin/python3 -m cowrie.scripts.fsctl /cowrie/cowrie-git/src/cowrie/data/fs.pickle",
+		"compose restart",
+	}
+	if got := fake.Commands(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("deploy commands:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if calls[4].Dir != profileDir || calls[6].Dir != profileDir {
+		t.Errorf("docker compose must run in the profile directory, got %q and %q", calls[4].Dir, calls[6].Dir)
+	}
+	if fsctl := calls[5].Stdin; !strings.Contains(fsctl, "touch /etc/share/secret.txt\n") || !strings.HasSuffix(fsctl, "\nexit\n") {
+		t.Errorf("fsctl input:\n%s", fsctl)
+	}
+	if running := fake.Running(); len(running) != 1 || running[0] != "otori-e2e" {
+		t.Errorf("running containers: %q", running)
+	}
+
+	// status
+	out, err = runOtori(t, "status")
+	if err != nil {
+		t.Fatalf("status: %v\n%s", err, out)
+	}
+	row := statusRow(out, "otori-e2e")
+	if fields := strings.Fields(row); len(fields) < 6 ||
+		fields[1] != "e2e" || fields[2] != "classic" || fields[3] != "active" || fields[4] != "web01" || fields[5] != "2222" {
+		t.Errorf("status row: %q\n%s", row, out)
+	}
+
+	// stop
+	out, err = runOtori(t, "stop", "-p", "e2e")
+	if err != nil {
+		t.Fatalf("stop: %v\n%s", err, out)
+	}
+	if got := fake.Commands(); got[len(got)-1] != "compose down" {
+		t.Errorf("stop ran %q", got[len(got)-1])
+	}
+	if running := fake.Running(); len(running) != 0 {
+		t.Errorf("containers still running after stop: %q", running)
+	}
+
+	// status of stopped profiles
+	out, err = runOtori(t, "status", "-a")
+	if err != nil {
+		t.Fatalf("status -a: %v\n%s", err, out)
+	}
+	if fields := strings.Fields(statusRow(out, "otori-e2e")); len(fields) < 4 || fields[3] != "stopped" {
+		t.Errorf("status -a:\n%s", out)
+	}
+}
+
+func TestInitInteractiveFlags(t *testing.T) {
+	t.Cleanup(func() { resetFlags(RootCmd) })
+
+	// Global flags still open the TUI, the flags of init do not
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"--home", t.TempDir(), "--color", "never"}, false},
+		{[]string{"--home", t.TempDir(), "-t", "classic"}, true},
+	} {
+		resetFlags(RootCmd)
+		if err := initCmd.ParseFlags(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if got := localFlagsChanged(initCmd); got != tt.want {
+			t.Errorf("localFlagsChanged(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestDeployErrors(t *testing.T) {
+	fake := setupE2E(t)
+
+	out, err := runOtori(t, "deploy", "-p", "missing")
+	if err == nil || !strings.Contains(out, "profile 'missing' not found") {
+		t.Errorf("deploy of a missing profile: %v\n%s", err, out)
+	}
+
+	if out, err := runOtori(t, "init", "-t", "classic", "-s", "web01", "-p", "broken"); err != nil {
+		t.Fatalf("init: %v\n%s", err, out)
+	}
+	fake.Reset()
+	fake.FailOn("compose up", errors.New("Cannot connect to the Docker daemon"))
+	out, err = runOtori(t, "deploy", "-p", "broken")
+	if err == nil || !strings.Contains(out, "failed to start containers") {
+		t.Errorf("deploy with a failing docker: %v\n%s", err, out)
+	}
+	if got := fake.Commands(); len(got) == 0 || got[len(got)-1] != "compose up -d" {
+		t.Errorf("deploy went on after the failure: %q", got)
+	}
+}
+
+func TestRenameWithoutRuntime(t *testing.T) {
+	fake := setupE2E(t)
+	if out, err := runOtori(t, "init", "-t", "classic", "-s", "web01", "-p", "web"); err != nil {
+		t.Fatalf("init: %v\n%s", err, out)
+	}
+
+	// The volumes cannot be migrated: nothing is renamed
+	fake.FailOn("ps", errors.New("Cannot connect to the Docker daemon"))
+	out, err := runOtori(t, "profiles", "rename", "web", "front")
+	if err == nil || !strings.Contains(out, "--skip-volumes") {
+		t.Errorf("rename without runtime: %v\n%s", err, out)
+	}
+	if !config.ProfileExists("web") || config.ProfileExists("front") {
+		t.Error("profile renamed without its volumes")
+	}
+
+	out, err = runOtori(t, "profiles", "rename", "web", "front", "--skip-volumes")
+	if err != nil || !strings.Contains(out, "otori-web-logs") {
+		t.Errorf("rename --skip-volumes: %v\n%s", err, out)
+	}
+	if config.ProfileExists("web") || !config.ProfileExists("front") {
+		t.Error("profile not renamed")
+	}
+}
+
+func TestRenderForce(t *testing.T) {
+	setupE2E(t)
+	if out, err := runOtori(t, "init", "-t", "classic", "-s", "web01", "-p", "web"); err != nil {
+		t.Fatalf("init: %v\n%s", err, out)
+	}
+
+	// A directory otori did not render is never cleared
+	foreign := t.TempDir()
+	kept := filepath.Join(foreign, "notes.txt")
+	if err := os.WriteFile(kept, []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := runOtori(t, "render", "-p", "web", "--out", foreign, "--force")
+	if err == nil || !strings.Contains(out, "refusing to clear it") {
+		t.Errorf("render --force over a foreign directory: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("render --force removed a foreign file: %v", err)
+	}
+
+	// An earlier render is replaced
+	rendered := filepath.Join(t.TempDir(), "web")
+	for range 2 {
+		if out, err := runOtori(t, "render", "-p", "web", "--out", rendered, "--force"); err != nil {
+			t.Fatalf("render --force: %v\n%s", err, out)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(rendered, "cowrie.cfg")); err != nil {
+		t.Error(err)
+	}
+}
+
+// statusRow returns the line of the status table describing a container
+func statusRow(out, name string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, name+" ") {
+			return line
+		}
+	}
+	return ""
+}
+
+// decodeDocument decodes a JSON document printed by otori and checks its kind
+func decodeDocument(t *testing.T, out, kind string, data any) {
+	t.Helper()
+
+	var doc struct {
+		SchemaVersion int             `json:"schemaVersion"`
+		Kind          string          `json:"kind"`
+		Data          json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("stdout is not a JSON document: %v\n%s", err, out)
+	}
+	if doc.SchemaVersion != schemaVersion || doc.Kind != kind {
+		t.Fatalf("document %s (schema %d), want %s\n%s", doc.Kind, doc.SchemaVersion, kind, out)
+	}
+	if err := json.Unmarshal(doc.Data, data); err != nil {
+		t.Fatalf("data of %s: %v\n%s", kind, err, out)
+	}
+}
+
+func TestStructuredOutput(t *testing.T) {
+	setupE2E(t)
+
+	processStdout := os.Stdout
+	stdout, stderr, err := runOtoriSplit(t, "init", "-t", "classic", "-s", "web01", "-p", "e2e", "-o", "json")
+	if err != nil {
+		t.Fatalf("init: %v\n%s", err, stderr)
+	}
+	if os.Stdout != processStdout {
+		t.Error("the standard output of the process was replaced")
+	}
+	var created initResult
+	decodeDocument(t, stdout, "InitResult", &created)
+	if created.Profile != "e2e" || created.Path != filepath.Join(config.GetConfigDir(), "e2e") {
+		t.Errorf("init result: %+v", created)
+	}
+	// Human messages go to stderr
+	if !strings.Contains(stderr, "Profile 'e2e' created successfully") {
+		t.Errorf("init stderr:\n%s", stderr)
+	}
+
+	stdout, stderr, err = runOtoriSplit(t, "deploy", "-p", "e2e", "-o", "json")
+	if err != nil {
+		t.Fatalf("deploy: %v\n%s", err, stderr)
+	}
+	var deployed deployResult
+	decodeDocument(t, stdout, "DeployResult", &deployed)
+	if deployed.Container != "otori-e2e" || deployed.Ports.SSH != 2222 || deployed.FSEntries == 0 || len(deployed.Warnings) != 0 {
+		t.Errorf("deploy result: %+v", deployed)
+	}
+
+	// --json and the output setting select JSON too
+	for _, args := range [][]string{{"status", "--json"}, {"status"}} {
+		if len(args) == 1 {
+			t.Setenv("OTORI_OUTPUT", "json")
+		}
+		stdout, stderr, err = runOtoriSplit(t, args...)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", args, err, stderr)
+		}
+		var honeypots []tui.Honeypot
+		decodeDocument(t, stdout, "HoneypotList", &honeypots)
+		if len(honeypots) != 1 || honeypots[0].Profile != "e2e" || honeypots[0].Status != tui.StatusActive {
+			t.Errorf("%s: %+v", args, honeypots)
+		}
+	}
+
+	// The output setting is ignored by the commands without JSON output
+	if out, err := runOtori(t, "profiles", "clone", "e2e", "copy"); err != nil {
+		t.Errorf("clone with OTORI_OUTPUT=json: %v\n%s", err, out)
+	}
+	t.Setenv("OTORI_OUTPUT", "")
+
+	stdout, _, err = runOtoriSplit(t, "profiles", "list", "-o", "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stdout, "schemaVersion: 1\nkind: ProfileList\ndata:\n") || !strings.Contains(stdout, "name: copy") {
+		t.Errorf("profiles list -o yaml:\n%s", stdout)
+	}
+
+	// Without a terminal, the table has no logo
+	stdout, _, err = runOtoriSplit(t, "profiles", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stdout, "\nAvailable profiles:") {
+		t.Errorf("profiles list without a terminal:\n%s", stdout)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	fake := setupE2E(t)
+	if out, err := runOtori(t, "init", "-t", "classic", "-s", "web01", "-p", "e2e"); err != nil {
+		t.Fatalf("init: %v\n%s", err, out)
+	}
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("type: other\nserverName: web01\nprofileName: imported\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"status", "--bogus"}, exitUsage},
+		{[]string{"bogus"}, exitUsage},
+		{[]string{"profiles", "show", "a", "b"}, exitUsage},
+		{[]string{"init", "-t", "classic"}, exitUsage},
+		{[]string{"profiles", "clone", "e2e", "copy", "-o", "json"}, exitUsage},
+		{[]string{"profiles", "export", "e2e", "-f", "xml"}, exitUsage},
+		{[]string{"profiles", "export", "e2e", "-f", "csv", "--delimiter", "#"}, exitUsage},
+		{[]string{"profiles", "export", "e2e", "-f", "csv", "--columns", "bogus=X"}, exitUsage},
+		{[]string{"profiles", "restore", "nope"}, exitNotFound},
+		{[]string{"deploy", "-p", "missing"}, exitNotFound},
+		{[]string{"profiles", "delete", "missing", "-y"}, exitNotFound},
+		{[]string{"init", "-t", "other", "-s", "web01", "-p", "bad"}, exitInvalid},
+		{[]string{"profiles", "import", invalid}, exitInvalid},
+		{[]string{"init", "-t", "classic", "-s", "web02", "-c", "Acme", "-u", "../../../../../../../../tmp/pwnx", "-p", "p2"}, exitInvalid},
+		{[]string{"profiles", "clone", "e2e", "e2e"}, exitConflict},
+		{[]string{"deploy", "-p", "e2e"}, exitRuntime},
+	}
+	fake.FailOn("compose up", errors.New("Cannot connect to the Docker daemon"))
+	for _, tt := range tests {
+		out, err := runOtori(t, tt.args...)
+		if code := exitCode(err); code != tt.code {
+			t.Errorf("%s: exit code %d, want %d (%v)\n%s", tt.args, code, tt.code, err, out)
+		}
+	}
+
+	// Errors are documents in JSON mode
+	_, stderr, err := runOtoriSplit(t, "deploy", "-p", "missing", "-o", "json")
+	var doc struct {
+		Kind  string        `json:"kind"`
+		Error errorDocument `json:"error"`
+	}
+	if jsonErr := json.Unmarshal([]byte(stderr), &doc); jsonErr != nil {
+		t.Fatalf("stderr is not a JSON document: %v\n%s", jsonErr, stderr)
+	}
+	if doc.Kind != "Error" || doc.Error.Code != "not_found" || doc.Error.ExitCode != exitNotFound || doc.Error.Message != err.Error() {
+		t.Errorf("error document: %+v", doc)
+	}
+}
+
+func TestAlertsInit(t *testing.T) {
+	setupE2E(t)
+
+	// A fresh --home has no directory yet
+	home := filepath.Join(t.TempDir(), "fresh")
+	if out, err := runOtori(t, "--home", home, "alerts", "init"); err != nil {
+		t.Fatalf("alerts init: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(home, "alerts.yaml")); err != nil {
+		t.Errorf("rules file not written: %v", err)
+	}
+	if out, err := runOtori(t, "--home", home, "alerts", "list"); err != nil || !strings.Contains(out, "bruteforce") {
+		t.Errorf("alerts list: %v\n%s", err, out)
+	}
+}
+
+func TestPinnedImage(t *testing.T) {
+	fake := setupE2E(t)
+	const id = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	pinned := "cowrie/cowrie:2.5.0@" + id
+
+	if out, err := runOtori(t, "init", "-t", "classic", "-s", "web01", "-p", "pinned", "--image", pinned); err != nil {
+		t.Fatalf("init: %v\n%s", err, out)
+	}
+	if out, err := runOtori(t, "init", "-t", "classic", "-s", "web01", "-p", "bad", "--image", "cowrie@sha256:123"); exitCode(err) != exitInvalid {
+		t.Errorf("init with an invalid digest: %v\n%s", err, out)
+	}
+
+	// Another image behind the same tag: nothing starts
+	fake.AddImage("cowrie/cowrie:2.5.0", "sha256:2222222222222222222222222222222222222222222222222222222222222222")
+	out, err := runOtori(t, "deploy", "-p", "pinned")
+	if exitCode(err) != exitConflict || !strings.Contains(out, "does not match the pinned digest") {
+		t.Errorf("deploy of a mismatching image: %v\n%s", err, out)
+	}
+	for _, call := range fake.Commands() {
+		if strings.HasPrefix(call, "compose") {
+			t.Fatalf("deploy started the honeypot with a mismatching image: %q", fake.Commands())
+		}
+	}
+
+	// The image moved from another machine as an archive
+	source := containertest.NewFake()
+	source.AddImage("cowrie/cowrie:2.5.0", id)
+	restore := source.Install()
+	archive := filepath.Join(t.TempDir(), "cowrie.tar")
+	out, err = runOtori(t, "image", "save", "-p", "pinned", "--file", archive)
+	restore()
+	if err != nil {
+		t.Fatalf("image save: %v\n%s", err, out)
+	}
+
+	stdout, stderr, err := runOtoriSplit(t, "image", "load", "--file", archive, "-p", "pinned", "-o", "json")
+	if err != nil {
+		t.Fatalf("image load: %v\n%s", err, stderr)
+	}
+	var loaded imageArchiveResult
+	decodeDocument(t, stdout, "ImageArchive", &loaded)
+	if len(loaded.Images) != 1 || loaded.Images[0] != "cowrie/cowrie:2.5.0" {
+		t.Errorf("image load result: %+v", loaded)
+	}
+
+	// The compose file names the tag, deploy checks the digest first
+	compose, err := os.ReadFile(filepath.Join(config.GetConfigDir(), "pinned", "docker-compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(compose), "image: cowrie/cowrie:2.5.0\n") {
+		t.Errorf("docker-compose.yml:\n%s", compose)
+	}
+	stdout, stderr, err = runOtoriSplit(t, "deploy", "-p", "pinned", "-o", "json")
+	if err != nil {
+		t.Fatalf("deploy: %v\n%s", err, stderr)
+	}
+	var deployed deployResult
+	decodeDocument(t, stdout, "DeployResult", &deployed)
+	if deployed.Image != pinned || deployed.ImageID != id {
+		t.Errorf("deploy result: %+v", deployed)
+	}
+
+	stdout, _, err = runOtoriSplit(t, "status", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var honeypots []tui.Honeypot
+	decodeDocument(t, stdout, "HoneypotList", &honeypots)
+	if len(honeypots) != 1 || honeypots[0].Image != pinned || honeypots[0].ImageID != id {
+		t.Errorf("status: %+v", honeypots)
+	}
+	if out, _ := runOtori(t, "status"); !strings.Contains(statusRow(out, "otori-pinned"), "cowrie/cowrie:2.5.0 (111111111111)") {
+		t.Errorf("status table:\n%s", out)
+	}
+}
+
+func TestImagePins(t *testing.T) {
+	setupE2E(t)
+
+	// The pin printed by image pull holds both digests
+	stdout, stderr, err := runOtoriSplit(t, "image", "pull", "cowrie/cowrie:2.5.0")
+	if err != nil {
+		t.Fatalf("image pull: %v\n%s", err, stderr)
+	}
+	digest := containertest.ManifestDigest("cowrie/cowrie:2.5.0")
+	var id string
+	for _, line := range strings.Split(stdout, "\n") {
+		if value, ok := strings.CutPrefix(line, "  ID: "); ok {
+			id = value
+		}
+	}
+	pinned := "cowrie/cowrie:2.5.0@" + digest + "@id=" + id
+	if id == "" || !strings.Contains(stdout, "  "+pinned+"\n") {
+		t.Fatalf("image pull output:\n%s", stdout)
+	}
+
+	// config set image pins the tag it is given
+	if out, err := runOtori(t, "config", "set", "image", "cowrie/cowrie:2.5.0"); err != nil {
+		t.Fatalf("config set image: %v\n%s", err, out)
+	}
+	if setting, _ := config.GetSetting("image"); setting.Value != pinned {
+		t.Errorf("image setting %s, want %s", setting.Value, pinned)
+	}
+	if out, err := runOtori(t, "init", "-t", "classic", "-s", "web01", "-p", "web"); err != nil {
+		t.Fatalf("init: %v\n%s", err, out)
+	}
+
+	// A new host pulls the image by its registry digest
+	host := containertest.NewFake()
+	restore := host.Install()
+	out, err := runOtori(t, "deploy", "-p", "web")
+	restore()
+	if err != nil {
+		t.Fatalf("deploy on a new host: %v\n%s", err, out)
+	}
+	if !slices.Contains(host.Commands(), "pull cowrie/cowrie@"+digest) {
+		t.Errorf("deploy on a new host: %q", host.Commands())
+	}
+
+	// An air-gapped host gets the image by save/load, without registry digest
+	archive := filepath.Join(t.TempDir(), "cowrie.tar")
+	if out, err := runOtori(t, "image", "save", "-p", "web", "--file", archive); err != nil {
+		t.Fatalf("image save: %v\n%s", err, out)
+	}
+	airGapped := containertest.NewFake()
+	restore = airGapped.Install()
+	if out, err := runOtori(t, "image", "load", "-p", "web", "--file", archive); err != nil {
+		t.Fatalf("image load: %v\n%s", err, out)
+	}
+	out, err = runOtori(t, "deploy", "-p", "web")
+	restore()
+	if err != nil {
+		t.Fatalf("deploy on an air-gapped host: %v\n%s", err, out)
+	}
+	for _, call := range airGapped.Commands() {
+		if strings.HasPrefix(call, "pull") {
+			t.Errorf("air-gapped deploy pulled the image: %q", airGapped.Commands())
+		}
+	}
+
+	// Neither digest matches another image behind the tag
+	other := containertest.NewFake()
+	other.AddImage("cowrie/cowrie:2.5.0", "sha256:2222222222222222222222222222222222222222222222222222222222222222", "cowrie/cowrie@sha256:3333333333333333333333333333333333333333333333333333333333333333")
+	restore = other.Install()
+	out, err = runOtori(t, "deploy", "-p", "web")
+	restore()
+	if exitCode(err) != exitConflict {
+		t.Errorf("deploy of another image: %v\n%s", err, out)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	fake := setupE2E(t)
+
+	if out, err := runOtori(t, "init", "-t", "classic", "-s", "web01", "-p", "web"); err != nil {
+		t.Fatalf("init: %v\n%s", err, out)
+	}
+	fake.Reset()
+
+	// The build context alone
+	context := filepath.Join(t.TempDir(), "context")
+	if out, err := runOtori(t, "build", "-p", "web", "--no-build", "--context", context); err != nil {
+		t.Fatalf("build --no-build: %v\n%s", err, out)
+	}
+	for _, name := range []string{"Dockerfile", "cowrie.cfg", "userdb.txt", "honeyfs", "txtcmds"} {
+		if _, err := os.Stat(filepath.Join(context, name)); err != nil {
+			t.Errorf("build context: %v", err)
+		}
+	}
+	if len(fake.Commands()) != 0 {
+		t.Errorf("build --no-build ran %q", fake.Commands())
+	}
+	if out, err := runOtori(t, "build", "-p", "web", "--no-build"); exitCode(err) != exitUsage {
+		t.Errorf("build --no-build without --context: %v\n%s", err, out)
+	}
+
+	stdout, stderr, err := runOtoriSplit(t, "build", "-p", "web", "--use", "-o", "json")
+	if err != nil {
+		t.Fatalf("build: %v\n%s", err, stderr)
+	}
+	var built buildResult
+	decodeDocument(t, stdout, "BuildResult", &built)
+	if !strings.HasPrefix(built.Image, "otori/web:") || built.ImageID == "" || !built.Built || !built.Baked {
+		t.Errorf("build result: %+v", built)
+	}
+	if calls := fake.Commands(); len(calls) < 5 || !strings.HasPrefix(calls[4], "build -t "+built.Image+" ") {
+		t.Errorf("build commands: %q", calls)
+	}
+
+	// The baked profile runs the built image without mounting its files
+	compose, err := os.ReadFile(filepath.Join(config.GetConfigDir(), "web", "docker-compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(compose), "image: otori/web:baked\n") || strings.Contains(string(compose), "./cowrie.cfg") {
+		t.Errorf("docker-compose.yml:\n%s", compose)
+	}
+	stdout, stderr, err = runOtoriSplit(t, "deploy", "-p", "web", "-o", "json")
+	if err != nil {
+		t.Fatalf("deploy: %v\n%s", err, stderr)
+	}
+	var deployed deployResult
+	decodeDocument(t, stdout, "DeployResult", &deployed)
+	if deployed.Image != built.Image || deployed.ImageID != built.ImageID || deployed.FSEntries != 0 {
+		t.Errorf("deploy result: %+v", deployed)
+	}
+	for _, call := range fake.Commands() {
+		if strings.HasPrefix(call, "exec") {
+			t.Errorf("deploy of a baked image updated fs.pickle: %q", fake.Commands())
+		}
+	}
+	if !slices.Contains(fake.Commands(), "tag "+built.Image+" otori/web:baked") {
+		t.Errorf("deploy did not tag the build of the current files: %q", fake.Commands())
+	}
+
+	// A file edited by hand needs a new image
+	motd := filepath.Join(config.GetConfigDir(), "web", "honeyfs", "etc", "motd")
+	if err := os.WriteFile(motd, []byte("Authorized users only\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := runOtori(t, "deploy", "-p", "web"); exitCode(err) != exitNotFound || !strings.Contains(out, "otori build -p web") {
+		t.Errorf("deploy of a stale build: %v\n%s", err, out)
+	}
+	stdout, stderr, err = runOtoriSplit(t, "build", "-p", "web", "-o", "json")
+	if err != nil {
+		t.Fatalf("build: %v\n%s", err, stderr)
+	}
+	var rebuilt buildResult
+	decodeDocument(t, stdout, "BuildResult", &rebuilt)
+	if rebuilt.Image == built.Image {
+		t.Errorf("build of edited files reused the name %s", built.Image)
+	}
+	if out, err := runOtori(t, "deploy", "-p", "web"); err != nil {
+		t.Errorf("deploy of the new build: %v\n%s", err, out)
+	}
+
+	// A changed configuration needs a new image
+	cfg, err := config.ReadConfig("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Company = "Other Corp"
+	if err := config.WriteConfigWithName("web", cfg); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := runOtori(t, "deploy", "-p", "web"); exitCode(err) != exitNotFound || !strings.Contains(out, "otori build -p web") {
+		t.Errorf("deploy of a stale build: %v\n%s", err, out)
+	}
+}
+
+func TestService(t *testing.T) {
+	fake := setupE2E(t)
+	system := t.TempDir()
+	t.Cleanup(service.SetSystemDirs(filepath.Join(system, "systemd"), filepath.Join(system, "containers")))
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("INVOCATION_ID", "")
+
+	if out, err := runOtori(t, "init", "-t", "classic", "-s", "web01", "-p", "web"); err != nil {
+		t.Fatalf("init: %v\n%s", err, out)
+	}
+	fake.Reset()
+
+	// The unit file alone
+	out, err := runOtori(t, "service", "install", "-p", "web", "--dry-run")
+	if err != nil || !strings.Contains(out, " deploy -p web\n") || !strings.Contains(out, "OTORI_HOME="+config.GetOtoriDir()) {
+		t.Fatalf("service install --dry-run: %v\n%s", err, out)
+	}
+	if len(fake.Commands()) != 0 || len(service.List()) != 0 {
+		t.Fatalf("service install --dry-run installed the unit: %q", fake.Commands())
+	}
+
+	stdout, stderr, err := runOtoriSplit(t, "service", "install", "-p", "web", "-o", "json")
+	if err != nil {
+		t.Fatalf("service install: %v\n%s", err, stderr)
+	}
+	var installed serviceResult
+	decodeDocument(t, stdout, "Service", &installed)
+	if installed.Kind != service.KindSystemd || installed.Scope != service.ScopeSystem || installed.Active != "active" || installed.Enabled != "enabled" {
+		t.Errorf("service install result: %+v", installed)
+	}
+	if _, err := os.Stat(filepath.Join(system, "systemd", "otori-web.service")); err != nil {
+		t.Error(err)
+	}
+	calls := strings.Join(fake.Commands(), "\n")
+	if !strings.Contains(calls, "systemctl daemon-reload\nsystemctl enable --now otori-web.service") {
+		t.Errorf("service install commands:\n%s", calls)
+	}
+
+	if out, err := runOtori(t, "service", "install", "-p", "web", "--quadlet"); exitCode(err) != exitUsage {
+		t.Errorf("service install --quadlet with docker: %v\n%s", err, out)
+	}
+
+	// Stopping by hand does not survive a reboot
+	if out, err := runOtori(t, "stop", "-p", "web"); err != nil || !strings.Contains(out, "otori service stop -p web") {
+		t.Errorf("stop of a service: %v\n%s", err, out)
+	}
+	if out, err := runOtori(t, "service", "stop", "-p", "web"); err != nil || !strings.Contains(out, "Active:  inactive") {
+		t.Errorf("service stop: %v\n%s", err, out)
+	}
+
+	// A Quadlet file in the user manager
+	if out, err := runOtori(t, "service", "install", "-p", "web", "--quadlet", "--scope", "user", "--runtime", "podman", "--no-start"); err != nil {
+		t.Fatalf("service install --quadlet: %v\n%s", err, out)
+	}
+	if out, err := runOtori(t, "service", "status", "-p", "web"); exitCode(err) != exitUsage {
+		t.Errorf("service status of two services: %v\n%s", err, out)
+	}
+	stdout, _, err = runOtoriSplit(t, "service", "list", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var services []serviceResult
+	decodeDocument(t, stdout, "ServiceList", &services)
+	if len(services) != 2 || services[1].Kind != service.KindQuadlet || services[1].Scope != service.ScopeUser || services[1].Active != "inactive" {
+		t.Errorf("service list: %+v", services)
+	}
+
+	if out, err := runOtori(t, "service", "uninstall", "-p", "web", "--scope", "system"); err != nil {
+		t.Fatalf("service uninstall: %v\n%s", err, out)
+	}
+	if units := service.Find("web", ""); len(units) != 1 || units[0].Scope != service.ScopeUser {
+		t.Errorf("services after uninstall: %+v", units)
+	}
+	if out, err := runOtori(t, "service", "uninstall", "-p", "web", "--scope", "system"); exitCode(err) != exitNotFound {
+		t.Errorf("service uninstall of a missing service: %v\n%s", err, out)
+	}
+
+	// The unit left would deploy a profile that is gone
+	for _, args := range [][]string{{"profiles", "delete", "web", "-y"}, {"profiles", "rename", "web", "front"}} {
+		if out, err := runOtori(t, args...); exitCode(err) != exitConflict || !strings.Contains(out, "otori service uninstall -p web") {
+			t.Errorf("%s of a profile run by a service: %v\n%s", args, err, out)
+		}
+	}
+	if !config.ProfileExists("web") || config.ProfileExists("front") {
+		t.Error("profile run by a service deleted or renamed")
+	}
+	if out, err := runOtori(t, "service", "uninstall", "-p", "web"); err != nil {
+		t.Fatalf("service uninstall: %v\n%s", err, out)
+	}
+	if out, err := runOtori(t, "profiles", "rename", "web", "front"); err != nil {
+		t.Errorf("rename after service uninstall: %v\n%s", err, out)
+	}
+}
@@ -35,7 +35,7 @@ func EditCommand(profileName string) error {
 
 	// If user cancelled
 	if m.IsCancelled() {
-		fmt.Println("Edit cancelled")
+		fmt.Fprintln(out, "Edit cancelled")
 		return nil
 	}
 
@@ -61,18 +61,18 @@ func EditCommand(profileName string) error {
 
 	// If user declined
 	if !preview.IsConfirmed() || preview.IsCancelled() {
-		fmt.Println("\nEdit cancelled")
+		fmt.Fprintln(out, "\nEdit cancelled")
 		return nil
 	}
 
 	// Validate configuration before saving
 	validationErrors := config.ValidateConfig(finalConfig)
 	if len(validationErrors) > 0 {
-		fmt.Println("Validation errors:")
+		fmt.Fprintln(out, "Validation errors:")
 		for _, err := range validationErrors {
-			fmt.Printf("  - %s: %s\n", err.Field, err.Message)
+			fmt.Fprintf(out, "  - %s: %s\n", err.Field, err.Message)
 		}
-		return fmt.Errorf("configuration validation failed")
+		return validationError(validationErrors)
 	}
 
 	// Write modified configuration
@@ -80,6 +80,7 @@ func EditCommand(profileName string) error {
 		return fmt.Errorf("save error: %w", err)
 	}
 
-	fmt.Printf("✓ Profile '%s' updated successfully\n", profileName)
+	fmt.Fprintf(out, "✓ Profile '%s' updated successfully\n", profileName)
+	printRenderConflicts(profileName)
 	return nil
 }
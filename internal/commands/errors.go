@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of otori (documented in internal/commands/README.md)
+const (
+	exitOK       = 0
+	exitError    = 1 // any other failure
+	exitUsage    = 2 // unknown command or flag, bad arguments
+	exitNotFound = 3 // profile, template, archive or file not found
+	exitInvalid  = 4 // invalid profile configuration
+	exitConflict = 5 // already exists, locked by another otori process, or image digest mismatch
+	exitRuntime  = 6 // the container CLI (docker, podman) failed
+)
+
+// errorCodes are the names of the exit codes in the JSON/YAML errors
+var errorCodes = map[int]string{
+	exitError:    "error",
+	exitUsage:    "usage",
+	exitNotFound: "not_found",
+	exitInvalid:  "invalid_config",
+	exitConflict: "conflict",
+	exitRuntime:  "runtime",
+}
+
+// cliError is an error with its exit code and optional details for the JSON/YAML output
+type cliError struct {
+	code    int
+	err     error
+	details any
+}
+
+func (e *cliError) Error() string {
+	return e.err.Error()
+}
+
+func (e *cliError) Unwrap() error {
+	return e.err
+}
+
+// withCode gives an exit code to an error
+func withCode(code int, err error) error {
+	return &cliError{code: code, err: err}
+}
+
+// usageError returns an error of the command line (exit code 2)
+func usageError(format string, args ...any) error {
+	return withCode(exitUsage, fmt.Errorf(format, args...))
+}
+
+// validationError returns the error of a profile failing config.ValidateConfig (exit code 4)
+func validationError(errs []config.ValidationError) error {
+	return &cliError{code: exitInvalid, err: fmt.Errorf("configuration validation failed"), details: errs}
+}
+
+// exitCode returns the exit code matching an error
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var cliErr *cliError
+	var runtimeErr *container.RuntimeError
+	switch {
+	case errors.As(err, &cliErr):
+		return cliErr.code
+	case errors.Is(err, config.ErrNotFound), errors.Is(err, fs.ErrNotExist):
+		return exitNotFound
+	case errors.Is(err, config.ErrExists), errors.Is(err, config.ErrLocked), errors.Is(err, container.ErrDigestMismatch):
+		return exitConflict
+	case errors.Is(err, config.ErrInvalid):
+		return exitInvalid
+	case errors.As(err, &runtimeErr):
+		return exitRuntime
+	}
+
+	// Errors of cobra itself
+	msg := err.Error()
+	if strings.HasPrefix(msg, "unknown command") || strings.HasPrefix(msg, "required flag") {
+		return exitUsage
+	}
+	return exitError
+}
+
+// errorDocument is the "error" field of the JSON/YAML errors
+type errorDocument struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	ExitCode int    `json:"exitCode"`
+	Details  any    `json:"details,omitempty"`
+}
+
+// printError prints the error of a command on stderr: "Error: ..." or,
+// when JSON/YAML was asked for, an Error document
+func printError(err error) {
+	code := exitCode(err)
+	format := errorFormat()
+	if format == formatTable {
+		fmt.Fprintln(errOut, "Error:", err)
+		return
+	}
+
+	doc := errorDocument{Code: errorCodes[code], Message: err.Error(), ExitCode: code}
+	var cliErr *cliError
+	if errors.As(err, &cliErr) {
+		doc.Details = cliErr.details
+	}
+	if encodeErr := writeDocument(errOut, format, document{SchemaVersion: schemaVersion, Kind: "Error", Error: &doc}); encodeErr != nil {
+		fmt.Fprintln(errOut, "Error:", err)
+	}
+}
+
+// errorFormat is the format of the errors: the one of the command, or the
+// -o flag when the command failed before it was applied (flag parsing)
+func errorFormat() string {
+	if structuredOutput() {
+		return outputFormat
+	}
+	if rootOutput == formatJSON || rootOutput == formatYAML {
+		return rootOutput
+	}
+	return formatTable
+}
+
+// usageArgsOnce wraps the argument checks of the commands once
+var usageArgsOnce sync.Once
+
+// markUsageErrors makes the argument errors of every command usage errors
+// (flag errors are marked by the flag error function of RootCmd)
+func markUsageErrors(cmd *cobra.Command) {
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(c *cobra.Command, a []string) error {
+			if err := args(c, a); err != nil {
+				return withCode(exitUsage, err)
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
+
+// execute runs the command line and prints its error
+func execute() error {
+	usageArgsOnce.Do(func() { markUsageErrors(RootCmd) })
+
+	// The writers of the root command until the command is known (flag errors)
+	outputFormat = formatTable
+	setWriters(RootCmd)
+	err := RootCmd.Execute()
+	if err != nil {
+		printError(err)
+	}
+	outputFormat = formatTable
+	return err
+}
+
+// Execute runs otori and returns its exit code
+func Execute() int {
+	return exitCode(execute())
+}
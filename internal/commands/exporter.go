@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/metrics"
+	"github.com/spf13/cobra"
+)
+
+var exporterListen string
+var exporterMaxUsernames int
+var exporterDashboardOut string
+
+// exporterCmd serves Prometheus metrics
+var exporterCmd = &cobra.Command{
+	Use:   "exporter",
+	Short: "Expose honeypot metrics for Prometheus",
+	Long: "Serve per-profile gauges and counters on /metrics in the Prometheus text format.\n" +
+		"Metrics are built from the container runtime and the Cowrie event stream.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runExporter()
+	},
+}
+
+// exporterDashboardCmd writes an example Grafana dashboard
+var exporterDashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Short: "Generate an example Grafana dashboard (JSON)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExporterDashboard()
+	},
+}
+
+func runExporter() error {
+	collector := metrics.NewCollector(exporterMaxUsernames)
+	defer collector.Stop()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := metrics.WriteText(&buf, collector.Gather()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "<html><head><title>Otori exporter</title></head><body>"+
+			"<h1>Otori exporter</h1><p><a href=\"/metrics\">Metrics</a></p></body></html>\n")
+	})
+
+	server := &http.Server{
+		Addr:              exporterListen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	// Start following running honeypots before the first scrape
+	collector.Gather()
+
+	fmt.Fprintf(out, "Serving metrics on http://%s/metrics (Ctrl+C to stop)\n", exporterListen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("exporter failed: %w", err)
+	}
+
+	return nil
+}
+
+func runExporterDashboard() error {
+	data, err := metrics.Dashboard()
+	if err != nil {
+		return fmt.Errorf("error encoding dashboard: %w", err)
+	}
+
+	if exporterDashboardOut == "" {
+		fmt.Fprintln(out, string(data))
+		return nil
+	}
+
+	if err := os.WriteFile(exporterDashboardOut, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	fmt.Fprintf(out, "✓ Grafana dashboard written to %s\n", exporterDashboardOut)
+	return nil
+}
+
+func init() {
+	exporterCmd.Flags().StringVarP(&exporterListen, "listen", "l", "127.0.0.1:9464", "Address to serve metrics on")
+	exporterCmd.Flags().IntVar(&exporterMaxUsernames, "max-usernames", 50, "Max distinct username labels per profile (0 for unlimited)")
+
+	exporterDashboardCmd.Flags().StringVarP(&exporterDashboardOut, "output", "o", "", "Output file (default: stdout)")
+
+	exporterCmd.AddCommand(exporterDashboardCmd)
+	RootCmd.AddCommand(exporterCmd)
+}
@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/spf13/cobra"
+)
+
+var imageProfile string
+var imageFile string
+
+// imageCmd is the parent command of the Cowrie image management
+var imageCmd = &cobra.Command{
+	Use:   "image",
+	Short: "Pull, save and load the Cowrie image of the profiles",
+	Long: "Manage the Cowrie image of a profile (image field of the profile, or image setting).\n" +
+		"A reference pinned with digests (repository:tag@sha256:<digest>@id=sha256:<image ID>)\n" +
+		"is pulled by its registry digest and checked by deploy against either digest.\n" +
+		"save and load move images as tar archives to machines without registry access.",
+}
+
+// imagePullCmd pulls the image of a profile
+var imagePullCmd = &cobra.Command{
+	Use:         "pull [reference]",
+	Short:       "Pull the image of a profile (or the given reference)",
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runImagePull(args)
+	},
+}
+
+// imageSaveCmd writes the image of a profile to a tar archive
+var imageSaveCmd = &cobra.Command{
+	Use:         "save [reference]",
+	Short:       "Save the image of a profile (or the given reference) to a tar archive",
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runImageSave(args)
+	},
+}
+
+// imageLoadCmd loads images from a tar archive
+var imageLoadCmd = &cobra.Command{
+	Use:         "load",
+	Short:       "Load images from a tar archive (checks the image of a profile with -p)",
+	Args:        cobra.NoArgs,
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runImageLoad(cmd.Flags().Changed("profile"))
+	},
+}
+
+// imageResult is the JSON/YAML result of image pull (kind Image)
+type imageResult struct {
+	Reference   string   `json:"reference"`
+	ID          string   `json:"id"`
+	RepoDigests []string `json:"repoDigests"`
+}
+
+// imageArchiveResult is the JSON/YAML result of image save and load (kind ImageArchive)
+type imageArchiveResult struct {
+	File   string   `json:"file"`
+	Images []string `json:"images"`
+}
+
+// imageReference returns the image reference given as argument, or the one of the profile
+func imageReference(args []string) (container.ImageRef, error) {
+	if len(args) > 0 {
+		ref, err := container.ParseImageRef(args[0])
+		if err != nil {
+			return ref, withCode(exitUsage, err)
+		}
+		return ref, nil
+	}
+
+	profileName := imageProfile
+	if profileName == "" {
+		profileName = config.DefaultProfile()
+	}
+	cfg, err := config.ReadEffectiveConfig(profileName)
+	if err != nil {
+		return container.ImageRef{}, fmt.Errorf("profile '%s' not found: %w", profileName, err)
+	}
+	return container.ParseImageRef(config.ProfileImage(cfg))
+}
+
+func runImagePull(args []string) error {
+	ref, err := imageReference(args)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Pulling %s...\n", ref)
+	if err := container.PullImage(ref); err != nil {
+		return err
+	}
+	img, err := container.VerifyImage(ref)
+	if err != nil {
+		return err
+	}
+	if img == nil {
+		return fmt.Errorf("image %s %w after pull", ref.Tagged(), config.ErrNotFound)
+	}
+
+	fmt.Fprintf(out, "✓ Image %s pulled\n", ref)
+	fmt.Fprintf(out, "  ID: %s\n", img.ID)
+	for _, digest := range img.RepoDigests {
+		fmt.Fprintf(out, "  Digest: %s\n", digest)
+	}
+	// The registry digest pulls the image, the image ID survives save/load
+	if pinned := img.Pin(ref); pinned.String() != ref.String() {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Pin it in the profile or the image setting with both digests:")
+		fmt.Fprintf(out, "  %s\n", pinned)
+	}
+
+	if structuredOutput() {
+		repoDigests := img.RepoDigests
+		if repoDigests == nil {
+			repoDigests = []string{}
+		}
+		return printResult("Image", imageResult{Reference: ref.String(), ID: img.ID, RepoDigests: repoDigests})
+	}
+	return nil
+}
+
+func runImageSave(args []string) error {
+	if imageFile == "" {
+		return usageError("please specify the archive with --file")
+	}
+	ref, err := imageReference(args)
+	if err != nil {
+		return err
+	}
+
+	// Never ship an image that is not the pinned one
+	img, err := container.VerifyImage(ref)
+	if err != nil {
+		return err
+	}
+	if img == nil {
+		return fmt.Errorf("image %s %w locally (run 'otori image pull')", ref.Tagged(), config.ErrNotFound)
+	}
+
+	fmt.Fprintf(out, "Saving %s to %s...\n", ref.Tagged(), imageFile)
+	if err := container.SaveImage(ref.Tagged(), imageFile); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "✓ Image %s (%s) saved to %s\n", ref.Tagged(), container.ShortID(img.ID), imageFile)
+	fmt.Fprintf(out, "Load it on the target machine with: otori image load --file %s\n", imageFile)
+
+	if structuredOutput() {
+		return printResult("ImageArchive", imageArchiveResult{File: imageFile, Images: []string{ref.Tagged()}})
+	}
+	return nil
+}
+
+func runImageLoad(checkProfile bool) error {
+	if imageFile == "" {
+		return usageError("please specify the archive with --file")
+	}
+
+	fmt.Fprintf(out, "Loading %s...\n", imageFile)
+	images, err := container.LoadImage(imageFile)
+	if err != nil {
+		return err
+	}
+	if images == nil {
+		images = []string{}
+	}
+	for _, image := range images {
+		fmt.Fprintf(out, "✓ Loaded %s\n", image)
+	}
+
+	// Check that the archive holds the image pinned by the profile
+	if checkProfile {
+		ref, err := imageReference(nil)
+		if err != nil {
+			return err
+		}
+		img, err := container.VerifyImage(ref)
+		if err != nil {
+			return err
+		}
+		if img == nil {
+			return fmt.Errorf("image %s of profile '%s' %w in %s", ref.Tagged(), imageProfile, config.ErrNotFound, imageFile)
+		}
+		fmt.Fprintf(out, "✓ Image %s of profile '%s' verified (%s)\n", ref.Tagged(), imageProfile, container.ShortID(img.ID))
+	}
+
+	if structuredOutput() {
+		return printResult("ImageArchive", imageArchiveResult{File: imageFile, Images: images})
+	}
+	return nil
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{imagePullCmd, imageSaveCmd, imageLoadCmd} {
+		cmd.Flags().StringVarP(&imageProfile, "profile", "p", "", "Profile whose image is used (default: defaultProfile setting)")
+	}
+	imageSaveCmd.Flags().StringVarP(&imageFile, "file", "f", "", "Tar archive to write")
+	imageLoadCmd.Flags().StringVarP(&imageFile, "file", "f", "", "Tar archive to read")
+
+	imageCmd.AddCommand(imagePullCmd)
+	imageCmd.AddCommand(imageSaveCmd)
+	imageCmd.AddCommand(imageLoadCmd)
+	RootCmd.AddCommand(imageCmd)
+}
@@ -1,19 +1,19 @@
 package commands
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 
 	"github.com/otori-lab/otori-cli/internal/config"
-	"github.com/otori-lab/otori-cli/internal/ui"
 )
 
-// ExportCommand exports a profile
-func ExportCommand(profileName, outputPath, format string) error {
-	fmt.Println(ui.GetLogo())
+// ExportCommand exports one or many profiles to a single file
+func ExportCommand(profileNames []string, outputPath, format string, csvOpts config.CSVOptions) error {
+	printLogo()
 
-	if profileName == "" {
-		return fmt.Errorf("please specify the profile to export")
+	if len(profileNames) == 0 {
+		return usageError("please specify the profile to export")
 	}
 
 	// Normalize format
@@ -30,65 +30,153 @@ func ExportCommand(profileName, outputPath, format string) error {
 		exportFormat = config.FormatCSV
 	case "json":
 		exportFormat = config.FormatJSON
+	case "bundle", "tar.gz", "tgz":
+		exportFormat = config.FormatBundle
 	default:
-		return fmt.Errorf("unsupported format: %s (use: yaml, csv)", format)
+		return usageError("unsupported format: %s (use: yaml, csv, json, bundle)", format)
 	}
 
-	if err := config.ExportConfig(profileName, exportFormat, outputPath); err != nil {
+	if err := config.ExportConfigs(profileNames, exportFormat, outputPath, csvOpts); err != nil {
 		return err
 	}
 
 	if outputPath == "" {
-		outputPath = "exports/" + profileName + "." + format
+		outputPath = config.DefaultExportPath(profileNames, exportFormat)
 	}
 
-	fmt.Printf("✓ Profile '%s' exported to %s\n", profileName, outputPath)
+	if len(profileNames) == 1 {
+		fmt.Fprintf(out, "✓ Profile '%s' exported to %s\n", profileNames[0], outputPath)
+	} else {
+		fmt.Fprintf(out, "✓ %d profiles exported to %s\n", len(profileNames), outputPath)
+	}
 	return nil
 }
 
-// ImportCommand imports a configuration
-func ImportCommand(filePath, profileName string) error {
-	fmt.Println(ui.GetLogo())
+// ImportOptions are the flags of the import command
+type ImportOptions struct {
+	Name       string
+	OnConflict string
+	DryRun     bool
+	CSV        config.CSVOptions
+}
+
+// ImportCommand imports the profiles of a file ("-" for stdin).
+// The format (JSON, YAML, CSV or bundle) is detected from the extension or the content,
+// every entry is validated and reported separately.
+func ImportCommand(filePath string, opts ImportOptions) error {
+	printLogo()
 
 	if filePath == "" {
-		return fmt.Errorf("please specify the file path to import")
+		return usageError("please specify the file path to import")
 	}
 
-	// Determine format from extension
-	var ext string
-	if len(filePath) > 4 {
-		ext = strings.ToLower(filePath[len(filePath)-4:])
-		if ext == ".yml" || ext == ".yaml" {
-			if err := config.ImportYAML(filePath, profileName); err != nil {
-				return err
-			}
-		} else if ext == ".json" {
-			// JSON: read and import as is
-			cfg, err := config.ReadConfig(filePath[:len(filePath)-5])
-			if err != nil {
-				return fmt.Errorf("error reading JSON: %w", err)
-			}
-			if profileName != "" {
-				cfg.ProfileName = profileName
-			}
-			if err := config.WriteConfig(cfg); err != nil {
-				return err
-			}
-		} else if ext == ".csv" {
-			if err := config.ImportCSV(filePath, profileName); err != nil {
-				return err
+	data, format, err := config.ReadImportFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	// Bundles carry the whole profile directory
+	if format == config.FormatBundle {
+		if opts.DryRun {
+			return usageError("--dry-run is not supported for bundles")
+		}
+		return importBundle(data, opts.Name, opts.OnConflict)
+	}
+
+	entries, err := config.ParseProfiles(data, format, opts.CSV)
+	if err != nil {
+		return err
+	}
+
+	origin := filePath
+	if filePath == "-" {
+		origin = ""
+	}
+	results, err := config.ImportProfiles(entries, config.ImportOptions{
+		Name:       opts.Name,
+		OnConflict: opts.OnConflict,
+		Origin:     origin,
+		DryRun:     opts.DryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		fmt.Fprintf(out, "Dry run: %d profile(s) in %s (%s)\n\n", len(results), displayPath(filePath), format)
+	} else {
+		fmt.Fprintf(out, "Importing %d profile(s) from %s (%s)\n\n", len(results), displayPath(filePath), format)
+	}
+
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+		name := result.Profile
+		if name == "" {
+			name = "(unnamed)"
+		}
+
+		switch result.Status {
+		case config.ImportFailed:
+			fmt.Fprintf(out, "  ✗ %-8s %s\n", result.Source, name)
+			for _, msg := range result.Errors {
+				fmt.Fprintf(out, "      - %s\n", msg)
 			}
-		} else {
-			return fmt.Errorf("unsupported format: %s", ext)
+		case config.ImportSkipped:
+			fmt.Fprintf(out, "  - %-8s %s: skipped (already exists)\n", result.Source, name)
+		case config.ImportUnchanged:
+			fmt.Fprintf(out, "  = %-8s %s: unchanged\n", result.Source, name)
+		case config.ImportRenamed:
+			fmt.Fprintf(out, "  + %-8s %s: create (renamed, name already taken)\n", result.Source, name)
+		case config.ImportUpdated:
+			fmt.Fprintf(out, "  ~ %-8s %s: update\n", result.Source, name)
+		default:
+			fmt.Fprintf(out, "  + %-8s %s: create\n", result.Source, name)
 		}
+	}
+
+	created := counts[config.ImportCreated] + counts[config.ImportRenamed]
+	if opts.DryRun {
+		fmt.Fprintf(out, "\nDry run: %d to create, %d to update, %d unchanged, %d skipped, %d error(s). Nothing was written.\n",
+			created, counts[config.ImportUpdated], counts[config.ImportUnchanged], counts[config.ImportSkipped], counts[config.ImportFailed])
 	} else {
-		return fmt.Errorf("invalid file: %s", filePath)
+		fmt.Fprintf(out, "\n%d created, %d updated, %d unchanged, %d skipped, %d failed\n",
+			created, counts[config.ImportUpdated], counts[config.ImportUnchanged], counts[config.ImportSkipped], counts[config.ImportFailed])
 	}
 
-	if profileName == "" {
-		profileName = "imported"
+	if counts[config.ImportFailed] > 0 {
+		return withCode(exitInvalid, fmt.Errorf("%d profile(s) could not be imported", counts[config.ImportFailed]))
 	}
+	return nil
+}
 
-	fmt.Printf("✓ Configuration imported as '%s'\n", profileName)
+// displayPath names the import source in messages
+func displayPath(filePath string) string {
+	if filePath == "-" {
+		return "stdin"
+	}
+	return filePath
+}
+
+// importBundle imports a profile bundle (tar.gz)
+func importBundle(data []byte, profileName, onConflict string) error {
+	result, err := config.ReadBundle(bytes.NewReader(data), config.BundleImportOptions{
+		Name:       profileName,
+		OnConflict: onConflict,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case result.Skipped:
+		fmt.Fprintf(out, "Profile '%s' already exists, bundle skipped\n", result.Profile)
+	case result.Replaced:
+		fmt.Fprintf(out, "✓ Profile '%s' replaced from bundle (%d files verified)\n", result.Profile, result.Files)
+	case result.Profile != result.Original && profileName == "":
+		fmt.Fprintf(out, "✓ Bundle imported as '%s' ('%s' already exists, %d files verified)\n", result.Profile, result.Original, result.Files)
+	default:
+		fmt.Fprintf(out, "✓ Bundle imported as '%s' (%d files verified)\n", result.Profile, result.Files)
+	}
 	return nil
 }
@@ -2,15 +2,16 @@ package commands
 
 import (
 	"fmt"
-	"os"
+	"path/filepath"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/container"
 	"github.com/otori-lab/otori-cli/internal/models"
 	"github.com/otori-lab/otori-cli/internal/tui"
-	"github.com/otori-lab/otori-cli/internal/ui"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var initType string
@@ -18,91 +19,150 @@ var initProfileName string
 var initServerName string
 var initCompanyName string
 var initUsers []string
+var initTemplate string
+var initImage string
 
 var initCmd = &cobra.Command{
-	Use:   "init",
-	Short: "Initialize a honeypot profile",
-	Run: func(cmd *cobra.Command, args []string) {
+	Use:         "init",
+	Short:       "Initialize a honeypot profile",
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
 
-		if cmd.Flags().NFlag() == 0 {
+		if !localFlagsChanged(cmd) && !structuredOutput() {
 			// Interactive mode with TUI Bubble Tea (logo is displayed by the TUI)
-			runInteractiveInit()
-			return
+			return runInteractiveInit()
 		}
 
 		// Display logo (non-interactive mode only)
-		fmt.Println(ui.GetLogo())
-
-		// Non-interactive mode: validate required fields
-		if initType == "" {
-			fmt.Println("Error: --type is required in non-interactive mode")
-			os.Exit(1)
-		}
-		if initServerName == "" {
-			fmt.Println("Error: --server-name is required in non-interactive mode")
-			os.Exit(1)
-		}
+		printLogo()
 
 		// Normalize type to lowercase
 		normalizedType := strings.ToLower(initType)
 
 		// Create configuration
 		cfg := models.NewConfig()
+		cfg.Extends = initTemplate
 		cfg.Type = normalizedType
 		cfg.ServerName = initServerName
 		cfg.Company = initCompanyName
 		cfg.Users = initUsers
+		cfg.Image = initImage
+
+		// Resolve the template, its values act as defaults for the flags
+		effective, _, err := config.ResolveConfig(cfg)
+		if err != nil {
+			return err
+		}
+
+		// Non-interactive mode: validate required fields
+		if effective.Type == "" {
+			return usageError("--type is required in non-interactive mode")
+		}
+		if effective.ServerName == "" {
+			return usageError("--server-name is required in non-interactive mode")
+		}
 
 		// Set profile name (default if empty)
 		if initProfileName != "" {
 			cfg.ProfileName = initProfileName
 		} else {
-			cfg.ProfileName = "default"
+			cfg.ProfileName = config.DefaultProfile()
 		}
 
-		// Validate configuration
-		validationErrors := config.ValidateConfig(cfg)
+		// Give the profile free host ports when a port range is configured
+		effective.ProfileName = cfg.ProfileName
+		if effective.Ports == nil {
+			if err := config.AllocatePorts(cfg); err != nil {
+				return err
+			}
+			effective.Ports = cfg.Ports
+		}
+
+		// Validate configuration (with template values applied)
+		validationErrors := config.ValidateConfig(effective)
 		if len(validationErrors) > 0 {
-			fmt.Println("Validation errors:")
-			for _, err := range validationErrors {
-				fmt.Printf("  - %s: %s\n", err.Field, err.Message)
+			// The JSON/YAML error lists them in its details
+			if !structuredOutput() {
+				printValidationErrors(validationErrors)
 			}
-			os.Exit(1)
+			return validationError(validationErrors)
 		}
+		pinProfileImage(cfg, effective)
 
 		// Save configuration
 		if err := config.WriteConfig(cfg); err != nil {
-			fmt.Printf("Error saving configuration: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("error saving configuration: %w", err)
 		}
 
-		fmt.Printf("✓ Profile '%s' created successfully!\n", cfg.ProfileName)
-
+		fmt.Fprintf(out, "✓ Profile '%s' created successfully!\n", cfg.ProfileName)
+		printRenderConflicts(cfg.ProfileName)
+		if structuredOutput() {
+			return printResult("InitResult", initResult{
+				Profile:   cfg.ProfileName,
+				Path:      filepath.Join(config.GetConfigDir(), cfg.ProfileName),
+				Conflicts: renderConflicts(cfg.ProfileName),
+			})
+		}
+		return nil
 	},
 }
 
+// initResult is the JSON/YAML result of init (kind InitResult)
+type initResult struct {
+	Profile   string                  `json:"profile"`
+	Path      string                  `json:"path"` // profile directory
+	Conflicts []config.RenderConflict `json:"conflicts"`
+}
+
+// pinProfileImage pins the image of a new classic profile with its digests:
+// the image setting is a moving tag (cowrie/cowrie:latest) by default.
+// Without a runtime the profile keeps the tag and a warning is printed.
+func pinProfileImage(cfg, effective *models.Config) {
+	if effective.Type != "classic" {
+		return
+	}
+	ref, err := container.ParseImageRef(config.ProfileImage(effective))
+	if err != nil || ref.Pinned() {
+		return
+	}
+	pinned, err := container.PinImage(ref)
+	if err != nil {
+		fmt.Fprintf(out, "Warning: image %s not pinned: %v\n", ref, err)
+		fmt.Fprintln(out, "The profile deploys whatever the tag points to, pin it with: otori image pull", ref.String())
+		return
+	}
+	cfg.Image = pinned.String()
+	fmt.Fprintf(out, "✓ Image pinned: %s\n", pinned)
+}
+
+// printValidationErrors lists the errors of config.ValidateConfig
+func printValidationErrors(errs []config.ValidationError) {
+	fmt.Fprintln(out, "Validation errors:")
+	for _, err := range errs {
+		fmt.Fprintf(out, "  - %s: %s\n", err.Field, err.Message)
+	}
+}
+
 // runInteractiveInit runs the interactive TUI to create a profile
-func runInteractiveInit() {
+func runInteractiveInit() error {
 	// Step 1: Launch the form
 	formModel := tui.NewModel()
 	formProgram := tea.NewProgram(formModel)
 
 	finalFormModel, err := formProgram.Run()
 	if err != nil {
-		fmt.Printf("Error during form: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("error during form: %w", err)
 	}
 
 	// Check if user cancelled
 	form, ok := finalFormModel.(tui.Model)
 	if !ok {
-		fmt.Println("Error: unable to retrieve form")
-		os.Exit(1)
+		return fmt.Errorf("unable to retrieve form")
 	}
 
 	if form.IsCancelled() {
-		fmt.Println("Configuration cancelled.")
-		return
+		fmt.Fprintln(out, "Configuration cancelled.")
+		return nil
 	}
 
 	// Get configuration from form
@@ -114,38 +174,48 @@ func runInteractiveInit() {
 
 	finalPreviewModel, err := previewProgram.Run()
 	if err != nil {
-		fmt.Printf("Error during preview: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("error during preview: %w", err)
 	}
 
 	preview, ok := finalPreviewModel.(tui.PreviewModel)
 	if !ok {
-		fmt.Println("Error: unable to retrieve preview")
-		os.Exit(1)
+		return fmt.Errorf("unable to retrieve preview")
 	}
 
 	if preview.IsCancelled() || !preview.IsConfirmed() {
-		fmt.Println("Configuration cancelled.")
-		return
+		fmt.Fprintln(out, "Configuration cancelled.")
+		return nil
 	}
 
-	// Step 3: Validate configuration
+	// Step 3: Validate configuration (with free host ports when a port range is configured)
+	if err := config.AllocatePorts(cfg); err != nil {
+		return err
+	}
 	validationErrors := config.ValidateConfig(cfg)
 	if len(validationErrors) > 0 {
-		fmt.Println("Validation errors:")
-		for _, err := range validationErrors {
-			fmt.Printf("  - %s: %s\n", err.Field, err.Message)
-		}
-		os.Exit(1)
+		printValidationErrors(validationErrors)
+		return validationError(validationErrors)
 	}
+	pinProfileImage(cfg, cfg)
 
 	// Step 4: Save configuration
 	if err := config.WriteConfig(cfg); err != nil {
-		fmt.Printf("Error saving configuration: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("error saving configuration: %w", err)
 	}
 
-	fmt.Printf("✓ Profile '%s' created successfully!\n", cfg.ProfileName)
+	fmt.Fprintf(out, "✓ Profile '%s' created successfully!\n", cfg.ProfileName)
+	printRenderConflicts(cfg.ProfileName)
+	return nil
+}
+
+// localFlagsChanged returns true if a flag of the command itself was set:
+// global flags (--home, --color...) do not select the non-interactive mode
+func localFlagsChanged(cmd *cobra.Command) bool {
+	changed := false
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		changed = changed || f.Changed
+	})
+	return changed
 }
 
 func init() {
@@ -189,5 +259,19 @@ func init() {
 		"Comma-separated list of fake users (e.g. root,admin,test)",
 	)
 
+	initCmd.Flags().StringVar(
+		&initTemplate,
+		"from-template",
+		"",
+		"Template to extend (from ~/.otori/templates)",
+	)
+
+	initCmd.Flags().StringVar(
+		&initImage,
+		"image",
+		"",
+		"Cowrie image, pinned with its digests at creation: repository:tag[@sha256:<digest>][@id=sha256:<image ID>] (default: image setting)",
+	)
+
 	RootCmd.AddCommand(initCmd)
 }
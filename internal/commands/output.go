@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mattn/go-isatty"
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/ui"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats (-o flag, output setting)
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// schemaVersion is the version of the JSON/YAML documents printed by otori.
+// It only changes when a field is removed or changes meaning.
+const schemaVersion = 1
+
+// annotationOutput marks the commands printing a document in JSON/YAML mode
+const annotationOutput = "otori/structured-output"
+
+// structuredCommand is the annotation of the commands supporting -o json|yaml
+var structuredCommand = map[string]string{annotationOutput: "true"}
+
+// outputFormat is the format of the running command (see applyOutput)
+var outputFormat = formatTable
+
+// Writers of the running command, taken from the cobra command (see applyOutput):
+// resultOut receives the JSON/YAML documents, out the human messages and docker
+// output, errOut the warnings and errors. In JSON/YAML mode out is the error
+// output, so that progress messages never end up in the document.
+var (
+	resultOut io.Writer = os.Stdout
+	out       io.Writer = os.Stdout
+	errOut    io.Writer = os.Stderr
+)
+
+// setWriters takes the writers of a command, out being its error output in JSON/YAML mode
+func setWriters(cmd *cobra.Command) {
+	resultOut, out, errOut = cmd.OutOrStdout(), cmd.OutOrStdout(), cmd.ErrOrStderr()
+	if structuredOutput() {
+		out = errOut
+	}
+}
+
+// document is the envelope of everything otori prints in JSON/YAML
+type document struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Kind          string         `json:"kind"`
+	Data          any            `json:"data,omitempty"`
+	Error         *errorDocument `json:"error,omitempty"`
+}
+
+// applyOutput selects the output format of a command: -o (or --json) >
+// OTORI_OUTPUT > config.yaml. The output setting only applies to the commands
+// supporting it, an explicit -o json|yaml on another command is a usage error.
+func applyOutput(cmd *cobra.Command) error {
+	outputFormat = formatTable
+	defer setWriters(cmd)
+
+	format := config.OutputFormat()
+	explicit := rootOutputChanged(cmd)
+	if flag := cmd.Flags().Lookup("json"); flag != nil && flag.Changed && flag.Value.String() == "true" {
+		format = formatJSON
+		explicit = true
+	}
+	if format == formatTable {
+		return nil
+	}
+
+	if cmd.Annotations[annotationOutput] == "" {
+		if explicit {
+			return usageError("'%s' does not support -o %s", cmd.CommandPath(), format)
+		}
+		return nil
+	}
+
+	outputFormat = format
+	return nil
+}
+
+// rootOutputChanged returns true if the global -o flag was given.
+// profiles export and exporter dashboard have their own -o (output file).
+func rootOutputChanged(cmd *cobra.Command) bool {
+	flag := cmd.Flags().Lookup("output")
+	return flag != nil && flag == cmd.Root().PersistentFlags().Lookup("output") && flag.Changed
+}
+
+// structuredOutput returns true when the running command prints JSON or YAML
+func structuredOutput() bool {
+	return outputFormat != formatTable
+}
+
+// printLogo prints the logo, only in table mode on a terminal
+func printLogo() {
+	if structuredOutput() || !isTerminal(os.Stdout) {
+		return
+	}
+	fmt.Fprintln(out, ui.GetLogo())
+}
+
+// isTerminal returns true if f is a terminal
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// printResult prints the result of a command as a JSON/YAML document on the standard output
+func printResult(kind string, data any) error {
+	return writeDocument(resultOut, outputFormat, document{SchemaVersion: schemaVersion, Kind: kind, Data: data})
+}
+
+// writeDocument writes a document as indented JSON or as YAML, with the field names of its JSON encoding
+func writeDocument(w io.Writer, format string, doc document) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", format, err)
+	}
+	if format == formatYAML {
+		// JSON is YAML: decoding it as a node keeps the order of the fields
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return fmt.Errorf("error encoding YAML: %w", err)
+		}
+		blockStyle(&node)
+		if data, err = yaml.Marshal(&node); err != nil {
+			return fmt.Errorf("error encoding YAML: %w", err)
+		}
+	} else {
+		data = append(data, '\n')
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// blockStyle drops the JSON flow style and quotes of a YAML node and its children
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
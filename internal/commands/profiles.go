@@ -3,12 +3,13 @@ package commands
 import (
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"strings"
 	"text/tabwriter"
 
+	"github.com/mattn/go-isatty"
 	"github.com/otori-lab/otori-cli/internal/config"
-	"github.com/otori-lab/otori-cli/internal/ui"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/otori-lab/otori-cli/internal/models"
 	"github.com/spf13/cobra"
 )
 
@@ -16,104 +17,355 @@ import (
 var profilesCmd = &cobra.Command{
 	Use:   "profiles",
 	Short: "Manage honeypot profiles",
-	Long:  "List, show, and delete honeypot profiles",
+	Long:  "List, show, clone, rename, export, import, delete and restore honeypot profiles and templates",
 }
 
 // profilesListCmd lists all profiles
 var profilesListCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List all available profiles",
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := ListCommand(); err != nil {
-			fmt.Println("Error:", err)
-			os.Exit(1)
-		}
+	Use:         "list",
+	Short:       "List all available profiles",
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ListCommand()
 	},
 }
 
 // profilesShowCmd shows a specific profile
 var profilesShowCmd = &cobra.Command{
-	Use:   "show [profile-name]",
-	Short: "Show details of a profile",
-	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		profileName := "default"
+	Use:         "show [profile-name]",
+	Short:       "Show details of a profile",
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profileName := config.DefaultProfile()
 		if len(args) > 0 {
 			profileName = args[0]
 		}
-		if err := ShowCommand(profileName); err != nil {
-			fmt.Println("Error:", err)
-			os.Exit(1)
-		}
+		return ShowCommand(profileName)
 	},
 }
 
 // profilesDeleteCmd deletes a profile
 var profilesDeleteCmd = &cobra.Command{
 	Use:   "delete [profile-name]",
-	Short: "Delete a profile",
+	Short: "Delete a profile (archived to ~/.otori/archive first)",
+	Long: "Delete a profile, its container and its log and download volumes.\n" +
+		"The profile files and the volume data are archived to ~/.otori/archive first,\n" +
+		"see 'otori profiles restore'.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return DeleteCommand(args[0], deleteOpts)
+	},
+}
+
+// profilesTemplatesCmd lists the profile templates
+var profilesTemplatesCmd = &cobra.Command{
+	Use:         "templates",
+	Short:       "List profile templates (~/.otori/templates)",
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return TemplatesCommand()
+	},
+}
+
+var exportOutput string
+var exportFormat string
+var exportAll bool
+var importOpts ImportOptions
+var csvColumns string
+var csvDelimiter string
+
+// profilesExportCmd exports profiles to a file
+var profilesExportCmd = &cobra.Command{
+	Use:   "export [profile-name...]",
+	Short: "Export profiles (yaml, csv, json or a full tar.gz bundle)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names := args
+		if exportAll {
+			var err error
+			if names, err = config.ListConfigs(); err != nil {
+				return err
+			}
+		}
+		csvOpts, err := csvOptions()
+		if err == nil {
+			err = ExportCommand(names, exportOutput, exportFormat, csvOpts)
+		}
+		return err
+	},
+}
+
+// profilesImportCmd imports profiles from a file
+var profilesImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import profiles from a file or a bundle (- for stdin)",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := DeleteCommand(args[0]); err != nil {
-			fmt.Println("Error:", err)
-			os.Exit(1)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		csvOpts, err := csvOptions()
+		if err == nil {
+			importOpts.CSV = csvOpts
+			err = ImportCommand(args[0], importOpts)
+		}
+		return err
+	},
+}
+
+// csvOptions builds the CSV options from the --columns and --delimiter flags
+func csvOptions() (config.CSVOptions, error) {
+	var opts config.CSVOptions
+	columns, err := config.ParseColumnMapping(csvColumns)
+	if err != nil {
+		return opts, usageError("%w", err)
+	}
+	opts.Columns = columns
+
+	switch csvDelimiter {
+	case "", ",":
+	case ";", "\\t", "\t", "|":
+		opts.Comma = []rune(strings.ReplaceAll(csvDelimiter, "\\t", "\t"))[0]
+	default:
+		return opts, usageError("unsupported CSV delimiter '%s' (use , ; | or \\t)", csvDelimiter)
+	}
+	return opts, nil
+}
+
+// profilesCloneCmd duplicates a profile
+var profilesCloneCmd = &cobra.Command{
+	Use:   "clone <source> <destination>",
+	Short: "Duplicate a profile (honeyfs and tuned files included)",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return CloneCommand(args[0], args[1])
+	},
+}
+
+// profilesRenameCmd renames a profile
+var profilesRenameCmd = &cobra.Command{
+	Use:   "rename <old-name> <new-name>",
+	Short: "Rename a profile and migrate its container data",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return RenameCommand(args[0], args[1], renameSkipVolumes)
+	},
+}
+
+var renameSkipVolumes bool
+
+var deleteOpts DeleteOptions
+var restoreName string
+var restoreOnConflict string
+var restoreList bool
+
+// profilesRestoreCmd brings back a deleted profile
+var profilesRestoreCmd = &cobra.Command{
+	Use:   "restore [archive|profile-name]",
+	Short: "Restore a deleted profile from ~/.otori/archive",
+	Long: "Restore an archived profile and the data of its volumes. The argument is an\n" +
+		"archive ID or a profile name (its most recent archive is used).",
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		if restoreList || len(args) == 0 {
+			err = ArchivesCommand()
+		} else {
+			err = RestoreCommand(args[0], restoreName, restoreOnConflict)
 		}
+		return err
+	},
+}
+
+var migrateDryRun bool
+
+// profilesMigrateCmd upgrades profiles to the current schema version
+var profilesMigrateCmd = &cobra.Command{
+	Use:   "migrate [profile-name]",
+	Short: "Upgrade profiles to the current schema version",
+	Long: "Rewrite profiles written by older otori versions (including the legacy flat\n" +
+		"profiles/<name>.json layout). Files are backed up to ~/.otori/backups first.",
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return MigrateCommand(args, migrateDryRun)
 	},
 }
 
 func init() {
+	profilesExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Output file (default: exports/<profile>.<ext>)")
+	profilesExportCmd.Flags().StringVarP(&exportFormat, "format", "f", "yaml", "Export format: yaml, csv, json or bundle")
+	profilesExportCmd.Flags().BoolVarP(&exportAll, "all", "a", false, "Export all profiles")
+	profilesImportCmd.Flags().StringVarP(&importOpts.Name, "name", "n", "", "Name of the imported profile (single-profile files)")
+	profilesImportCmd.Flags().StringVar(&importOpts.OnConflict, "on-conflict", config.ConflictRename, "When the profile exists: rename, overwrite or skip")
+	profilesImportCmd.Flags().BoolVar(&importOpts.DryRun, "dry-run", false, "Show creates, updates and errors without writing anything")
+	for _, cmd := range []*cobra.Command{profilesExportCmd, profilesImportCmd} {
+		cmd.Flags().StringVar(&csvColumns, "columns", "", "CSV column mapping, e.g. serverName=Hostname,users=Accounts")
+		cmd.Flags().StringVar(&csvDelimiter, "delimiter", ",", "CSV field delimiter: , ; | or \\t")
+	}
+	profilesDeleteCmd.Flags().BoolVarP(&deleteOpts.Yes, "yes", "y", false, "Do not ask for confirmation")
+	profilesDeleteCmd.Flags().BoolVar(&deleteOpts.KeepData, "keep-data", false, "Keep the log and download volumes")
+	profilesDeleteCmd.Flags().BoolVar(&deleteOpts.PurgeVolumes, "purge-volumes", false, "Remove the volumes without archiving their data")
+	profilesRestoreCmd.Flags().StringVarP(&restoreName, "name", "n", "", "Name of the restored profile")
+	profilesRestoreCmd.Flags().StringVar(&restoreOnConflict, "on-conflict", config.ConflictRename, "When the profile exists: rename, overwrite or skip")
+	profilesRestoreCmd.Flags().BoolVarP(&restoreList, "list", "l", false, "List archived profiles")
+	profilesRenameCmd.Flags().BoolVar(&renameSkipVolumes, "skip-volumes", false, "Rename the profile even if the container runtime is unavailable, leaving its volumes under the old name")
+	profilesMigrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Show pending migrations without rewriting anything")
+
 	profilesCmd.AddCommand(profilesListCmd)
+	profilesCmd.AddCommand(profilesTemplatesCmd)
 	profilesCmd.AddCommand(profilesShowCmd)
+	profilesCmd.AddCommand(profilesCloneCmd)
+	profilesCmd.AddCommand(profilesRenameCmd)
 	profilesCmd.AddCommand(profilesDeleteCmd)
+	profilesCmd.AddCommand(profilesRestoreCmd)
+	profilesCmd.AddCommand(profilesExportCmd)
+	profilesCmd.AddCommand(profilesImportCmd)
+	profilesCmd.AddCommand(profilesMigrateCmd)
 	RootCmd.AddCommand(profilesCmd)
 }
 
+// profileSummary is a profile in the JSON/YAML output of profiles list (kind ProfileList)
+type profileSummary struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	ServerName string `json:"serverName"`
+	Company    string `json:"company"`
+	CreatedAt  string `json:"createdAt"`
+	Error      string `json:"error,omitempty"` // the profile could not be read
+}
+
 // ListCommand lists all available profiles
 func ListCommand() error {
-	fmt.Println(ui.GetLogo())
+	printLogo()
 
 	profiles, err := config.ListConfigs()
 	if err != nil {
 		return fmt.Errorf("error reading profiles: %w", err)
 	}
 
-	if len(profiles) == 0 {
-		fmt.Println("No profiles found. Create one with: otori init")
+	summaries := make([]profileSummary, 0, len(profiles))
+	for _, name := range profiles {
+		cfg, err := config.ReadEffectiveConfig(name)
+		if err != nil {
+			summaries = append(summaries, profileSummary{Name: name, Error: err.Error()})
+			continue
+		}
+		summaries = append(summaries, profileSummary{
+			Name:       name,
+			Type:       cfg.Type,
+			ServerName: cfg.ServerName,
+			Company:    cfg.Company,
+			CreatedAt:  cfg.CreatedAt,
+		})
+	}
+	if structuredOutput() {
+		return printResult("ProfileList", summaries)
+	}
+
+	if len(summaries) == 0 {
+		fmt.Fprintln(out, "No profiles found. Create one with: otori init")
 		return nil
 	}
 
-	fmt.Println("\nAvailable profiles:\n")
+	fmt.Fprint(out, "\nAvailable profiles:\n\n")
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "PROFILE\tTYPE\tSERVER\tCOMPANY\tCREATED")
 
-	for _, name := range profiles {
-		cfg, err := config.ReadConfig(name)
-		if err != nil {
-			fmt.Fprintf(w, "%s\t[error]\t-\t-\t-\n", name)
+	for _, profile := range summaries {
+		if profile.Error != "" {
+			fmt.Fprintf(w, "%s\t[error]\t-\t-\t-\n", profile.Name)
 			continue
 		}
 
-		createdAt := cfg.CreatedAt
+		createdAt := profile.CreatedAt
 		if len(createdAt) > 16 {
 			createdAt = createdAt[:16]
 		}
 
 		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-			name, cfg.Type, cfg.ServerName, cfg.Company, createdAt)
+			profile.Name, profile.Type, profile.ServerName, profile.Company, createdAt)
 	}
 
 	w.Flush()
-	fmt.Println()
+	fmt.Fprintln(out)
 	return nil
 }
 
+// templateSummary is a template in the JSON/YAML output of profiles templates (kind TemplateList)
+type templateSummary struct {
+	Name       string `json:"name"`
+	Extends    string `json:"extends,omitempty"`
+	Type       string `json:"type,omitempty"`
+	ServerName string `json:"serverName,omitempty"`
+	Company    string `json:"company,omitempty"`
+	Error      string `json:"error,omitempty"` // the template could not be read
+}
+
+// TemplatesCommand lists the available profile templates
+func TemplatesCommand() error {
+	templates, err := config.ListTemplates()
+	if err != nil {
+		return fmt.Errorf("error reading templates: %w", err)
+	}
+
+	summaries := make([]templateSummary, 0, len(templates))
+	for _, name := range templates {
+		tmpl, err := config.ReadTemplate(name)
+		if err != nil {
+			summaries = append(summaries, templateSummary{Name: name, Error: err.Error()})
+			continue
+		}
+		summaries = append(summaries, templateSummary{
+			Name:       name,
+			Extends:    tmpl.Extends,
+			Type:       tmpl.Type,
+			ServerName: tmpl.ServerName,
+			Company:    tmpl.Company,
+		})
+	}
+	if structuredOutput() {
+		return printResult("TemplateList", summaries)
+	}
+
+	if len(summaries) == 0 {
+		fmt.Fprintf(out, "No templates found in %s\n", config.GetTemplatesDir())
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TEMPLATE\tEXTENDS\tTYPE\tSERVER\tCOMPANY")
+	for _, tmpl := range summaries {
+		if tmpl.Error != "" {
+			fmt.Fprintf(w, "%s\t[error]\t-\t-\t-\n", tmpl.Name)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", tmpl.Name, dash(tmpl.Extends), dash(tmpl.Type), dash(tmpl.ServerName), dash(tmpl.Company))
+	}
+	w.Flush()
+	return nil
+}
+
+// dash returns "-" for empty values in tables
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// profileDetails is the JSON/YAML output of profiles show (kind Profile)
+type profileDetails struct {
+	Name       string                  `json:"name"`
+	Extends    string                  `json:"extends,omitempty"`
+	Config     *models.Config          `json:"config"`     // effective configuration, templates applied
+	Provenance config.Provenance       `json:"provenance"` // field -> template or settings it comes from
+	Conflicts  []config.RenderConflict `json:"conflicts"`
+}
+
 // ShowCommand displays profile details
 func ShowCommand(profileName string) error {
-	fmt.Println(ui.GetLogo())
+	printLogo()
 
 	if profileName == "" {
-		profileName = "default"
+		profileName = config.DefaultProfile()
 	}
 
 	cfg, err := config.ReadConfig(profileName)
@@ -121,80 +373,427 @@ func ShowCommand(profileName string) error {
 		return fmt.Errorf("profile '%s' not found: %w", profileName, err)
 	}
 
-	fmt.Printf("\nProfile: %s\n\n", profileName)
-	fmt.Printf("  Type:       %s\n", cfg.Type)
-	fmt.Printf("  Server:     %s\n", cfg.ServerName)
-	fmt.Printf("  Company:    %s\n", cfg.Company)
-	fmt.Printf("  Created:    %s\n\n", cfg.CreatedAt)
+	// Display the effective configuration (templates applied)
+	effective, prov, err := config.ResolveConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		if prov == nil {
+			prov = config.Provenance{}
+		}
+		effective.SchemaVersion = cfg.SchemaVersion
+		return printResult("Profile", profileDetails{
+			Name:       profileName,
+			Extends:    cfg.Extends,
+			Config:     effective,
+			Provenance: prov,
+			Conflicts:  renderConflicts(profileName),
+		})
+	}
 
-	if len(cfg.Users) > 0 {
-		fmt.Println("  Users:")
-		for _, user := range cfg.Users {
-			fmt.Printf("    - %s\n", user)
+	fmt.Fprintf(out, "\nProfile: %s\n\n", profileName)
+	if cfg.Extends != "" {
+		fmt.Fprintf(out, "  Extends:    %s\n", cfg.Extends)
+	}
+	fmt.Fprintf(out, "  Type:       %s%s\n", effective.Type, sourceSuffix(prov["type"]))
+	fmt.Fprintf(out, "  Server:     %s%s\n", effective.ServerName, sourceSuffix(prov["serverName"]))
+	fmt.Fprintf(out, "  Company:    %s%s\n", effective.Company, sourceSuffix(prov["company"]))
+	imageSource := prov["image"]
+	if imageSource == "" {
+		imageSource = config.SourceSettings
+	}
+	fmt.Fprintf(out, "  Image:      %s%s\n", config.ProfileImage(effective), sourceSuffix(imageSource))
+	if effective.Baked {
+		fmt.Fprintf(out, "  Baked:      %s%s\n", config.BakedImage(effective.ProfileName), sourceSuffix(prov["baked"]))
+	}
+	fmt.Fprintf(out, "  Created:    %s\n\n", cfg.CreatedAt)
+
+	if len(effective.Users) > 0 {
+		fmt.Fprintln(out, "  Users:")
+		for _, user := range effective.Users {
+			fmt.Fprintf(out, "    - %s%s\n", user, sourceSuffix(prov["users."+user]))
 		}
 	} else {
-		fmt.Println("  Users: (none)")
+		fmt.Fprintln(out, "  Users: (none)")
+	}
+
+	if len(effective.CowrieOverrides) > 0 {
+		fmt.Fprintln(out, "\n  Cowrie overrides:")
+		for _, key := range prov.SortedKeys() {
+			if !strings.HasPrefix(key, "cowrie.") {
+				continue
+			}
+			parts := strings.SplitN(strings.TrimPrefix(key, "cowrie."), ".", 2)
+			fmt.Fprintf(out, "    [%s] %s = %s%s\n", parts[0], parts[1],
+				effective.CowrieOverrides[parts[0]][parts[1]], sourceSuffix(prov[key]))
+		}
+	}
+	printRenderConflicts(profileName)
+	fmt.Fprintln(out)
+
+	return nil
+}
+
+// printRenderConflicts reports the files changed by hand that the last render did not overwrite
+func printRenderConflicts(profileName string) {
+	conflicts, err := config.ReadRenderConflicts(profileName)
+	if err != nil {
+		fmt.Fprintf(out, "Warning: %v\n", err)
+		return
+	}
+	if len(conflicts) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "\nWarning: %d file(s) changed by hand were not regenerated:\n", len(conflicts))
+	for _, conflict := range conflicts {
+		fmt.Fprintf(out, "  ! %s: %s\n", conflict.Path, conflict.Reason)
+	}
+	fmt.Fprintln(out, "Move your version to the overlay directory (e.g. "+config.OverlayPath(conflicts[0].Path)+
+		") to keep it, or delete the file to get the generated one back.")
+}
+
+// renderConflicts returns the render conflicts of a profile for the JSON/YAML results
+// (never nil, unreadable conflicts are reported by printRenderConflicts)
+func renderConflicts(profileName string) []config.RenderConflict {
+	conflicts, err := config.ReadRenderConflicts(profileName)
+	if err != nil || conflicts == nil {
+		return []config.RenderConflict{}
+	}
+	return conflicts
+}
+
+// MigrateCommand upgrades one or all profiles to the current schema version
+func MigrateCommand(args []string, dryRun bool) error {
+	profiles := args
+	if len(profiles) == 0 {
+		var err error
+		if profiles, err = config.ListConfigs(); err != nil {
+			return fmt.Errorf("error reading profiles: %w", err)
+		}
+	}
+
+	failed := 0
+	migrated := 0
+	for _, name := range profiles {
+		plan, err := config.PlanMigration(name)
+		if err != nil {
+			fmt.Fprintf(out, "✗ %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		if !plan.Pending() {
+			fmt.Fprintf(out, "  %s: up to date (v%d)\n", name, plan.FromVersion)
+			continue
+		}
+
+		fmt.Fprintf(out, "→ %s: v%d → v%d\n", name, plan.FromVersion, models.CurrentSchemaVersion)
+		for _, step := range plan.Steps {
+			fmt.Fprintf(out, "    - %s\n", step)
+		}
+		if dryRun {
+			continue
+		}
+
+		backupDir, err := config.MigrateProfile(name)
+		if err != nil {
+			fmt.Fprintf(out, "✗ %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(out, "✓ %s migrated (backup: %s)\n", name, backupDir)
+		migrated++
+	}
+
+	if dryRun {
+		fmt.Fprintln(out, "\nDry run: nothing was rewritten")
+	} else if migrated > 0 {
+		fmt.Fprintln(out, "\nRedeploy running honeypots to apply regenerated files: otori deploy -p <profile>")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d profile(s) could not be migrated", failed)
+	}
+	return nil
+}
+
+// sourceSuffix formats where a value comes from, only when it is not the profile itself
+func sourceSuffix(source string) string {
+	if source == "" || source == config.SourceProfile {
+		return ""
+	}
+	return "  (" + source + ")"
+}
+
+// CloneCommand duplicates a profile under a new name
+func CloneCommand(src, dst string) error {
+	if err := config.CloneProfile(src, dst); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "✓ Profile '%s' cloned to '%s'\n", src, dst)
+	fmt.Fprintln(out, "Deploy it with: otori deploy -p", dst)
+	return nil
+}
+
+// RenameCommand renames a profile. The honeypot is stopped during the rename,
+// its log and download volumes are copied to the volumes of the new name,
+// then it is deployed again if it was running. Without a container runtime the
+// rename fails, unless skipVolumes leaves the volumes under the old name.
+func RenameCommand(oldName, newName string, skipVolumes bool) error {
+	if !config.ProfileExists(oldName) {
+		return fmt.Errorf("profile '%s' %w", oldName, config.ErrNotFound)
+	}
+	if config.ProfileExists(newName) {
+		return fmt.Errorf("profile '%s' %w", newName, config.ErrExists)
+	}
+
+	// Both names stay locked until the honeypot runs again under the new name
+	locks, err := config.LockProfiles(oldName, newName)
+	if err != nil {
+		return err
+	}
+	defer locks.Unlock()
+
+	// The unit would still deploy the old name
+	if err := checkNoService(oldName); err != nil {
+		return err
+	}
+
+	containers, runtimeErr := container.ListContainers([]string{oldName})
+	if runtimeErr != nil && !skipVolumes {
+		return fmt.Errorf("container runtime unavailable, volumes %s cannot be migrated (use --skip-volumes to rename the profile only): %w",
+			strings.Join(container.ProfileVolumes(oldName), ", "), runtimeErr)
+	}
+	var deployed, wasRunning bool
+	for _, ct := range containers {
+		if ct.Profile() == oldName {
+			deployed = true
+			wasRunning = ct.State == "running"
+		}
+	}
+
+	// Volumes of the old name are migrated, the new names must be free
+	var volumes [][2]string
+	if runtimeErr == nil {
+		newVolumes := container.ProfileVolumes(newName)
+		for i, oldVolume := range container.ProfileVolumes(oldName) {
+			if !container.VolumeExists(oldVolume) {
+				continue
+			}
+			if container.VolumeExists(newVolumes[i]) {
+				return fmt.Errorf("volume '%s' %w, remove it first", newVolumes[i], config.ErrExists)
+			}
+			volumes = append(volumes, [2]string{oldVolume, newVolumes[i]})
+		}
+	}
+
+	if deployed {
+		if err := stopHoneypot(oldName, false); err != nil {
+			return err
+		}
 	}
-	fmt.Println()
 
+	if err := config.RenameProfile(oldName, newName); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "✓ Profile '%s' renamed to '%s'\n", oldName, newName)
+
+	if runtimeErr != nil {
+		fmt.Fprintf(out, "Warning: container runtime unavailable, volumes %s were not migrated: %v\n",
+			strings.Join(container.ProfileVolumes(oldName), ", "), runtimeErr)
+	}
+	for _, pair := range volumes {
+		fmt.Fprintf(out, "Migrating volume %s → %s...\n", pair[0], pair[1])
+		if err := container.CopyVolume(pair[0], pair[1]); err != nil {
+			return fmt.Errorf("error migrating volume %s (old volume kept): %w", pair[0], err)
+		}
+		if err := container.RemoveVolume(pair[0]); err != nil {
+			fmt.Fprintf(out, "Warning: could not remove old volume %s: %v\n", pair[0], err)
+		}
+	}
+
+	if wasRunning {
+		_, err := deployHoneypot(newName, false)
+		return err
+	}
 	return nil
 }
 
-// DeleteCommand deletes a profile
-func DeleteCommand(profileName string) error {
-	fmt.Println(ui.GetLogo())
+// DeleteOptions are the flags of the delete command
+type DeleteOptions struct {
+	Yes          bool // do not ask for confirmation
+	KeepData     bool // leave the log and download volumes in place
+	PurgeVolumes bool // remove the volumes without saving their data
+}
+
+// DeleteCommand deletes a profile after archiving it to ~/.otori/archive
+func DeleteCommand(profileName string, opts DeleteOptions) error {
+	printLogo()
 
 	if profileName == "" {
 		return fmt.Errorf("please specify the profile name to delete")
 	}
+	if opts.KeepData && opts.PurgeVolumes {
+		return fmt.Errorf("--keep-data and --purge-volumes cannot be used together")
+	}
+	if !config.ProfileExists(profileName) {
+		return fmt.Errorf("profile '%s' %w", profileName, config.ErrNotFound)
+	}
 
-	// Confirmation
-	fmt.Printf("Are you sure you want to delete profile '%s'? (yes/no): ", profileName)
-	var response string
-	fmt.Scanln(&response)
+	if !opts.Yes {
+		if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+			return fmt.Errorf("refusing to delete '%s' without confirmation, use --yes", profileName)
+		}
+		fmt.Fprintf(out, "Are you sure you want to delete profile '%s'? (yes/no): ", profileName)
+		var response string
+		fmt.Scanln(&response)
 
-	if response != "yes" && response != "y" {
-		fmt.Println("Deletion cancelled")
-		return nil
+		if response != "yes" && response != "y" {
+			fmt.Fprintln(out, "Deletion cancelled")
+			return nil
+		}
+	}
+
+	return deleteProfile(profileName, opts)
+}
+
+// deleteProfile archives a profile (files, and volume data unless told otherwise),
+// removes its container and volumes, then its files
+func deleteProfile(profileName string, opts DeleteOptions) error {
+	lock, err := config.LockProfile(profileName)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// The unit would deploy a profile that no longer exists
+	if err := checkNoService(profileName); err != nil {
+		return err
 	}
 
-	// Stop and remove Docker container if it exists
-	containerName := "otori-" + profileName
-	fmt.Printf("Removing container '%s'...\n", containerName)
+	containers, runtimeErr := container.ListContainers([]string{profileName})
+	if runtimeErr != nil && !opts.KeepData {
+		return fmt.Errorf("container runtime unavailable, cannot remove the container and volumes (use --keep-data to delete the profile files only): %w", runtimeErr)
+	}
 
-	// Stop container (ignore errors if not running)
-	stopCmd := exec.Command("docker", "stop", containerName)
-	stopCmd.Run()
+	var volumes []string
+	if runtimeErr == nil && !opts.KeepData {
+		for _, kind := range container.VolumeKinds {
+			if container.VolumeExists(container.ProfileVolume(profileName, kind)) {
+				volumes = append(volumes, kind)
+			}
+		}
+	}
 
-	// Remove container (ignore errors if doesn't exist)
-	rmCmd := exec.Command("docker", "rm", containerName)
-	rmCmd.Run()
+	archive, err := config.ArchiveProfile(profileName)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "✓ Profile archived to %s\n", archive.Dir)
 
-	// Find profile (new structure: directory, old structure: file)
-	configDir := config.GetConfigDir()
-	profileDir := filepath.Join(configDir, profileName)
-	oldFilename := filepath.Join(configDir, profileName+".json")
+	for _, ct := range containers {
+		if ct.Profile() != profileName {
+			continue
+		}
+		fmt.Fprintf(out, "Removing container '%s'...\n", ct.Name)
+		if err := container.Remove(ct.ID); err != nil {
+			return fmt.Errorf("error removing container (profile kept): %w", err)
+		}
+	}
 
-	// Check new structure first (directory)
-	if info, err := os.Stat(profileDir); err == nil && info.IsDir() {
-		// Delete entire directory
-		if err := os.RemoveAll(profileDir); err != nil {
-			return fmt.Errorf("error deleting profile directory: %w", err)
+	for _, kind := range volumes {
+		volume := container.ProfileVolume(profileName, kind)
+		if !opts.PurgeVolumes {
+			fmt.Fprintf(out, "Archiving volume %s...\n", volume)
+			if err := container.ExportVolume(volume, archive.VolumePath(kind)); err != nil {
+				return fmt.Errorf("error archiving volume %s (profile kept): %w", volume, err)
+			}
+			if err := config.AddArchiveVolume(archive, kind); err != nil {
+				return fmt.Errorf("error archiving volume %s (profile kept): %w", volume, err)
+			}
+		}
+		if err := container.RemoveVolume(volume); err != nil {
+			return fmt.Errorf("error removing volume %s (profile kept): %w", volume, err)
 		}
-		fmt.Printf("✓ Profile '%s' deleted successfully\n", profileName)
+	}
+
+	if err := config.RemoveProfile(profileName); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "✓ Profile '%s' deleted successfully\n", profileName)
+	switch {
+	case opts.KeepData:
+		fmt.Fprintf(out, "Volumes %s were kept\n", strings.Join(container.ProfileVolumes(profileName), ", "))
+	case opts.PurgeVolumes && len(volumes) > 0:
+		fmt.Fprintln(out, "Volumes were removed without archiving their data")
+	}
+	fmt.Fprintln(out, "Restore it with: otori profiles restore", archive.ID)
+	return nil
+}
+
+// RestoreCommand brings back an archived profile and the data of its volumes
+func RestoreCommand(ref, name, onConflict string) error {
+	printLogo()
+
+	archive, err := config.FindArchive(ref)
+	if err != nil {
+		return err
+	}
+
+	result, err := config.RestoreArchive(archive, config.BundleImportOptions{
+		Name:       name,
+		OnConflict: onConflict,
+	})
+	if err != nil {
+		return err
+	}
+	if result.Skipped {
+		fmt.Fprintf(out, "Profile '%s' already exists, archive %s not restored\n", result.Profile, archive.ID)
 		return nil
 	}
+	fmt.Fprintf(out, "✓ Profile '%s' restored from %s\n", result.Profile, archive.ID)
 
-	// Fallback to old structure (single file)
-	if _, err := os.Stat(oldFilename); os.IsNotExist(err) {
-		return fmt.Errorf("profile '%s' not found", profileName)
+	if len(archive.Volumes) == 0 {
+		return nil
+	}
+	for _, kind := range archive.Volumes {
+		volume := container.ProfileVolume(result.Profile, kind)
+		if container.VolumeExists(volume) {
+			fmt.Fprintf(out, "Warning: volume %s already exists, archived %s not restored\n", volume, kind)
+			continue
+		}
+		fmt.Fprintf(out, "Restoring volume %s...\n", volume)
+		if err := container.ImportVolume(volume, archive.VolumePath(kind)); err != nil {
+			return fmt.Errorf("error restoring volume %s: %w", volume, err)
+		}
+	}
+	fmt.Fprintln(out, "Deploy it with: otori deploy -p", result.Profile)
+	return nil
+}
+
+// ArchivesCommand lists the archived profiles
+func ArchivesCommand() error {
+	archives, err := config.ListArchives()
+	if err != nil {
+		return fmt.Errorf("error reading archives: %w", err)
 	}
 
-	// Delete file
-	if err := os.Remove(oldFilename); err != nil {
-		return fmt.Errorf("error deleting profile: %w", err)
+	if len(archives) == 0 {
+		fmt.Fprintf(out, "No archived profiles in %s\n", config.GetArchiveDir())
+		return nil
 	}
 
-	fmt.Printf("✓ Profile '%s' deleted successfully\n", profileName)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ARCHIVE\tPROFILE\tDELETED\tDATA")
+	for _, archive := range archives {
+		deleted := archive.CreatedAt
+		if len(deleted) > 16 {
+			deleted = deleted[:16]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", archive.ID, archive.Profile, deleted, dash(strings.Join(archive.Volumes, ", ")))
+	}
+	w.Flush()
 	return nil
 }
@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var renderProfile string
+var renderFile string
+var renderOut string
+var renderForce bool
+
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Write the generated files of a profile to a directory",
+	Long: `Render the files otori generates for a profile (cowrie.cfg, userdb.txt, honeyfs,
+txtcmds, docker-compose.yml) into a directory, without deploying anything.
+The same profile always renders the same bytes, so the output can be diffed
+or used as golden files.`,
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runRender()
+	},
+}
+
+// renderResult is the JSON/YAML result of render (kind RenderResult)
+type renderResult struct {
+	Profile string `json:"profile"`
+	Out     string `json:"out"`   // output directory
+	Files   int    `json:"files"` // number of files written
+}
+
+func runRender() error {
+	if renderOut == "" {
+		return usageError("please specify the output directory with --out")
+	}
+	if renderFile != "" && renderProfile != "" {
+		return usageError("--profile and --file cannot be used together")
+	}
+
+	var cfg *models.Config
+	var err error
+	if renderFile != "" {
+		cfg, err = readRenderFile(renderFile)
+	} else {
+		profileName := renderProfile
+		if profileName == "" {
+			profileName = config.DefaultProfile()
+		}
+		cfg, err = config.ReadConfig(profileName)
+	}
+	if err != nil {
+		return err
+	}
+
+	effective, _, err := config.ResolveConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if effective.Type != "classic" {
+		return fmt.Errorf("profile '%s' is of type '%s', only 'classic' profiles have generated files", cfg.ProfileName, effective.Type)
+	}
+
+	// Never mix a render with other files, and only clear an earlier render
+	if entries, err := os.ReadDir(renderOut); err == nil && len(entries) > 0 {
+		if !renderForce {
+			return fmt.Errorf("directory %s is not empty (use --force to replace its content)", renderOut)
+		}
+		if !config.IsRenderDir(renderOut) {
+			return fmt.Errorf("directory %s was not written by otori render, refusing to clear it", renderOut)
+		}
+		if err := os.RemoveAll(renderOut); err != nil {
+			return fmt.Errorf("error cleaning output directory: %w", err)
+		}
+	}
+
+	if err := config.RenderProfile(renderOut, cfg); err != nil {
+		return err
+	}
+
+	files := 0
+	filepath.Walk(renderOut, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() && info.Name() != config.RenderMarker {
+			files++
+		}
+		return nil
+	})
+	fmt.Fprintf(out, "✓ Profile '%s' rendered to %s (%d files)\n", cfg.ProfileName, renderOut, files)
+	if structuredOutput() {
+		return printResult("RenderResult", renderResult{Profile: cfg.ProfileName, Out: renderOut, Files: files})
+	}
+	return nil
+}
+
+// readRenderFile reads a single profile from a JSON, YAML or CSV file and validates it
+func readRenderFile(filePath string) (*models.Config, error) {
+	data, format, err := config.ReadImportFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if format == config.FormatBundle {
+		return nil, fmt.Errorf("bundles cannot be rendered, import them first")
+	}
+	entries, err := config.ParseProfiles(data, format, config.CSVOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 {
+		return nil, fmt.Errorf("%s holds %d profiles, render needs exactly one", displayPath(filePath), len(entries))
+	}
+	if entries[0].Err != nil {
+		return nil, entries[0].Err
+	}
+
+	cfg := entries[0].Config
+	if cfg.ProfileName == "" {
+		cfg.ProfileName = config.DefaultProfile()
+	}
+	effective, _, err := config.ResolveConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if errs := config.ValidateConfig(effective); len(errs) > 0 {
+		return nil, &cliError{
+			code:    exitInvalid,
+			err:     fmt.Errorf("invalid profile: %s: %s", errs[0].Field, errs[0].Message),
+			details: errs,
+		}
+	}
+	return cfg, nil
+}
+
+func init() {
+	renderCmd.Flags().StringVarP(
+		&renderProfile,
+		"profile",
+		"p",
+		"",
+		"Profile to render (default: defaultProfile setting)",
+	)
+
+	renderCmd.Flags().StringVarP(
+		&renderFile,
+		"file",
+		"f",
+		"",
+		"Render a profile file (JSON, YAML or CSV) instead of a stored profile",
+	)
+
+	renderCmd.Flags().StringVar(
+		&renderOut,
+		"out",
+		"",
+		"Output directory",
+	)
+
+	renderCmd.Flags().BoolVar(
+		&renderForce,
+		"force",
+		false,
+		"Replace the content of a non-empty output directory",
+	)
+
+	RootCmd.AddCommand(renderCmd)
+}
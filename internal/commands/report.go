@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/events"
+	"github.com/otori-lab/otori-cli/internal/geoip"
+	"github.com/spf13/cobra"
+)
+
+var reportProfile string
+var reportFile string
+var reportBy string
+var reportTop int
+var reportJson bool
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Summarize attacker activity of a honeypot",
+	Long: "Summarize the Cowrie events of a honeypot, grouped by source IP, country or ASN.\n" +
+		"Country and ASN require MaxMind-format .mmdb files in ~/.otori/geoip (no network lookups).",
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runReport()
+	},
+}
+
+// reportOutput is the JSON/YAML result of report (kind Report)
+type reportOutput struct {
+	Profile   string         `json:"profile,omitempty"`
+	By        string         `json:"by"`
+	Events    int            `json:"events"`
+	GeoIP     bool           `json:"geoip"`
+	Breakdown []events.Count `json:"breakdown"`
+}
+
+func runReport() error {
+	var keyFn events.KeyFunc
+	by := strings.ToLower(reportBy)
+	switch by {
+	case "ip":
+		keyFn = events.ByIP
+	case "country":
+		keyFn = events.ByCountry
+	case "asn":
+		keyFn = events.ByASN
+	default:
+		return usageError("unsupported grouping: %s (use: ip, country, asn)", reportBy)
+	}
+
+	// Load events from a local file or from the profile's container
+	var evs []events.Event
+	var err error
+	profileName := reportProfile
+	if reportFile != "" {
+		evs, err = events.ReadFileEvents(reportFile)
+	} else {
+		if profileName == "" {
+			profileName = config.DefaultProfile()
+		}
+		evs, err = events.ReadProfileEvents(profileName)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Enrich with GeoIP data when databases are available
+	db, err := geoip.OpenDefault()
+	if err != nil {
+		fmt.Fprintf(errOut, "Warning: %v (GeoIP enrichment disabled)\n", err)
+	}
+	defer db.Close()
+	db.Annotate(evs)
+
+	breakdown := events.CountBy(evs, keyFn)
+	if reportTop > 0 && len(breakdown) > reportTop {
+		breakdown = breakdown[:reportTop]
+	}
+
+	if structuredOutput() {
+		if breakdown == nil {
+			breakdown = []events.Count{}
+		}
+		return printResult("Report", reportOutput{
+			Profile:   profileName,
+			By:        by,
+			Events:    len(evs),
+			GeoIP:     db.Available(),
+			Breakdown: breakdown,
+		})
+	}
+
+	if profileName != "" {
+		fmt.Fprintf(out, "Report for profile '%s' (%d events)\n\n", profileName, len(evs))
+	} else {
+		fmt.Fprintf(out, "Report for %s (%d events)\n\n", reportFile, len(evs))
+	}
+
+	if !db.Available() && by != "ip" {
+		fmt.Fprintf(out, "No GeoIP database found in %s, %s data is unavailable.\n\n", geoip.GetGeoIPDir(), by)
+	}
+
+	if len(breakdown) == 0 {
+		fmt.Fprintln(out, "No attacker activity recorded yet.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tNAME\tEVENTS\tUNIQUE IPS\n", strings.ToUpper(by))
+	for _, c := range breakdown {
+		label := c.Label
+		if label == "" {
+			label = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", c.Key, label, c.Events, c.UniqueIPs)
+	}
+	w.Flush()
+	fmt.Fprintln(out)
+
+	return nil
+}
+
+func init() {
+	reportCmd.Flags().StringVarP(&reportProfile, "profile", "p", "", "Profile to report on (default: defaultProfile setting)")
+	reportCmd.Flags().StringVarP(&reportFile, "file", "f", "", "Read events from a local cowrie.json file instead of the container")
+	reportCmd.Flags().StringVarP(&reportBy, "by", "b", "country", "Group by: ip, country, asn")
+	reportCmd.Flags().IntVarP(&reportTop, "top", "n", 10, "Number of rows to display (0 for all)")
+	reportCmd.Flags().BoolVarP(&reportJson, "json", "j", false, "Output as JSON (same as -o json)")
+
+	RootCmd.AddCommand(reportCmd)
+}
@@ -1,8 +1,111 @@
 package commands
 
-import "github.com/spf13/cobra"
+import (
+	"path/filepath"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/otori-lab/otori-cli/internal/version"
+	"github.com/spf13/cobra"
+)
+
+var rootHome string
+var rootRuntime string
+var rootColor string
+var rootOutput string
 
 var RootCmd = &cobra.Command{
-	Use:   "otori",
-	Short: "Otori honeypot CLI",
+	Use:     "otori",
+	Short:   "Otori honeypot CLI",
+	Version: version.Version,
+	// Commands return their errors (RunE), a failing command is not a usage error
+	SilenceUsage: true,
+	// Errors are printed by execute, as text or as a JSON/YAML document
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return applyGlobalSettings(cmd)
+	},
+}
+
+// applyGlobalSettings applies the global flags and settings (see 'otori config list')
+// before any command: flag > environment > config.yaml > default
+func applyGlobalSettings(cmd *cobra.Command) error {
+	if rootHome != "" {
+		home, err := filepath.Abs(rootHome)
+		if err != nil {
+			return err
+		}
+		config.SetOtoriDir(home)
+	}
+	if _, err := config.ResolveOtoriDir(); err != nil {
+		return err
+	}
+
+	config.ResetSettingFlags()
+	if cmd.Flags().Changed("runtime") {
+		if err := config.SetSettingFlag("runtime", rootRuntime); err != nil {
+			return err
+		}
+	}
+	if cmd.Flags().Changed("color") {
+		if err := config.SetSettingFlag("color", rootColor); err != nil {
+			return err
+		}
+	}
+	if rootOutputChanged(cmd) {
+		if err := config.SetSettingFlag("output", rootOutput); err != nil {
+			return withCode(exitUsage, err)
+		}
+	}
+
+	// Fail early on an invalid config.yaml or OTORI_* variable
+	if _, err := config.ListSettings(); err != nil {
+		return err
+	}
+
+	container.SetRuntime(config.ContainerRuntime())
+	switch config.ColorMode() {
+	case "never":
+		lipgloss.SetColorProfile(termenv.Ascii)
+	case "always":
+		lipgloss.SetColorProfile(termenv.ANSI256)
+	}
+	return applyOutput(cmd)
+}
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(
+		&rootHome,
+		"home",
+		"",
+		"Otori directory (default: $OTORI_HOME or ~/.otori)",
+	)
+
+	RootCmd.PersistentFlags().StringVar(
+		&rootRuntime,
+		"runtime",
+		"",
+		"Container CLI: 'docker' or 'podman' (default: runtime setting)",
+	)
+
+	RootCmd.PersistentFlags().StringVar(
+		&rootColor,
+		"color",
+		"",
+		"Colour mode: 'auto', 'always' or 'never' (default: color setting)",
+	)
+
+	RootCmd.PersistentFlags().StringVarP(
+		&rootOutput,
+		"output",
+		"o",
+		"",
+		"Output format: 'table', 'json' or 'yaml' (default: output setting)",
+	)
+
+	RootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withCode(exitUsage, err)
+	})
 }
@@ -0,0 +1,422 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/otori-lab/otori-cli/internal/service"
+	"github.com/spf13/cobra"
+)
+
+var serviceProfile string
+var serviceScope string
+var serviceQuadlet bool
+var serviceDryRun bool
+var serviceNoStart bool
+
+// serviceCmd is the parent command of the systemd services of the honeypots
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Run honeypots at boot as systemd units or Podman Quadlet files",
+	Long: "Install a honeypot as a systemd unit wrapping 'otori deploy' and 'otori stop',\n" +
+		"or as a Podman Quadlet file running its container, in the system or user manager.\n" +
+		"The installed units are the inventory of what runs at boot: see 'otori service list'.",
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:         "install",
+	Short:       "Install and start the service of a profile",
+	Args:        cobra.NoArgs,
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runServiceInstall()
+	},
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:         "uninstall",
+	Short:       "Stop and remove the service of a profile",
+	Args:        cobra.NoArgs,
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runServiceUninstall()
+	},
+}
+
+var serviceStartCmd = &cobra.Command{
+	Use:         "start",
+	Short:       "Start the service of a profile",
+	Args:        cobra.NoArgs,
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runServiceAction("start")
+	},
+}
+
+var serviceStopCmd = &cobra.Command{
+	Use:         "stop",
+	Short:       "Stop the service of a profile (it starts again at boot)",
+	Args:        cobra.NoArgs,
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runServiceAction("stop")
+	},
+}
+
+var serviceStatusCmd = &cobra.Command{
+	Use:         "status",
+	Short:       "Show the service of a profile",
+	Args:        cobra.NoArgs,
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runServiceAction("")
+	},
+}
+
+var serviceListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "List the installed services",
+	Args:        cobra.NoArgs,
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runServiceList()
+	},
+}
+
+// serviceResult is the JSON/YAML result of the service commands (kind Service, ServiceList)
+type serviceResult struct {
+	service.Unit
+	Active  string `json:"active"`            // systemctl is-active: active, inactive, failed...
+	Enabled string `json:"enabled"`           // systemctl is-enabled: enabled, disabled, generated...
+	Content string `json:"content,omitempty"` // unit file, with install --dry-run
+}
+
+// serviceProfileName returns the profile of the service commands
+func serviceProfileName() string {
+	if serviceProfile == "" {
+		return config.DefaultProfile()
+	}
+	return serviceProfile
+}
+
+// checkServiceScope checks the --scope flag
+func checkServiceScope() error {
+	if serviceScope != "" && serviceScope != service.ScopeSystem && serviceScope != service.ScopeUser {
+		return usageError("invalid scope '%s' (expected %s or %s)", serviceScope, service.ScopeSystem, service.ScopeUser)
+	}
+	return nil
+}
+
+// findService returns the installed service of a profile
+func findService(profileName string) (service.Unit, error) {
+	if err := checkServiceScope(); err != nil {
+		return service.Unit{}, err
+	}
+	units := service.Find(profileName, serviceScope)
+	switch len(units) {
+	case 0:
+		return service.Unit{}, fmt.Errorf("service of profile '%s' %w (install it with: otori service install -p %s)", profileName, config.ErrNotFound, profileName)
+	case 1:
+		return units[0], nil
+	}
+	return service.Unit{}, usageError("profile '%s' has a system and a user service, choose one with --scope", profileName)
+}
+
+// systemctl runs systemctl for the manager of a unit, its output goes to the terminal
+func systemctl(scope string, args ...string) error {
+	cmd := service.Systemctl(scope, args...)
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	if _, err := container.Run(cmd); err != nil {
+		return fmt.Errorf("systemctl %s failed: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// serviceState returns a unit with its state as reported by systemctl
+func serviceState(unit service.Unit) serviceResult {
+	state := func(query string) string {
+		// is-active and is-enabled print the state and fail when it is not active/enabled
+		output, _ := container.Run(service.Systemctl(unit.Scope, query, unit.Name))
+		if s := strings.TrimSpace(string(output)); s != "" {
+			return s
+		}
+		return "unknown"
+	}
+	return serviceResult{Unit: unit, Active: state("is-active"), Enabled: state("is-enabled")}
+}
+
+func runServiceInstall() error {
+	if err := checkServiceScope(); err != nil {
+		return err
+	}
+	scope := serviceScope
+	if scope == "" {
+		scope = service.ScopeSystem
+	}
+	kind := service.KindSystemd
+	if serviceQuadlet {
+		kind = service.KindQuadlet
+		if container.Runtime() != "podman" {
+			return usageError("Quadlet files are run by Podman, use --runtime podman (or the runtime setting)")
+		}
+	}
+
+	profileName := serviceProfileName()
+	cfg, err := config.ReadEffectiveConfig(profileName)
+	if err != nil {
+		return fmt.Errorf("profile '%s' not found: %w", profileName, err)
+	}
+	if cfg.Type != "classic" {
+		return fmt.Errorf("profile '%s' is of type '%s', only 'classic' profiles can be run as a service", profileName, cfg.Type)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error locating the otori executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+	profileDir := filepath.Join(config.GetConfigDir(), profileName)
+	content := service.Render(cfg, service.Options{
+		Kind:       kind,
+		Scope:      scope,
+		Executable: executable,
+		OtoriHome:  config.GetOtoriDir(),
+		Runtime:    container.Runtime(),
+		ProfileDir: profileDir,
+	})
+
+	dir, err := service.Dir(kind, scope)
+	if err != nil {
+		return err
+	}
+	unit := service.Unit{
+		Profile: profileName,
+		Name:    service.UnitName(profileName),
+		Kind:    kind,
+		Scope:   scope,
+		Path:    filepath.Join(dir, service.FileName(profileName, kind)),
+	}
+
+	if serviceDryRun {
+		if structuredOutput() {
+			return printResult("Service", serviceResult{Unit: unit, Active: "inactive", Enabled: "disabled", Content: content})
+		}
+		fmt.Fprintf(out, "# %s\n", unit.Path)
+		fmt.Fprint(out, content)
+		return nil
+	}
+
+	// Both kinds give a unit named otori-<profile>.service
+	for _, other := range service.Find(profileName, scope) {
+		if other.Kind != kind {
+			return fmt.Errorf("profile '%s' already has a %s service (%s), uninstall it first: %w", profileName, other.Kind, other.Path, config.ErrExists)
+		}
+	}
+
+	// Quadlet runs the container itself: the image checks and fs.pickle updates of deploy do not happen
+	if kind == service.KindQuadlet {
+		if cfg.Baked {
+			_, _, err = builtImage(cfg)
+		} else {
+			_, err = ensureImage(config.ProfileImage(cfg))
+		}
+		if err != nil {
+			return err
+		}
+		if !cfg.Baked && len(profileFsctlCommands(profileDir)) > 0 {
+			fmt.Fprintf(out, "Warning: the custom honeyfs entries and txtcmds of '%s' are not added to fs.pickle by Quadlet,\n", profileName)
+			fmt.Fprintf(out, "         bake them into an image first: otori build -p %s --use\n\n", profileName)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return serviceWriteError(dir, err)
+	}
+	if err := os.WriteFile(unit.Path, []byte(content), 0644); err != nil {
+		return serviceWriteError(unit.Path, err)
+	}
+	fmt.Fprintf(out, "✓ Service %s written to %s\n", unit.Name, unit.Path)
+
+	if err := systemctl(scope, "daemon-reload"); err != nil {
+		return err
+	}
+	switch {
+	case kind == service.KindSystemd && serviceNoStart:
+		err = systemctl(scope, "enable", unit.Name)
+	case kind == service.KindSystemd:
+		err = systemctl(scope, "enable", "--now", unit.Name)
+	case !serviceNoStart:
+		// Quadlet units are generated: [Install] of the file enables them
+		err = systemctl(scope, "start", unit.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "✓ Honeypot '%s' runs as %s (%s, %s scope)\n", profileName, unit.Name, kind, scope)
+	if scope == service.ScopeUser {
+		fmt.Fprintln(out, "  User services only start at boot with lingering: loginctl enable-linger")
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "To check the service: otori service status -p", profileName)
+
+	if structuredOutput() {
+		return printResult("Service", serviceState(unit))
+	}
+	return nil
+}
+
+// serviceWriteError explains the usual cause of a failed unit file write
+func serviceWriteError(path string, err error) error {
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("error writing %s: %w (run as root or use --scope user)", path, err)
+	}
+	return fmt.Errorf("error writing %s: %w", path, err)
+}
+
+func runServiceUninstall() error {
+	profileName := serviceProfileName()
+	unit, err := findService(profileName)
+	if err != nil {
+		return err
+	}
+
+	if unit.Kind == service.KindSystemd {
+		err = systemctl(unit.Scope, "disable", "--now", unit.Name)
+	} else {
+		err = systemctl(unit.Scope, "stop", unit.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(unit.Path); err != nil {
+		return serviceWriteError(unit.Path, err)
+	}
+	if err := systemctl(unit.Scope, "daemon-reload"); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "✓ Service %s removed (%s)\n", unit.Name, unit.Path)
+
+	if structuredOutput() {
+		return printResult("Service", serviceResult{Unit: unit, Active: "inactive", Enabled: "disabled"})
+	}
+	return nil
+}
+
+// runServiceAction starts or stops the service of a profile, then prints its state ("" only prints it)
+func runServiceAction(action string) error {
+	profileName := serviceProfileName()
+	unit, err := findService(profileName)
+	if err != nil {
+		return err
+	}
+
+	if action != "" {
+		if err := systemctl(unit.Scope, action, unit.Name); err != nil {
+			return err
+		}
+	}
+
+	result := serviceState(unit)
+	if structuredOutput() {
+		return printResult("Service", result)
+	}
+
+	logs := "journalctl -u " + unit.Name
+	if unit.Scope == service.ScopeUser {
+		logs = "journalctl --user-unit " + unit.Name
+	}
+	fmt.Fprintf(out, "Service of profile '%s':\n", profileName)
+	fmt.Fprintf(out, "  Unit:    %s (%s, %s scope)\n", unit.Name, unit.Kind, unit.Scope)
+	fmt.Fprintf(out, "  File:    %s\n", unit.Path)
+	fmt.Fprintf(out, "  Active:  %s\n", result.Active)
+	fmt.Fprintf(out, "  Enabled: %s\n", result.Enabled)
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Logs:", logs)
+	return nil
+}
+
+func runServiceList() error {
+	results := []serviceResult{}
+	for _, unit := range service.List() {
+		results = append(results, serviceState(unit))
+	}
+	if structuredOutput() {
+		return printResult("ServiceList", results)
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintln(out, "No services installed. Install one with: otori service install -p <profile>")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROFILE\tUNIT\tKIND\tSCOPE\tACTIVE\tENABLED")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Profile, r.Name, r.Kind, r.Scope, r.Active, r.Enabled)
+	}
+	w.Flush()
+	return nil
+}
+
+// checkNoService refuses to change the name or the files of a profile run by a service
+func checkNoService(profileName string) error {
+	if units := service.Find(profileName, ""); len(units) > 0 {
+		unit := units[0]
+		return fmt.Errorf("service %s (%s scope) %w for profile '%s', uninstall it first with 'otori service uninstall -p %s'",
+			unit.Name, unit.Scope, config.ErrExists, profileName, profileName)
+	}
+	return nil
+}
+
+// printServiceNote reminds that a honeypot stopped by hand is started again by its service
+func printServiceNote(profileName string) {
+	// Not when otori runs as the service itself (ExecStop)
+	if os.Getenv("INVOCATION_ID") != "" {
+		return
+	}
+	for _, unit := range service.Find(profileName, "") {
+		fmt.Fprintf(out, "Note: '%s' is run by %s (%s scope) and starts again at boot,\n", profileName, unit.Name, unit.Scope)
+		fmt.Fprintf(out, "      use 'otori service stop -p %s' or 'otori service uninstall -p %s'\n", profileName, profileName)
+	}
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{serviceInstallCmd, serviceUninstallCmd, serviceStartCmd, serviceStopCmd, serviceStatusCmd} {
+		cmd.Flags().StringVarP(&serviceProfile, "profile", "p", "", "Profile of the service (default: defaultProfile setting)")
+		cmd.Flags().StringVar(&serviceScope, "scope", "", "Service manager: system or user (install default: system)")
+	}
+	serviceInstallCmd.Flags().BoolVar(&serviceQuadlet, "quadlet", false, "Write a Podman Quadlet file instead of a unit running otori")
+	serviceInstallCmd.Flags().BoolVar(&serviceDryRun, "dry-run", false, "Print the unit file without installing it")
+	serviceInstallCmd.Flags().BoolVar(&serviceNoStart, "no-start", false, "Install without starting the honeypot now")
+
+	serviceCmd.AddCommand(serviceInstallCmd)
+	serviceCmd.AddCommand(serviceUninstallCmd)
+	serviceCmd.AddCommand(serviceStartCmd)
+	serviceCmd.AddCommand(serviceStopCmd)
+	serviceCmd.AddCommand(serviceStatusCmd)
+	serviceCmd.AddCommand(serviceListCmd)
+	RootCmd.AddCommand(serviceCmd)
+}
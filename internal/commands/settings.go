@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/spf13/cobra"
+)
+
+// configCmd is the parent command of the global settings
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the global settings (~/.otori/config.yaml)",
+	Long: "Read and write the global settings of otori (~/.otori/config.yaml).\n" +
+		"Each setting can be overridden by an OTORI_<KEY> environment variable\n" +
+		"(e.g. OTORI_DEFAULT_PROFILE) and some by a flag: flag > environment > file > default.\n\n" +
+		"Settings: " + strings.Join(config.SettingKeys(), ", "),
+}
+
+// configGetCmd prints the effective value of a setting
+var configGetCmd = &cobra.Command{
+	Use:         "get <key>",
+	Short:       "Print the effective value of a setting",
+	Args:        cobra.ExactArgs(1),
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		setting, err := config.GetSetting(args[0])
+		if err != nil {
+			return err
+		}
+		if structuredOutput() {
+			return printResult("Setting", setting)
+		}
+		fmt.Fprintln(out, setting.Value)
+		return nil
+	},
+}
+
+// configSetCmd writes a setting in config.yaml
+var configSetCmd = &cobra.Command{
+	Use:         "set <key> <value>",
+	Short:       "Write a setting in config.yaml (an empty value restores the default)",
+	Args:        cobra.ExactArgs(2),
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConfigSet(args[0], args[1])
+	},
+}
+
+// configListCmd lists every setting with its value and source
+var configListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "List the settings, their effective value and where it comes from",
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConfigList()
+	},
+}
+
+// runConfigSet writes a setting and warns when a stronger source hides it
+func runConfigSet(key, value string) error {
+	if key == "image" && value != "" {
+		value = pinImageSetting(value)
+	}
+	if err := config.SetSetting(key, value); err != nil {
+		return err
+	}
+
+	if value == "" {
+		fmt.Fprintf(out, "✓ %s reset to its default in %s\n", key, config.SettingsPath())
+	} else {
+		fmt.Fprintf(out, "✓ %s set to %s in %s\n", key, value, config.SettingsPath())
+	}
+
+	setting, err := config.GetSetting(key)
+	if err != nil {
+		return err
+	}
+	switch setting.Source {
+	case config.SettingFromEnv:
+		fmt.Fprintf(out, "Warning: %s is overridden by %s=%s\n", key, config.SettingEnv(key), setting.Value)
+	case config.SettingFromFlag:
+		fmt.Fprintf(out, "Warning: %s is overridden by a flag (%s)\n", key, setting.Value)
+	}
+	if structuredOutput() {
+		return printResult("Setting", setting)
+	}
+	return nil
+}
+
+// pinImageSetting pins an image reference with its digests before it becomes
+// the image of every new profile. An invalid reference is left to SetSetting.
+func pinImageSetting(value string) string {
+	ref, err := container.ParseImageRef(value)
+	if err != nil || ref.Pinned() {
+		return value
+	}
+	pinned, err := container.PinImage(ref)
+	if err != nil {
+		fmt.Fprintf(out, "Warning: image %s not pinned: %v\n", ref, err)
+		return value
+	}
+	fmt.Fprintf(out, "✓ Image pinned: %s\n", pinned)
+	return pinned.String()
+}
+
+// runConfigList prints the settings as a table
+func runConfigList() error {
+	settings, err := config.ListSettings()
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		return printResult("SettingList", settings)
+	}
+
+	fmt.Fprintf(out, "Settings file: %s\n\n", config.SettingsPath())
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE\tENV")
+	for _, setting := range settings {
+		value := setting.Value
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", setting.Key, value, setting.Source, config.SettingEnv(setting.Key))
+	}
+	return w.Flush()
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configListCmd)
+
+	RootCmd.AddCommand(configCmd)
+}
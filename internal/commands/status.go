@@ -1,13 +1,13 @@
 package commands
 
 import (
-	"encoding/json"
 	"fmt"
+	"os"
+	"text/tabwriter"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/otori-lab/otori-cli/internal/config"
 	"github.com/otori-lab/otori-cli/internal/tui"
-	"github.com/otori-lab/otori-cli/internal/ui"
 	"github.com/spf13/cobra"
 )
 
@@ -16,65 +16,89 @@ var statusJson bool
 var statusAll bool
 
 var statusCmd = &cobra.Command{
-	Use:   "status",
-	Short: "Display status of honeypots",
-	Long:  "Display status of running honeypots. Use -a to show all profiles (including stopped).",
-	Run: func(cmd *cobra.Command, args []string) {
-		// Get running honeypots from Docker
-		honeypots := tui.GetRunningHoneypots()
-
-		// If --all flag, also include stopped profiles
-		if statusAll {
-			honeypots = addStoppedProfiles(honeypots)
-		}
-
-		// Filter by profile if specified
-		if statusProfile != "" {
-			var filtered []tui.Honeypot
-			for _, hp := range honeypots {
-				if hp.Profile == statusProfile {
-					filtered = append(filtered, hp)
-				}
+	Use:         "status",
+	Short:       "Display status of honeypots",
+	Long:        "Display status of running honeypots. Use -a to show all profiles (including stopped).",
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// JSON/YAML output mode (-o, --json or output setting)
+		if structuredOutput() {
+			honeypots := collectHoneypots()
+			if honeypots == nil {
+				honeypots = []tui.Honeypot{}
 			}
-			honeypots = filtered
+			return printResult("HoneypotList", honeypots)
 		}
 
-		// JSON output mode
-		if statusJson {
-			fmt.Println(ui.GetLogo())
-			outputJSON(honeypots)
-			return
+		// Plain table when stdout is not a terminal (pipes, cron, CI)
+		if !isTerminal(os.Stdout) {
+			outputTable(collectHoneypots())
+			return nil
 		}
 
-		// Interactive TUI mode
-		model := tui.NewStatusModel(honeypots)
+		// Interactive TUI mode, refreshed periodically
+		model := tui.NewStatusModel(collectHoneypots)
 		p := tea.NewProgram(model)
 
 		if _, err := p.Run(); err != nil {
-			fmt.Printf("Error running status: %v\n", err)
+			return fmt.Errorf("error running status: %w", err)
 		}
+		return nil
 	},
 }
 
-// addStoppedProfiles adds profiles that exist but are not running
-func addStoppedProfiles(running []tui.Honeypot) []tui.Honeypot {
+// collectHoneypots returns the honeypots selected by the status flags
+func collectHoneypots() []tui.Honeypot {
+	// Get otori containers from Docker (running, crashed and stopped)
+	honeypots := tui.GetHoneypots()
+
+	// If --all flag, also include stopped profiles, otherwise
+	// only show running and failing honeypots
+	if statusAll {
+		honeypots = addStoppedProfiles(honeypots)
+	} else {
+		var visible []tui.Honeypot
+		for _, hp := range honeypots {
+			if hp.Status != tui.StatusStopped {
+				visible = append(visible, hp)
+			}
+		}
+		honeypots = visible
+	}
+
+	// Filter by profile if specified
+	if statusProfile != "" {
+		var filtered []tui.Honeypot
+		for _, hp := range honeypots {
+			if hp.Profile == statusProfile {
+				filtered = append(filtered, hp)
+			}
+		}
+		honeypots = filtered
+	}
+
+	return honeypots
+}
+
+// addStoppedProfiles adds profiles that have no container
+func addStoppedProfiles(honeypots []tui.Honeypot) []tui.Honeypot {
 	// Get all profiles
 	profiles, err := config.ListConfigs()
 	if err != nil {
-		return running
+		return honeypots
 	}
 
-	// Create a map of running profiles
-	runningMap := make(map[string]bool)
-	for _, hp := range running {
-		runningMap[hp.Profile] = true
+	// Create a map of profiles having a container
+	deployedMap := make(map[string]bool)
+	for _, hp := range honeypots {
+		deployedMap[hp.Profile] = true
 	}
 
-	// Add stopped profiles
+	// Add profiles never deployed (or removed with stop)
 	for _, profileName := range profiles {
-		if !runningMap[profileName] {
+		if !deployedMap[profileName] {
 			// Read profile config to get details
-			cfg, err := config.ReadConfig(profileName)
+			cfg, err := config.ReadEffectiveConfig(profileName)
 			if err != nil {
 				continue
 			}
@@ -85,28 +109,46 @@ func addStoppedProfiles(running []tui.Honeypot) []tui.Honeypot {
 				Type:       cfg.Type,
 				Status:     tui.StatusStopped,
 				ServerName: cfg.ServerName,
-				Port:       2222,
+				Port:       cfg.SSHPort(),
 			}
-			running = append(running, honeypot)
+			honeypots = append(honeypots, honeypot)
 		}
 	}
 
-	return running
+	return honeypots
 }
 
-// outputJSON outputs honeypots as JSON
-func outputJSON(honeypots []tui.Honeypot) {
-	data, err := json.MarshalIndent(honeypots, "", "  ")
-	if err != nil {
-		fmt.Printf("Error encoding JSON: %v\n", err)
+// outputTable outputs honeypots as a plain text table
+func outputTable(honeypots []tui.Honeypot) {
+	if len(honeypots) == 0 {
+		fmt.Fprintln(out, "No honeypots running. Deploy one with: otori deploy")
 		return
 	}
-	fmt.Println(string(data))
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPROFILE\tTYPE\tSTATUS\tSERVER\tPORT\tUPTIME\tDRIFT\tIMAGE\tERROR")
+	for _, hp := range honeypots {
+		uptime := hp.Uptime
+		if uptime == "" {
+			uptime = "-"
+		}
+		drift := "-"
+		if hp.Drift {
+			drift = "yes"
+		}
+		lastError := hp.LastError
+		if lastError == "" {
+			lastError = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			hp.Name, hp.Profile, hp.Type, hp.Status, hp.ServerName, hp.Port, uptime, drift, dash(hp.ImageVersion()), lastError)
+	}
+	w.Flush()
 }
 
 func init() {
 	statusCmd.Flags().StringVarP(&statusProfile, "profile", "p", "", "Filter by profile name")
-	statusCmd.Flags().BoolVarP(&statusJson, "json", "j", false, "Output as JSON")
+	statusCmd.Flags().BoolVarP(&statusJson, "json", "j", false, "Output as JSON (same as -o json)")
 	statusCmd.Flags().BoolVarP(&statusAll, "all", "a", false, "Show all profiles (including stopped)")
 
 	RootCmd.AddCommand(statusCmd)
@@ -3,11 +3,10 @@ package commands
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 
 	"github.com/otori-lab/otori-cli/internal/config"
-	"github.com/otori-lab/otori-cli/internal/ui"
+	"github.com/otori-lab/otori-cli/internal/container"
 	"github.com/spf13/cobra"
 )
 
@@ -15,28 +14,52 @@ var stopProfile string
 var stopForce bool
 
 var stopCmd = &cobra.Command{
-	Use:   "stop",
-	Short: "Stop a running honeypot",
-	Long:  "Stop a running honeypot container using Docker Compose",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(ui.GetLogo())
-
-		if err := runStop(); err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
-		}
+	Use:         "stop",
+	Short:       "Stop a running honeypot",
+	Long:        "Stop a running honeypot container using Docker Compose",
+	Annotations: structuredCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printLogo()
+
+		return runStop()
 	},
 }
 
+// stopResult is the JSON/YAML result of stop (kind StopResult)
+type stopResult struct {
+	Profile   string `json:"profile"`
+	Container string `json:"container"`
+	Force     bool   `json:"force"`
+}
+
 func runStop() error {
-	// Use default profile if not specified
+	// Use the default profile (defaultProfile setting) if not specified
 	profileName := stopProfile
 	if profileName == "" {
-		profileName = "default"
+		profileName = config.DefaultProfile()
+	}
+
+	if err := stopHoneypot(profileName, stopForce); err != nil {
+		return err
+	}
+	printServiceNote(profileName)
+	if !structuredOutput() {
+		return nil
+	}
+	return printResult("StopResult", stopResult{Profile: profileName, Container: "otori-" + profileName, Force: stopForce})
+}
+
+// stopHoneypot stops and removes the honeypot container of a profile
+func stopHoneypot(profileName string, force bool) error {
+	// Serialize with other otori processes working on this profile (edit, deploy, delete...)
+	lock, err := config.LockProfile(profileName)
+	if err != nil {
+		return err
 	}
+	defer lock.Unlock()
 
 	// Check if profile exists
-	_, err := config.ReadConfig(profileName)
+	_, err = config.ReadConfig(profileName)
 	if err != nil {
 		return fmt.Errorf("profile '%s' not found: %w", profileName, err)
 	}
@@ -47,32 +70,32 @@ func runStop() error {
 	// Check if docker-compose.yml exists
 	dockerComposePath := filepath.Join(profileDir, "docker-compose.yml")
 	if _, err := os.Stat(dockerComposePath); os.IsNotExist(err) {
-		return fmt.Errorf("docker-compose.yml not found in profile '%s'", profileName)
+		return fmt.Errorf("docker-compose.yml %w in profile '%s'", config.ErrNotFound, profileName)
 	}
 
-	fmt.Printf("Stopping honeypot '%s'...\n", profileName)
+	fmt.Fprintf(out, "Stopping honeypot '%s'...\n", profileName)
 
 	// Build docker compose command
-	var dockerCmd *exec.Cmd
-	if stopForce {
+	var dockerCmd container.Command
+	if force {
 		// Force stop with timeout 0
-		dockerCmd = exec.Command("docker", "compose", "down", "-t", "0")
+		dockerCmd = container.Docker("compose", "down", "-t", "0")
 	} else {
-		dockerCmd = exec.Command("docker", "compose", "down")
+		dockerCmd = container.Docker("compose", "down")
 	}
 
 	// Set working directory to profile directory
 	dockerCmd.Dir = profileDir
-	dockerCmd.Stdout = os.Stdout
-	dockerCmd.Stderr = os.Stderr
+	dockerCmd.Stdout = out
+	dockerCmd.Stderr = errOut
 
 	// Run docker compose down
-	if err := dockerCmd.Run(); err != nil {
+	if _, err := container.Run(dockerCmd); err != nil {
 		return fmt.Errorf("failed to stop containers: %w", err)
 	}
 
-	fmt.Println()
-	fmt.Printf("✓ Honeypot '%s' stopped successfully!\n", profileName)
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "✓ Honeypot '%s' stopped successfully!\n", profileName)
 
 	return nil
 }
@@ -83,7 +106,7 @@ func init() {
 		"profile",
 		"p",
 		"",
-		"Profile to stop (default: 'default')",
+		"Profile to stop (default: defaultProfile setting)",
 	)
 
 	stopCmd.Flags().BoolVarP(
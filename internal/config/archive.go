@@ -0,0 +1,208 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/version"
+)
+
+// Archive layout: ~/.otori/archive/<profile>-<timestamp>/ holds a metadata file,
+// the profile bundle (or the JSON of a legacy profile) and one tar.gz per volume
+const (
+	archiveMetadataName = "otori-archive.json"
+	archiveBundleName   = "profile.tar.gz"
+	archiveTimeLayout   = "20060102-150405"
+)
+
+// Archive is a deleted profile kept in ~/.otori/archive
+type Archive struct {
+	ID           string   `json:"-"` // directory name, <profile>-<timestamp>
+	Dir          string   `json:"-"`
+	Profile      string   `json:"profile"`
+	OtoriVersion string   `json:"otoriVersion"`
+	CreatedAt    string   `json:"createdAt"`
+	Legacy       bool     `json:"legacy,omitempty"`  // the profile JSON is stored as is, without bundle
+	Volumes      []string `json:"volumes,omitempty"` // volume kinds saved in the archive (logs, downloads)
+}
+
+// GetArchiveDir returns the directory of archived profiles
+func GetArchiveDir() string {
+	return filepath.Join(GetOtoriDir(), "archive")
+}
+
+// VolumePath returns the file holding the data of a volume kind
+func (a *Archive) VolumePath(kind string) string {
+	return filepath.Join(a.Dir, kind+".tar.gz")
+}
+
+// HasVolume returns true if the data of a volume kind was saved
+func (a *Archive) HasVolume(kind string) bool {
+	for _, v := range a.Volumes {
+		if v == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ArchiveProfile saves the files of a profile to a new archive directory.
+// Volume data is added afterwards by the caller (see AddArchiveVolume).
+func ArchiveProfile(profileName string) (*Archive, error) {
+	if !ProfileExists(profileName) {
+		return nil, fmt.Errorf("profile '%s' %w", profileName, ErrNotFound)
+	}
+
+	now := time.Now()
+	archive := &Archive{
+		ID:           profileName + "-" + now.Format(archiveTimeLayout),
+		Profile:      profileName,
+		OtoriVersion: version.Version,
+		CreatedAt:    now.Format(time.RFC3339),
+		Legacy:       isLegacyProfile(profileName),
+	}
+	archive.Dir = filepath.Join(GetArchiveDir(), archive.ID)
+	if _, err := os.Stat(archive.Dir); err == nil {
+		return nil, fmt.Errorf("archive %s %w", archive.Dir, ErrExists)
+	}
+	if err := os.MkdirAll(archive.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating archive directory: %w", err)
+	}
+
+	var err error
+	if archive.Legacy {
+		var data []byte
+		if data, err = os.ReadFile(legacyProfilePath(profileName)); err == nil {
+			err = os.WriteFile(filepath.Join(archive.Dir, profileName+".json"), data, 0644)
+		}
+	} else {
+		err = ExportBundle(profileName, filepath.Join(archive.Dir, archiveBundleName))
+	}
+	if err == nil {
+		err = archive.save()
+	}
+	if err != nil {
+		os.RemoveAll(archive.Dir)
+		return nil, fmt.Errorf("error archiving profile: %w", err)
+	}
+	return archive, nil
+}
+
+// AddArchiveVolume records that the data of a volume kind was written to VolumePath(kind)
+func AddArchiveVolume(archive *Archive, kind string) error {
+	if !archive.HasVolume(kind) {
+		archive.Volumes = append(archive.Volumes, kind)
+	}
+	return archive.save()
+}
+
+// save writes the metadata file of the archive
+func (a *Archive) save() error {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(a.Dir, archiveMetadataName), data, 0644)
+}
+
+// ListArchives returns the archived profiles, most recent first
+func ListArchives() ([]*Archive, error) {
+	entries, err := os.ReadDir(GetArchiveDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []*Archive
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		archive, err := readArchive(entry.Name())
+		if err != nil {
+			continue
+		}
+		archives = append(archives, archive)
+	}
+	sort.SliceStable(archives, func(i, j int) bool {
+		return archives[i].CreatedAt > archives[j].CreatedAt
+	})
+	return archives, nil
+}
+
+// readArchive reads the metadata of an archive directory
+func readArchive(id string) (*Archive, error) {
+	dir := filepath.Join(GetArchiveDir(), id)
+	data, err := os.ReadFile(filepath.Join(dir, archiveMetadataName))
+	if err != nil {
+		return nil, err
+	}
+	archive := &Archive{}
+	if err := json.Unmarshal(data, archive); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", archiveMetadataName, err)
+	}
+	archive.ID = id
+	archive.Dir = dir
+	return archive, nil
+}
+
+// FindArchive returns an archive by ID, or the most recent archive of a profile
+func FindArchive(ref string) (*Archive, error) {
+	if ref == "" || strings.ContainsAny(ref, `/\`) || ref == "." || ref == ".." {
+		return nil, fmt.Errorf("invalid archive '%s'", ref)
+	}
+	if archive, err := readArchive(ref); err == nil {
+		return archive, nil
+	}
+
+	archives, err := ListArchives()
+	if err != nil {
+		return nil, err
+	}
+	for _, archive := range archives {
+		if archive.Profile == ref {
+			return archive, nil
+		}
+	}
+	return nil, fmt.Errorf("archive of '%s' %w (see: otori profiles restore --list)", ref, ErrNotFound)
+}
+
+// RestoreArchive brings back the profile files of an archive.
+// Volume data is restored by the caller from VolumePath.
+func RestoreArchive(archive *Archive, opts BundleImportOptions) (*BundleImportResult, error) {
+	if !archive.Legacy {
+		return ImportBundle(filepath.Join(archive.Dir, archiveBundleName), opts)
+	}
+
+	// Legacy profiles are restored through the regular import (and migrated on the way)
+	data, err := os.ReadFile(filepath.Join(archive.Dir, archive.Profile+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading archived profile: %w", err)
+	}
+	entries, err := ParseProfiles(data, FormatJSON, CSVOptions{})
+	if err != nil {
+		return nil, err
+	}
+	results, err := ImportProfiles(entries, ImportOptions{Name: opts.Name, OnConflict: opts.OnConflict})
+	if err != nil {
+		return nil, err
+	}
+	result := results[0]
+	if result.Status == ImportFailed {
+		return nil, fmt.Errorf("error restoring profile: %s", strings.Join(result.Errors, "; "))
+	}
+	return &BundleImportResult{
+		Profile:  result.Profile,
+		Original: archive.Profile,
+		Skipped:  result.Status == ImportSkipped,
+		Replaced: result.Status == ImportUpdated || result.Status == ImportUnchanged,
+		Files:    1,
+	}, nil
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/otori-lab/otori-cli/internal/version"
+)
+
+// FsctlFile is the file of the build context holding the fsctl commands run on fs.pickle
+const FsctlFile = "fsctl.txt"
+
+// DockerfileTemplate is the template for the Dockerfile of a baked profile image
+const DockerfileTemplate = `# Dockerfile for Cowrie Honeypot
+# Generated by Otori CLI
+# Profile: %s
+
+FROM %s
+
+LABEL otori.profile="%s" \
+      otori.config-hash="%s" \
+      otori.version="%s" \
+      otori.base-image="%s"
+
+COPY cowrie.cfg userdb.txt /cowrie/cowrie-git/etc/
+COPY honeyfs/ /cowrie/cowrie-git/honeyfs/
+COPY txtcmds/ /cowrie/cowrie-git/txtcmds/
+%s
+ENV COWRIE_HOSTNAME="%s"
+`
+
+// dockerfileFsctl pre-builds fs.pickle. The Cowrie image has no shell, so
+// Python itself feeds the commands to fsctl (as deploy does with docker exec).
+const dockerfileFsctl = `
+# Custom honeyfs entries and txtcmds, added to fs.pickle at build time
+COPY ` + FsctlFile + ` /cowrie/` + FsctlFile + `
+RUN ["/cowrie/cowrie-env/bin/python3", "-c", "import subprocess, sys; subprocess.run([sys.executable, '-m', 'cowrie.scripts.fsctl', '/cowrie/cowrie-git/src/cowrie/data/fs.pickle'], stdin=open('/cowrie/` + FsctlFile + `'), env={'PYTHONPATH': '/cowrie/cowrie-git/src'}, check=True)"]
+`
+
+// dockerfileEscape escapes a value written between double quotes in a Dockerfile
+func dockerfileEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(s)
+}
+
+// BakedImage returns the local name of the image run by a baked profile.
+// deploy gives it to the build of the current files of the profile (see BuildImage).
+func BakedImage(profileName string) string {
+	return "otori/" + strings.ToLower(profileName) + ":baked"
+}
+
+// BuildImage returns the name of the image built by "otori build" from a
+// build context: otori/<profile>:<hash>. The hash covers every file of the
+// context (configuration, honeyfs, txtcmds, fsctl commands, Dockerfile with
+// the base image reference) and the ID of the base image, so any change of
+// them gives another name.
+func BuildImage(profileName, contextDir, baseImageID string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(contextDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(contextDir, p)
+		if err != nil {
+			return err
+		}
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", filepath.ToSlash(rel), sum)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error hashing build context: %w", err)
+	}
+	fmt.Fprintf(h, "base\x00%s\n", baseImageID)
+	return "otori/" + strings.ToLower(profileName) + ":" + hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+// WriteDockerfile generates and writes the Dockerfile of a build context.
+// withFsctl adds the fs.pickle step, reading FsctlFile from the context.
+func WriteDockerfile(contextDir string, config *models.Config, withFsctl bool) error {
+	base := ProfileImage(config)
+	from := base
+	// A pinned image is checked before the build and used by its local name
+	if ref, err := container.ParseImageRef(base); err == nil {
+		from = ref.Tagged()
+	}
+	baked := *config
+	baked.Baked = true
+
+	fsctl := ""
+	if withFsctl {
+		fsctl = dockerfileFsctl
+	}
+
+	content := fmt.Sprintf(DockerfileTemplate,
+		config.ProfileName,
+		from,
+		config.ProfileName,
+		ConfigHash(&baked),
+		version.Version,
+		base,
+		fsctl,
+		dockerfileEscape(config.ServerName),
+	)
+
+	filename := filepath.Join(contextDir, "Dockerfile")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		return fmt.Errorf("error writing Dockerfile: %w", err)
+	}
+
+	return nil
+}
+
+// WriteBuildContext writes the build context of a profile image into an empty
+// directory: the Cowrie files of the profile directory (what deploy would
+// mount), the fsctl commands run on fs.pickle and the Dockerfile
+func WriteBuildContext(contextDir, profileDir string, config *models.Config, fsctlCommands []string) error {
+	if err := os.MkdirAll(contextDir, 0755); err != nil {
+		return fmt.Errorf("error creating build context: %w", err)
+	}
+
+	for _, name := range []string{"cowrie.cfg", "userdb.txt"} {
+		data, err := os.ReadFile(filepath.Join(profileDir, name))
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(contextDir, name), data, 0644); err != nil {
+			return fmt.Errorf("error writing %s: %w", name, err)
+		}
+	}
+	for _, name := range []string{"honeyfs", "txtcmds"} {
+		// COPY fails on a missing directory
+		if err := os.MkdirAll(filepath.Join(contextDir, name), 0755); err != nil {
+			return fmt.Errorf("error creating %s: %w", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(profileDir, name)); os.IsNotExist(err) {
+			continue
+		}
+		if err := copyDir(filepath.Join(profileDir, name), filepath.Join(contextDir, name)); err != nil {
+			return fmt.Errorf("error copying %s: %w", name, err)
+		}
+	}
+
+	if len(fsctlCommands) > 0 {
+		input := strings.Join(fsctlCommands, "\n") + "\nexit\n"
+		if err := os.WriteFile(filepath.Join(contextDir, FsctlFile), []byte(input), 0644); err != nil {
+			return fmt.Errorf("error writing %s: %w", FsctlFile, err)
+		}
+	}
+
+	return WriteDockerfile(contextDir, config, len(fsctlCommands) > 0)
+}
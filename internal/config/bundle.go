@@ -0,0 +1,496 @@
+package config
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/otori-lab/otori-cli/internal/version"
+)
+
+// BundleFormatVersion is the version of the bundle layout written by this version of otori
+const BundleFormatVersion = 1
+
+// Bundle layout: a manifest at the root, the profile directory under "profile/"
+const (
+	bundleManifestName = "otori-bundle.json"
+	bundleProfileDir   = "profile/"
+)
+
+// Limits protecting the import against oversized archives
+const (
+	maxBundleFileSize  = 64 << 20
+	maxBundleTotalSize = 512 << 20
+)
+
+// BundleManifest describes the content of a profile bundle
+type BundleManifest struct {
+	FormatVersion int          `json:"formatVersion"`
+	Profile       string       `json:"profile"`
+	OtoriVersion  string       `json:"otoriVersion"`
+	CreatedAt     string       `json:"createdAt"`
+	Files         []BundleFile `json:"files"`
+}
+
+// BundleFile is a file of the bundle with its checksum
+type BundleFile struct {
+	Path   string `json:"path"` // relative to the profile directory, slash separated
+	Size   int64  `json:"size"`
+	Mode   int64  `json:"mode"`
+	SHA256 string `json:"sha256"`
+}
+
+// Conflict policies when an imported profile already exists
+const (
+	ConflictRename    = "rename"
+	ConflictOverwrite = "overwrite"
+	ConflictSkip      = "skip"
+)
+
+// ExportBundle writes a whole profile directory (JSON, cowrie.cfg, userdb.txt,
+// honeyfs, compose...) to a tar.gz with a manifest and checksums
+func ExportBundle(profileName, outputPath string) error {
+	if isLegacyProfile(profileName) {
+		return fmt.Errorf("profile '%s' uses the legacy layout, run 'otori profiles migrate %s' first", profileName, profileName)
+	}
+	profileDir := getProfileDir(profileName)
+	if _, err := os.Stat(filepath.Join(profileDir, profileName+".json")); err != nil {
+		return fmt.Errorf("profile '%s' not found: %w", profileName, err)
+	}
+
+	manifest := BundleManifest{
+		FormatVersion: BundleFormatVersion,
+		Profile:       profileName,
+		OtoriVersion:  version.Version,
+		CreatedAt:     time.Now().Format(time.RFC3339),
+	}
+
+	err := filepath.Walk(profileDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil // directories are implied, symlinks are not exported
+		}
+		rel, err := filepath.Rel(profileDir, p)
+		if err != nil {
+			return err
+		}
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, BundleFile{
+			Path:   filepath.ToSlash(rel),
+			Size:   info.Size(),
+			Mode:   int64(info.Mode().Perm()),
+			SHA256: sum,
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error reading profile: %w", err)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %w", err)
+	}
+	if err := writeTarFile(tw, bundleManifestName, 0644, data); err != nil {
+		return err
+	}
+
+	for _, f := range manifest.Files {
+		content, err := os.ReadFile(filepath.Join(profileDir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", f.Path, err)
+		}
+		if err := writeTarFile(tw, bundleProfileDir+f.Path, f.Mode, content); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("error writing bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("error writing bundle: %w", err)
+	}
+	return out.Close()
+}
+
+// writeTarFile adds a regular file to a tar archive
+func writeTarFile(tw *tar.Writer, name string, mode int64, content []byte) error {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     mode,
+		Size:     int64(len(content)),
+		Typeflag: tar.TypeReg,
+		ModTime:  time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("error writing bundle: %w", err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		return fmt.Errorf("error writing bundle: %w", err)
+	}
+	return nil
+}
+
+// fileSHA256 returns the hex SHA-256 of a file
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// BundleImportOptions controls ImportBundle
+type BundleImportOptions struct {
+	Name       string // target profile name (default: name stored in the bundle)
+	OnConflict string // rename, overwrite or skip (default: rename)
+}
+
+// BundleImportResult describes an imported bundle
+type BundleImportResult struct {
+	Profile  string // name of the imported profile
+	Original string // name stored in the bundle
+	Skipped  bool
+	Replaced bool
+	Files    int
+}
+
+// ImportBundle verifies a profile bundle and installs it in the profiles directory.
+// Every file must be listed in the manifest with a matching checksum, and entries
+// that are not plain files or would escape the profile directory are rejected.
+func ImportBundle(bundlePath string, opts BundleImportOptions) (*BundleImportResult, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening bundle: %w", err)
+	}
+	defer f.Close()
+
+	return ReadBundle(f, opts)
+}
+
+// ReadBundle imports a bundle from a reader (see ImportBundle)
+func ReadBundle(r io.Reader, opts BundleImportOptions) (*BundleImportResult, error) {
+	if err := os.MkdirAll(getConfigDir(), 0755); err != nil {
+		return nil, fmt.Errorf("error creating profiles directory: %w", err)
+	}
+
+	// Extract into a staging directory next to the profiles, verified before it is moved
+	staging, err := os.MkdirTemp(getConfigDir(), ".import-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if err := os.Chmod(staging, 0755); err != nil {
+		return nil, fmt.Errorf("error creating staging directory: %w", err)
+	}
+
+	manifest, err := extractBundle(r, staging)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BundleImportResult{Original: manifest.Profile, Files: len(manifest.Files)}
+	name := opts.Name
+	if name == "" {
+		name = manifest.Profile
+	}
+	if !IsValidProfileName(name) {
+		return nil, fmt.Errorf("invalid profile name '%s'", name)
+	}
+
+	if ProfileExists(name) {
+		switch opts.OnConflict {
+		case ConflictSkip:
+			result.Profile = name
+			result.Skipped = true
+			return result, nil
+		case ConflictOverwrite:
+			result.Replaced = true
+		case ConflictRename, "":
+			name = availableProfileName(name)
+		default:
+			return nil, fmt.Errorf("unknown conflict policy '%s' (use rename, overwrite or skip)", opts.OnConflict)
+		}
+	}
+	result.Profile = name
+
+	if err := renameStagedProfile(staging, manifest.Profile, name, ""); err != nil {
+		return nil, err
+	}
+	if err := checkStagedProfile(staging, name); err != nil {
+		return nil, err
+	}
+
+	lock, err := LockProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	if result.Replaced && isLegacyProfile(name) {
+		if err := RemoveProfile(name); err != nil {
+			return nil, err
+		}
+	}
+	if err := installProfileDir(staging, name); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// extractBundle reads and verifies a bundle into dir, returning its manifest
+func extractBundle(r io.Reader, dir string) (*BundleManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a gzip bundle: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest *BundleManifest
+	sums := make(map[string]string)
+	var total int64
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading bundle: %w", err)
+		}
+
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("bundle entry '%s' is not a regular file", header.Name)
+		}
+		if header.Size > maxBundleFileSize {
+			return nil, fmt.Errorf("bundle entry '%s' is too large", header.Name)
+		}
+		if total += header.Size; total > maxBundleTotalSize {
+			return nil, fmt.Errorf("bundle is too large")
+		}
+
+		if header.Name == bundleManifestName {
+			var m BundleManifest
+			if err := json.NewDecoder(io.LimitReader(tr, maxBundleFileSize)).Decode(&m); err != nil {
+				return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+			}
+			manifest = &m
+			continue
+		}
+
+		rel, ok := bundleEntryPath(header.Name)
+		if !ok {
+			return nil, fmt.Errorf("unsafe path '%s' in bundle", header.Name)
+		}
+		if _, dup := sums[rel]; dup {
+			return nil, fmt.Errorf("duplicate entry '%s' in bundle", rel)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("error extracting bundle: %w", err)
+		}
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, os.FileMode(header.Mode).Perm()|0600)
+		if err != nil {
+			return nil, fmt.Errorf("error extracting bundle: %w", err)
+		}
+		h := sha256.New()
+		_, err = io.Copy(io.MultiWriter(out, h), io.LimitReader(tr, header.Size))
+		out.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error extracting bundle: %w", err)
+		}
+		sums[rel] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("bundle has no %s", bundleManifestName)
+	}
+	if manifest.FormatVersion > BundleFormatVersion {
+		return nil, fmt.Errorf("bundle format %d is not supported (max %d)", manifest.FormatVersion, BundleFormatVersion)
+	}
+	if !IsValidProfileName(manifest.Profile) {
+		return nil, fmt.Errorf("invalid profile name '%s' in bundle", manifest.Profile)
+	}
+
+	// The manifest and the archive content must match exactly
+	listed := make(map[string]bool)
+	for _, file := range manifest.Files {
+		listed[file.Path] = true
+		sum, ok := sums[file.Path]
+		if !ok {
+			return nil, fmt.Errorf("file '%s' listed in the manifest is missing", file.Path)
+		}
+		if sum != file.SHA256 {
+			return nil, fmt.Errorf("checksum mismatch for '%s'", file.Path)
+		}
+	}
+	for rel := range sums {
+		if !listed[rel] {
+			return nil, fmt.Errorf("file '%s' is not listed in the manifest", rel)
+		}
+	}
+	if !listed[manifest.Profile+".json"] {
+		return nil, fmt.Errorf("bundle does not contain %s.json", manifest.Profile)
+	}
+
+	return manifest, nil
+}
+
+// bundleEntryPath returns the profile-relative path of a bundle entry,
+// or false if it is outside "profile/" or could escape the target directory
+func bundleEntryPath(name string) (string, bool) {
+	if !strings.HasPrefix(name, bundleProfileDir) || strings.Contains(name, "\\") {
+		return "", false
+	}
+	rel := strings.TrimPrefix(name, bundleProfileDir)
+	if rel == "" || path.IsAbs(rel) || path.Clean(rel) != rel {
+		return "", false
+	}
+	for _, part := range strings.Split(rel, "/") {
+		if part == ".." || part == "." || part == "" {
+			return "", false
+		}
+	}
+	return rel, true
+}
+
+// renameStagedProfile makes a copied profile directory match its new name:
+// JSON file name, ProfileName and the generated compose file (container and volume names).
+// A non-empty createdAt replaces the creation date (clones are new profiles).
+func renameStagedProfile(dir, from, to, createdAt string) error {
+	data, err := os.ReadFile(filepath.Join(dir, from+".json"))
+	if err != nil {
+		return fmt.Errorf("error reading profile: %w", err)
+	}
+	cfg, _, err := decodeConfig(data)
+	if err != nil {
+		return err
+	}
+	if from == to && cfg.ProfileName == to && createdAt == "" {
+		return nil
+	}
+
+	if createdAt != "" {
+		cfg.CreatedAt = createdAt
+	}
+	cfg.ProfileName = to
+	cfg.SchemaVersion = models.CurrentSchemaVersion
+	data, err = json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding JSON: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, to+".json"), data, 0644); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	if from != to {
+		if err := os.Remove(filepath.Join(dir, from+".json")); err != nil {
+			return err
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "docker-compose.yml")); err == nil {
+		effective, _, err := ResolveConfig(cfg)
+		if err != nil {
+			return fmt.Errorf("error resolving templates: %w", err)
+		}
+		if err := WriteDockerCompose(dir, effective); err != nil {
+			return fmt.Errorf("error writing docker-compose.yml: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkStagedProfile validates the configuration of an extracted bundle and
+// regenerates its docker-compose.yml. The checksums only prove that the files
+// match the manifest of the bundle, not who made it: the compose file of a
+// bundle is never installed as is.
+func checkStagedProfile(dir, name string) error {
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return fmt.Errorf("error reading profile: %w", err)
+	}
+	cfg, _, err := decodeConfig(data)
+	if err != nil {
+		return err
+	}
+	effective, _, err := ResolveConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("error resolving templates: %w", err)
+	}
+	effective.ProfileName = name
+
+	if verrs := ValidateConfig(effective); len(verrs) > 0 {
+		msgs := make([]string, len(verrs))
+		for i, verr := range verrs {
+			msgs[i] = verr.Field + ": " + verr.Message
+		}
+		return fmt.Errorf("profile '%s' of the bundle has an %w: %s", name, ErrInvalid, strings.Join(msgs, "; "))
+	}
+
+	compose := filepath.Join(dir, "docker-compose.yml")
+	if effective.Type != "classic" {
+		if err := os.Remove(compose); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing docker-compose.yml: %w", err)
+		}
+		return nil
+	}
+	if err := WriteDockerCompose(dir, effective); err != nil {
+		return fmt.Errorf("error writing docker-compose.yml: %w", err)
+	}
+	return nil
+}
+
+// availableProfileName returns name, or name-2, name-3... if it is taken
+func availableProfileName(name string) string {
+	if !ProfileExists(name) {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !ProfileExists(candidate) {
+			return candidate
+		}
+	}
+}
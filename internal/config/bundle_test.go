@@ -0,0 +1,290 @@
+package config_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/otori-lab/otori-cli/internal/testutil"
+)
+
+// bundleEntry is an entry of a bundle written by a test
+type bundleEntry struct {
+	header *tar.Header
+	data   []byte
+}
+
+// testBundle is the content of a bundle, altered by the tests before it is written
+type testBundle struct {
+	manifest config.BundleManifest
+	entries  []bundleEntry
+}
+
+// exportedBundle writes a classic profile and returns the content of its exported bundle
+func exportedBundle(t *testing.T, profileName string) *testBundle {
+	t.Helper()
+
+	cfg := models.NewConfig()
+	cfg.Type = "classic"
+	cfg.ServerName = "srv-" + profileName
+	cfg.ProfileName = profileName
+	if err := config.WriteConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), profileName+".tar.gz")
+	if err := config.ExportBundle(profileName, path); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &testBundle{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Name == "otori-bundle.json" {
+			if err := json.Unmarshal(data, &b.manifest); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		b.entries = append(b.entries, bundleEntry{header: header, data: data})
+	}
+	return b
+}
+
+// add adds an entry to the archive only, not to the manifest
+func (b *testBundle) add(header *tar.Header, data []byte) {
+	header.Size = int64(len(data))
+	b.entries = append(b.entries, bundleEntry{header: header, data: data})
+}
+
+// set replaces or adds a file of the profile, listed in the manifest with its checksum
+func (b *testBundle) set(rel string, data []byte) {
+	sum := sha256.Sum256(data)
+	file := config.BundleFile{Path: rel, Size: int64(len(data)), Mode: 0644, SHA256: hex.EncodeToString(sum[:])}
+
+	listed := false
+	for i := range b.manifest.Files {
+		if b.manifest.Files[i].Path == rel {
+			b.manifest.Files[i] = file
+			listed = true
+		}
+	}
+	if !listed {
+		b.manifest.Files = append(b.manifest.Files, file)
+	}
+
+	for i := range b.entries {
+		if b.entries[i].header.Name == "profile/"+rel {
+			b.entries[i].data = data
+			b.entries[i].header.Size = int64(len(data))
+			return
+		}
+	}
+	b.add(&tar.Header{Name: "profile/" + rel, Mode: 0644, Typeflag: tar.TypeReg}, data)
+}
+
+// write writes the bundle to a tar.gz and returns its path
+func (b *testBundle) write(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.Marshal(b.manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := append([]bundleEntry{{
+		header: &tar.Header{Name: "otori-bundle.json", Mode: 0644, Size: int64(len(manifest)), Typeflag: tar.TypeReg},
+		data:   manifest,
+	}}, b.entries...)
+	for _, entry := range entries {
+		if err := tw.WriteHeader(entry.header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportBundleConflicts(t *testing.T) {
+	testutil.OtoriHome(t)
+	path := exportedBundle(t, "web").write(t)
+
+	for _, tt := range []struct {
+		onConflict string
+		profile    string
+		replaced   bool
+		skipped    bool
+	}{
+		{"", "web-2", false, false},
+		{config.ConflictRename, "web-3", false, false},
+		{config.ConflictOverwrite, "web", true, false},
+		{config.ConflictSkip, "web", false, true},
+	} {
+		result, err := config.ImportBundle(path, config.BundleImportOptions{OnConflict: tt.onConflict})
+		if err != nil {
+			t.Fatalf("%q: %v", tt.onConflict, err)
+		}
+		if result.Profile != tt.profile || result.Original != "web" || result.Replaced != tt.replaced || result.Skipped != tt.skipped {
+			t.Errorf("%q: result %+v", tt.onConflict, result)
+		}
+
+		cfg, err := config.ReadConfig(tt.profile)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.onConflict, err)
+		}
+		if cfg.ProfileName != tt.profile {
+			t.Errorf("%q: imported profile is named %s", tt.onConflict, cfg.ProfileName)
+		}
+		compose, err := os.ReadFile(filepath.Join(config.GetConfigDir(), tt.profile, "docker-compose.yml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(compose), "container_name: otori-"+tt.profile+"\n") {
+			t.Errorf("%q: compose file not generated for %s:\n%s", tt.onConflict, tt.profile, compose)
+		}
+	}
+
+	if _, err := config.ImportBundle(path, config.BundleImportOptions{OnConflict: "merge"}); err == nil {
+		t.Error("unknown conflict policy accepted")
+	}
+	if _, err := config.ImportBundle(path, config.BundleImportOptions{Name: "other"}); err != nil {
+		t.Error(err)
+	} else if !config.ProfileExists("other") {
+		t.Error("profile not imported under the name given")
+	}
+}
+
+func TestImportBundleRegeneratesCompose(t *testing.T) {
+	testutil.OtoriHome(t)
+	b := exportedBundle(t, "web")
+	config.RemoveProfile("web")
+
+	// Checksums prove nothing about the author of the bundle
+	b.set("docker-compose.yml", []byte("services:\n  cowrie:\n    image: evil\n    privileged: true\n    volumes:\n      - /:/host\n"))
+	if _, err := config.ImportBundle(b.write(t), config.BundleImportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	compose, err := os.ReadFile(filepath.Join(config.GetConfigDir(), "web", "docker-compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(compose), "privileged") || strings.Contains(string(compose), "/host") {
+		t.Errorf("compose file of the bundle installed:\n%s", compose)
+	}
+	if !strings.Contains(string(compose), "container_name: otori-web\n") {
+		t.Errorf("compose file not generated:\n%s", compose)
+	}
+}
+
+func TestImportBundleRejected(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		alter  func(b *testBundle)
+		reason string
+	}{
+		{"parent path", func(b *testBundle) {
+			b.set("../escape.txt", []byte("escaped"))
+		}, "unsafe path"},
+		{"absolute path", func(b *testBundle) {
+			b.add(&tar.Header{Name: "/tmp/escape.txt", Mode: 0644, Typeflag: tar.TypeReg}, []byte("escaped"))
+		}, "unsafe path"},
+		{"symlink", func(b *testBundle) {
+			b.add(&tar.Header{Name: "profile/honeyfs/etc/shadow", Linkname: "/etc/shadow", Typeflag: tar.TypeSymlink}, nil)
+		}, "not a regular file"},
+		{"hardlink", func(b *testBundle) {
+			b.add(&tar.Header{Name: "profile/honeyfs/etc/shadow", Linkname: "/etc/shadow", Typeflag: tar.TypeLink}, nil)
+		}, "not a regular file"},
+		{"checksum mismatch", func(b *testBundle) {
+			for i := range b.entries {
+				if b.entries[i].header.Name == "profile/userdb.txt" {
+					b.entries[i].data = append(b.entries[i].data, "root:x:*\n"...)
+					b.entries[i].header.Size = int64(len(b.entries[i].data))
+				}
+			}
+		}, "checksum mismatch"},
+		{"unlisted file", func(b *testBundle) {
+			b.add(&tar.Header{Name: "profile/honeyfs/etc/issue.net.bak", Mode: 0644, Typeflag: tar.TypeReg}, []byte("hello"))
+		}, "not listed in the manifest"},
+		{"missing file", func(b *testBundle) {
+			b.entries = b.entries[1:]
+		}, "is missing"},
+		{"invalid config", func(b *testBundle) {
+			b.set("web.json", []byte(`{"schemaVersion": 2, "type": "classic", "serverName": "x", "profileName": "web"}`))
+		}, "invalid configuration"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.OtoriHome(t)
+			b := exportedBundle(t, "web")
+			tt.alter(b)
+
+			_, err := config.ImportBundle(b.write(t), config.BundleImportOptions{})
+			if err == nil || !strings.Contains(err.Error(), tt.reason) {
+				t.Fatalf("error %v, want %q", err, tt.reason)
+			}
+			if tt.name == "invalid config" && !errors.Is(err, config.ErrInvalid) {
+				t.Errorf("error %v is not ErrInvalid", err)
+			}
+
+			// Nothing installed, nothing left behind
+			entries, err := os.ReadDir(config.GetConfigDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				if entry.Name() != "web" {
+					t.Errorf("%s left in the profiles directory", entry.Name())
+				}
+			}
+			if _, err := os.Stat(filepath.Join(config.GetOtoriDir(), "escape.txt")); err == nil {
+				t.Error("file written outside the profile directory")
+			}
+		})
+	}
+}
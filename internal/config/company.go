@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/otori-lab/otori-cli/internal/models"
+)
+
+// The company of a profile brands the simulated machine: internal domain, legal
+// banner, GECOS of the accounts and bait files. Generated values (passwords, phone
+// numbers...) come from a generator seeded by the profile, so every render of a
+// profile gives the same files while two profiles of a company differ.
+
+// companySlug returns the company name as a DNS label (e.g. "ACME Corp" -> acme-corp),
+// or "" without company
+func companySlug(config *models.Config) string {
+	name := strings.NewReplacer(
+		"à", "a", "â", "a", "ä", "a", "ç", "c", "é", "e", "è", "e", "ê", "e", "ë", "e",
+		"î", "i", "ï", "i", "ô", "o", "ö", "o", "ù", "u", "û", "u", "ü", "u", "&", "-and-",
+	).Replace(strings.ToLower(config.Company))
+
+	var b strings.Builder
+	dash := false
+	for _, ch := range name {
+		if (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(ch)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	label := b.String()
+	if len(label) > 63 {
+		label = strings.TrimRight(label[:63], "-")
+	}
+	return label
+}
+
+// companyDomain returns the internal DNS domain derived from the company name
+// (e.g. "ACME Corp" -> acme-corp.internal), or localdomain without company
+func companyDomain(config *models.Config) string {
+	if slug := companySlug(config); slug != "" {
+		return slug + ".internal"
+	}
+	return "localdomain"
+}
+
+// companyMailDomain returns the public mail domain of the company (acme-corp.com), or "" without company
+func companyMailDomain(config *models.Config) string {
+	if slug := companySlug(config); slug != "" {
+		return slug + ".com"
+	}
+	return ""
+}
+
+// userGecos returns the GECOS field of an account: full name and mail address
+// of the company ("Deploy,,,,deploy@acme-corp.com"), or the capitalized name
+func userGecos(config *models.Config, user string) string {
+	domain := companyMailDomain(config)
+	if domain == "" {
+		return capitalize(user)
+	}
+	var names []string
+	for _, part := range strings.FieldsFunc(user, func(r rune) bool { return r == '.' || r == '_' || r == '-' }) {
+		names = append(names, capitalize(part))
+	}
+	return strings.Join(names, " ") + ",,,," + strings.ToLower(user) + "@" + domain
+}
+
+// legalBanner returns the legal notice of the company, framed with asterisks
+func legalBanner(company string) string {
+	lines := []string{
+		"",
+		"This system is the property of " + company + ".",
+		"Access is restricted to authorized users only.",
+		"All activity on this system is monitored and recorded.",
+		"Unauthorized access will be reported to law enforcement.",
+		"",
+	}
+	width := 0
+	for _, line := range lines {
+		width = max(width, utf8.RuneCountInString(line))
+	}
+
+	var b strings.Builder
+	border := strings.Repeat("*", width+6)
+	b.WriteString(border + "\n")
+	for _, line := range lines {
+		fmt.Fprintf(&b, "*  %-*s  *\n", width, line)
+	}
+	b.WriteString(border + "\n")
+	return b.String()
+}
+
+// writeCompanyFiles renders the files branded by the company: resolv.conf, motd and
+// issue.net banners. Nothing is changed without company.
+func writeCompanyFiles(tree fileTree, config *models.Config) {
+	if config.Company == "" {
+		return
+	}
+	network := networkOf(config)
+
+	tree.set("etc/resolv.conf", []byte(fmt.Sprintf("domain %s\nsearch %s\nnameserver %s\n",
+		network.Domain, network.Domain, network.Gateway.IP)))
+
+	banner := legalBanner(config.Company)
+	tree.set("etc/issue.net", []byte(banner))
+	motd := banner
+	if base := tree["etc/motd"].Data; len(base) > 0 {
+		motd += "\n" + strings.TrimLeft(string(base), "\n")
+	}
+	tree.set("etc/motd", []byte(motd))
+}
+
+// companyPassword returns a weak password in the style of a company
+// (e.g. Acme2023!), as found in real bait files
+func companyPassword(config *models.Config, r *rand.Rand) string {
+	word := "Welcome"
+	if fields := strings.Fields(config.Company); len(fields) > 0 {
+		if slug := companySlug(&models.Config{Company: fields[0]}); slug != "" {
+			word = capitalize(slug)
+		}
+	}
+	year := referenceTime(config).Year() - r.IntN(3)
+	return fmt.Sprintf("%s%d%c", word, year, "!#$@*"[r.IntN(5)])
+}
+
+// companyBaitFiles returns the default bait files branded by the company: credentials
+// in /etc/share/secret.txt and onboarding notes in the home of the first user
+func companyBaitFiles(config *models.Config) []models.BaitFile {
+	r := personaRand(config, "bait")
+	network := networkOf(config)
+	slug := companySlug(config)
+	mail := companyMailDomain(config)
+
+	dbHost := "db01." + network.Domain
+	for _, neighbor := range network.Neighbors {
+		if strings.HasPrefix(neighbor.Name, "db") || strings.Contains(neighbor.Name, "sql") {
+			dbHost = neighbor.FQDN
+			break
+		}
+	}
+	dbUser := strings.ReplaceAll(slug, "-", "_") + "_app"
+
+	files := []models.BaitFile{{
+		Path: "/etc/share/secret.txt",
+		Content: fmt.Sprintf(`# %s - Confidential - Do Not Share
+DB_HOST=%s
+DB_PORT=5432
+DB_NAME=%s
+DB_USER=%s
+DB_PASS=%s
+SMTP_RELAY=mail.%s
+SMTP_USER=noreply@%s
+SMTP_PASS=%s
+`, config.Company, dbHost, strings.ReplaceAll(slug, "-", "_"), dbUser, companyPassword(config, r),
+			network.Domain, mail, companyPassword(config, r)),
+	}}
+
+	for _, user := range config.Users {
+		if user == "root" {
+			continue
+		}
+		files = append(files, models.BaitFile{
+			Path: "/home/" + user + "/notes.txt",
+			Content: fmt.Sprintf(`%s - IT onboarding
+
+Intranet:  https://intranet.%s
+Webmail:   https://mail.%s (login: %s@%s)
+VPN:       vpn.%s - same password as the domain account
+Wi-Fi:     %s-Staff / %s
+Helpdesk:  +33 1 %02d %02d %02d %02d - helpdesk@%s
+
+Temporary password: %s (to change at first login)
+`, config.Company, network.Domain, mail, strings.ToLower(user), mail, mail,
+				strings.ToUpper(slug), companyPassword(config, r),
+				r.IntN(90)+10, r.IntN(90)+10, r.IntN(90)+10, r.IntN(90)+10, mail,
+				companyPassword(config, r)),
+		})
+		break
+	}
+	return files
+}
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/otori-lab/otori-cli/internal/models"
+)
+
+// CSV layout: one profile per row. The users column holds "user[:password|password]"
+// items separated by "; ", e.g. "root:toor|123456; admin; deploy:*"
+const (
+	csvUserSeparator     = ";"
+	csvPasswordSeparator = ":"
+	csvPasswordListSep   = "|"
+)
+
+// csvFields are the profile fields mapped to CSV columns, in export order
+var csvFields = []string{"type", "serverName", "profileName", "company", "users", "extends"}
+
+// csvDefaultHeaders are the column headers written by default
+var csvDefaultHeaders = map[string]string{
+	"type":        "Type",
+	"serverName":  "ServerName",
+	"profileName": "ProfileName",
+	"company":     "Company",
+	"users":       "Users",
+	"extends":     "Extends",
+}
+
+// csvRequired are the fields that must have a column when importing
+var csvRequired = []string{"type", "serverName", "profileName"}
+
+// CSVOptions controls the CSV reader and writer
+type CSVOptions struct {
+	Comma   rune              // field delimiter (default ',')
+	Columns map[string]string // profile field -> column header, for spreadsheets with their own headers
+}
+
+// ParseColumnMapping parses a "field=Header,field=Header" mapping (e.g. "serverName=Hostname")
+func ParseColumnMapping(spec string) (map[string]string, error) {
+	columns := make(map[string]string)
+	if strings.TrimSpace(spec) == "" {
+		return columns, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		field, header, ok := strings.Cut(pair, "=")
+		field, header = strings.TrimSpace(field), strings.TrimSpace(header)
+		if !ok || field == "" || header == "" {
+			return nil, fmt.Errorf("invalid column mapping '%s' (expected field=Header)", pair)
+		}
+		canonical := csvField(field)
+		if canonical == "" {
+			return nil, fmt.Errorf("unknown field '%s' in column mapping (use: %s)", field, strings.Join(csvFields, ", "))
+		}
+		columns[canonical] = header
+	}
+	return columns, nil
+}
+
+// csvField returns the canonical name of a CSV field, or "" if unknown
+func csvField(name string) string {
+	for _, field := range csvFields {
+		if strings.EqualFold(field, name) {
+			return field
+		}
+	}
+	return ""
+}
+
+// header returns the column header of a field
+func (o CSVOptions) header(field string) string {
+	if h, ok := o.Columns[field]; ok {
+		return h
+	}
+	return csvDefaultHeaders[field]
+}
+
+// comma returns the field delimiter
+func (o CSVOptions) comma() rune {
+	if o.Comma == 0 {
+		return ','
+	}
+	return o.Comma
+}
+
+// parseCSV reads one profile per row. Columns are matched by header name
+// (case-insensitive, see CSVOptions.Columns); unknown columns are ignored.
+func parseCSV(data []byte, opts CSVOptions) ([]ImportEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = opts.comma()
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("empty CSV")
+	}
+
+	index := make(map[string]int)
+	for i, header := range records[0] {
+		index[strings.ToLower(strings.TrimSpace(header))] = i
+	}
+	columns := make(map[string]int)
+	for _, field := range csvFields {
+		if i, ok := index[strings.ToLower(opts.header(field))]; ok {
+			columns[field] = i
+		}
+	}
+	for _, field := range csvRequired {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("invalid CSV: missing column '%s'", opts.header(field))
+		}
+	}
+
+	var fields []string
+	for _, field := range csvFields {
+		if _, ok := columns[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+
+	var entries []ImportEntry
+	for n, row := range records[1:] {
+		if isBlankRow(row) {
+			continue
+		}
+		entry := ImportEntry{Source: fmt.Sprintf("row %d", n+2)}
+		get := func(field string) string {
+			i, ok := columns[field]
+			if ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		cfg := models.NewConfig()
+		cfg.Type = strings.ToLower(get("type")) // Normalize type to lowercase
+		cfg.ServerName = get("serverName")
+		cfg.ProfileName = get("profileName")
+		cfg.Company = get("company")
+		cfg.Extends = get("extends")
+		cfg.Users, cfg.Passwords = parseCSVUsers(get("users"))
+
+		entry.Config = cfg
+		entry.Fields = fields
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// mergeCSVFields returns the current profile with the fields of a CSV row
+func mergeCSVFields(current, row *models.Config, fields []string) *models.Config {
+	merged := *current
+	for _, field := range fields {
+		switch field {
+		case "type":
+			merged.Type = row.Type
+		case "serverName":
+			merged.ServerName = row.ServerName
+		case "profileName":
+			merged.ProfileName = row.ProfileName
+		case "company":
+			merged.Company = row.Company
+		case "users":
+			merged.Users, merged.Passwords = row.Users, row.Passwords
+		case "extends":
+			merged.Extends = row.Extends
+		}
+	}
+	return &merged
+}
+
+// isBlankRow returns true for rows left empty by spreadsheets
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCSVUsers parses "root:toor|123456; admin" into users and per-user passwords
+func parseCSVUsers(value string) ([]string, map[string][]string) {
+	users := []string{}
+	var passwords map[string][]string
+
+	for _, item := range strings.Split(value, csvUserSeparator) {
+		user, list, hasPasswords := strings.Cut(strings.TrimSpace(item), csvPasswordSeparator)
+		user = strings.TrimSpace(user)
+		if user == "" {
+			continue
+		}
+		users = append(users, user)
+		if !hasPasswords {
+			continue
+		}
+		for _, password := range strings.Split(list, csvPasswordListSep) {
+			if password = strings.TrimSpace(password); password != "" {
+				if passwords == nil {
+					passwords = make(map[string][]string)
+				}
+				passwords[user] = append(passwords[user], password)
+			}
+		}
+	}
+	return users, passwords
+}
+
+// formatCSVUsers is the inverse of parseCSVUsers
+func formatCSVUsers(config *models.Config) string {
+	items := make([]string, 0, len(config.Users))
+	for _, user := range config.Users {
+		if passwords := config.Passwords[user]; len(passwords) > 0 {
+			user += csvPasswordSeparator + strings.Join(passwords, csvPasswordListSep)
+		}
+		items = append(items, user)
+	}
+	return strings.Join(items, csvUserSeparator+" ")
+}
+
+// exportCSV exports profiles to CSV format, one profile per row
+func exportCSV(configs []*models.Config, outputPath string, opts CSVOptions) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Comma = opts.comma()
+
+	// The Extends column is only written when a profile uses a template
+	fields := csvFields[:len(csvFields)-1]
+	for _, cfg := range configs {
+		if cfg.Extends != "" {
+			fields = csvFields
+			break
+		}
+	}
+
+	headers := make([]string, 0, len(fields))
+	for _, field := range fields {
+		headers = append(headers, opts.header(field))
+	}
+	writer.Write(headers)
+
+	for _, cfg := range configs {
+		values := map[string]string{
+			"type":        cfg.Type,
+			"serverName":  cfg.ServerName,
+			"profileName": cfg.ProfileName,
+			"company":     cfg.Company,
+			"users":       formatCSVUsers(cfg),
+			"extends":     cfg.Extends,
+		}
+		row := make([]string, 0, len(fields))
+		for _, field := range fields {
+			row = append(row, values[field])
+		}
+		writer.Write(row)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing CSV: %w", err)
+	}
+	return nil
+}
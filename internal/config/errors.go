@@ -0,0 +1,11 @@
+package config
+
+import "errors"
+
+// Errors wrapped by otori to tell the kind of a failure apart (see errors.Is)
+var (
+	ErrNotFound = errors.New("not found")
+	ErrExists   = errors.New("already exists")
+	ErrLocked   = errors.New("is locked by another otori process")
+	ErrInvalid  = errors.New("invalid configuration")
+)
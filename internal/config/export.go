@@ -1,12 +1,10 @@
 package config
 
 import (
-	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
-	"time"
 
 	"github.com/otori-lab/otori-cli/internal/models"
 	"gopkg.in/yaml.v3"
@@ -19,17 +17,37 @@ const (
 	FormatJSON ExportFormat = "json"
 	FormatYAML ExportFormat = "yaml"
 	FormatCSV  ExportFormat = "csv"
+	// FormatBundle is a tar.gz of the whole profile directory (see ExportBundle)
+	FormatBundle ExportFormat = "bundle"
 )
 
 // ExportConfig exports a configuration in the specified format
 func ExportConfig(profileName string, format ExportFormat, outputPath string) error {
-	cfg, err := ReadConfig(profileName)
-	if err != nil {
-		return fmt.Errorf("profile '%s' not found: %w", profileName, err)
+	return ExportConfigs([]string{profileName}, format, outputPath, CSVOptions{})
+}
+
+// ExportConfigs exports one or many profiles to a single file.
+// Several profiles are written as a list (JSON, YAML) or one row per profile (CSV);
+// bundles hold a single profile.
+func ExportConfigs(profileNames []string, format ExportFormat, outputPath string, csvOpts CSVOptions) error {
+	if len(profileNames) == 0 {
+		return fmt.Errorf("no profile to export")
+	}
+	if format == FormatBundle && len(profileNames) > 1 {
+		return fmt.Errorf("a bundle holds a single profile")
+	}
+
+	var configs []*models.Config
+	for _, name := range profileNames {
+		cfg, err := ReadConfig(name)
+		if err != nil {
+			return fmt.Errorf("profile '%s' not found: %w", name, err)
+		}
+		configs = append(configs, cfg)
 	}
 
 	if outputPath == "" {
-		outputPath = filepath.Join("exports", profileName+"."+string(format))
+		outputPath = DefaultExportPath(profileNames, format)
 	}
 
 	// Create export directory if it doesn't exist
@@ -38,148 +56,67 @@ func ExportConfig(profileName string, format ExportFormat, outputPath string) er
 		return fmt.Errorf("error creating directory: %w", err)
 	}
 
+	// A single profile is exported as an object, several as a list
+	var doc interface{} = configs
+	if len(configs) == 1 {
+		doc = configs[0]
+	}
+
 	switch format {
 	case FormatYAML:
-		return exportYAML(cfg, outputPath)
+		return exportYAML(doc, outputPath)
 	case FormatCSV:
-		return exportCSV(cfg, outputPath)
+		return exportCSV(configs, outputPath, csvOpts)
 	case FormatJSON:
-		// JSON already supported natively
-		return fmt.Errorf("use WriteConfig for JSON")
+		return exportJSON(doc, outputPath)
+	case FormatBundle:
+		return ExportBundle(profileNames[0], outputPath)
 	default:
 		return fmt.Errorf("unsupported format: %s", format)
 	}
 }
 
-// exportYAML exports to YAML format
-func exportYAML(config *models.Config, outputPath string) error {
-	data, err := yaml.Marshal(config)
-	if err != nil {
-		return fmt.Errorf("error encoding YAML: %w", err)
-	}
-
-	if err := os.WriteFile(outputPath, data, 0644); err != nil {
-		return fmt.Errorf("error writing file: %w", err)
+// DefaultExportPath returns exports/<profile>.<ext>, or exports/profiles.<ext> for several profiles
+func DefaultExportPath(profileNames []string, format ExportFormat) string {
+	name := "profiles"
+	if len(profileNames) == 1 {
+		name = profileNames[0]
 	}
-
-	return nil
+	return filepath.Join("exports", name+format.Extension())
 }
 
-// exportCSV exports to CSV format
-func exportCSV(config *models.Config, outputPath string) error {
-	file, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("error creating file: %w", err)
+// Extension returns the file extension of an export format
+func (f ExportFormat) Extension() string {
+	if f == FormatBundle {
+		return ".tar.gz"
 	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Headers
-	headers := []string{"Type", "ServerName", "ProfileName", "Company", "Users"}
-	writer.Write(headers)
-
-	// Data - join users with "; " separator
-	usersStr := strings.Join(config.Users, "; ")
-
-	row := []string{
-		config.Type,
-		config.ServerName,
-		config.ProfileName,
-		config.Company,
-		usersStr,
-	}
-	writer.Write(row)
-
-	return nil
+	return "." + string(f)
 }
 
-// ImportYAML imports a configuration from YAML
-func ImportYAML(filePath string, profileName string) error {
-	data, err := os.ReadFile(filePath)
+// exportJSON exports to JSON format (same content as the profile JSON)
+func exportJSON(doc interface{}, outputPath string) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
-		return fmt.Errorf("error reading file: %w", err)
+		return fmt.Errorf("error encoding JSON: %w", err)
 	}
 
-	var config models.Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("error decoding YAML: %w", err)
-	}
-
-	// Use provided profile name or the one from file
-	if profileName == "" {
-		profileName = config.ProfileName
-		if profileName == "" {
-			profileName = filepath.Base(filePath[:len(filePath)-len(filepath.Ext(filePath))])
-		}
-	}
-
-	config.ProfileName = profileName
-	config.CreatedAt = time.Now().Format(time.RFC3339)
-
-	// Validate before importing
-	validationErrors := ValidateConfig(&config)
-	if len(validationErrors) > 0 {
-		return fmt.Errorf("invalid configuration: %v", validationErrors[0].Message)
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
 	}
 
-	return WriteConfig(&config)
+	return nil
 }
 
-// ImportCSV imports a configuration from CSV
-func ImportCSV(filePath string, profileName string) error {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("error opening file: %w", err)
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+// exportYAML exports to YAML format
+func exportYAML(doc interface{}, outputPath string) error {
+	data, err := yaml.Marshal(doc)
 	if err != nil {
-		return fmt.Errorf("error reading CSV: %w", err)
-	}
-
-	if len(records) < 2 {
-		return fmt.Errorf("empty CSV")
-	}
-
-	// Skip headers and read first data row
-	row := records[1]
-	if len(row) < 5 {
-		return fmt.Errorf("invalid CSV: missing columns")
-	}
-
-	config := models.NewConfig()
-	config.Type = strings.ToLower(row[0]) // Normalize type to lowercase
-	config.ServerName = row[1]
-	config.ProfileName = row[2]
-	if profileName != "" {
-		config.ProfileName = profileName
-	}
-	config.Company = row[3]
-
-	// Parse users (separated by "; ")
-	if row[4] != "" {
-		usersStr := row[4]
-		// Split by "; " separator and clean each user
-		users := strings.Split(usersStr, "; ")
-		for _, user := range users {
-			cleaned := strings.TrimSpace(user)
-			if cleaned != "" {
-				config.Users = append(config.Users, cleaned)
-			}
-		}
+		return fmt.Errorf("error encoding YAML: %w", err)
 	}
 
-	config.CreatedAt = time.Now().Format(time.RFC3339)
-
-	// Validate before importing
-	validationErrors := ValidateConfig(config)
-	if len(validationErrors) > 0 {
-		return fmt.Errorf("invalid configuration: %v", validationErrors[0].Message)
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
 	}
 
-	return WriteConfig(config)
+	return nil
 }
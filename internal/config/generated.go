@@ -0,0 +1,244 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Generated directories (honeyfs...) are rendered from the profile, then synced to disk:
+// otori records what it wrote in <dir>.manifest.json, files of <dir>.d/ are copied
+// over the generated ones, and files changed by hand in <dir>/ are never overwritten.
+const (
+	manifestSuffix = ".manifest.json"
+	overlaySuffix  = ".d"
+)
+
+// fileTree is the content of a generated directory, by slash-separated relative path
+type fileTree map[string]treeFile
+
+// treeFile is a file of a fileTree
+type treeFile struct {
+	Data []byte
+	Mode os.FileMode
+}
+
+// set adds or replaces a file (mode 0644). The path is cleaned: a path leaving
+// the directory is kept as is, so that syncGeneratedDir refuses it.
+func (t fileTree) set(p string, data []byte) {
+	t[path.Clean(p)] = treeFile{Data: data, Mode: 0644}
+}
+
+// isInsideTree returns true if a slash-separated path stays inside the directory of a tree
+func isInsideTree(p string) bool {
+	return p != "" && p != "." && path.Clean(p) == p && !path.IsAbs(p) && p != ".." && !strings.HasPrefix(p, "../")
+}
+
+// loadDir adds the files of a directory to the tree, replacing existing ones
+func (t fileTree) loadDir(dir string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		t[filepath.ToSlash(rel)] = treeFile{Data: data, Mode: info.Mode().Perm()}
+		return nil
+	})
+}
+
+// generatedManifest records the files written by otori in a generated directory
+type generatedManifest struct {
+	Files     map[string]string `json:"files"` // path -> sha256 of the content written by otori
+	Conflicts []RenderConflict  `json:"conflicts,omitempty"`
+}
+
+// RenderConflict is a file changed by hand that otori would have regenerated
+type RenderConflict struct {
+	Path   string `json:"path"` // relative to the profile directory, e.g. honeyfs/etc/passwd
+	Reason string `json:"reason"`
+}
+
+// Conflict reasons
+const (
+	conflictModified = "modified by hand and regenerated with different content, local version kept"
+	conflictAdded    = "added by hand where otori now generates a file, local version kept"
+)
+
+// syncGeneratedDir writes a generated tree to profileDir/name, with the files of
+// profileDir/name.d copied over it. Unchanged generated files are rewritten, files
+// changed or added by hand are kept (a conflict is recorded when otori also changed
+// them) and generated files no longer produced are removed.
+func syncGeneratedDir(profileDir, name string, tree fileTree) ([]RenderConflict, error) {
+	dir := filepath.Join(profileDir, name)
+	manifestPath := filepath.Join(profileDir, name+manifestSuffix)
+
+	// Without a manifest, the directory was written by an older otori: all of it is generated
+	var previous *generatedManifest
+	if data, err := os.ReadFile(manifestPath); err == nil {
+		previous = &generatedManifest{}
+		if err := json.Unmarshal(data, previous); err != nil {
+			return nil, fmt.Errorf("error decoding %s: %w", name+manifestSuffix, err)
+		}
+	}
+
+	for p := range tree {
+		if !isInsideTree(p) {
+			return nil, fmt.Errorf("error writing %s: unsafe path %s", name, p)
+		}
+	}
+
+	// User overlay: explicit overrides, always applied
+	overlay := make(fileTree)
+	overlayDir := filepath.Join(profileDir, name+overlaySuffix)
+	if info, err := os.Stat(overlayDir); err == nil && info.IsDir() {
+		if err := overlay.loadDir(overlayDir); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", name+overlaySuffix, err)
+		}
+	}
+	for p, f := range overlay {
+		tree[p] = f
+	}
+
+	current := make(map[string]string)
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		existing := make(fileTree)
+		if err := existing.loadDir(dir); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", name, err)
+		}
+		for p, f := range existing {
+			current[p] = contentHash(f.Data)
+		}
+	}
+
+	manifest := &generatedManifest{Files: make(map[string]string)}
+	for _, p := range sortedPaths(tree) {
+		f := tree[p]
+		want := contentHash(f.Data)
+		have, exists := current[p]
+		_, overridden := overlay[p]
+		var recorded string
+		var tracked bool
+		if previous != nil {
+			recorded, tracked = previous.Files[p]
+		}
+
+		switch {
+		case !exists, have == want, previous == nil, tracked && have == recorded:
+			// Missing, up to date or untouched since the last render
+		case overridden:
+			// Overridden in the overlay: the overlay wins over a hand edit
+		case tracked && recorded == want:
+			// Only changed by hand: otori has nothing new to write
+			manifest.Files[p] = recorded
+			continue
+		case tracked:
+			manifest.Files[p] = recorded
+			manifest.Conflicts = append(manifest.Conflicts, RenderConflict{Path: path.Join(name, p), Reason: conflictModified})
+			continue
+		default:
+			manifest.Conflicts = append(manifest.Conflicts, RenderConflict{Path: path.Join(name, p), Reason: conflictAdded})
+			continue
+		}
+
+		if have != want {
+			target := filepath.Join(dir, filepath.FromSlash(p))
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return nil, fmt.Errorf("error creating directory for %s: %w", p, err)
+			}
+			if err := os.WriteFile(target, f.Data, f.Mode); err != nil {
+				return nil, fmt.Errorf("error writing %s: %w", p, err)
+			}
+		}
+		manifest.Files[p] = want
+	}
+
+	// Generated files that are no longer produced (e.g. a removed bait file)
+	if previous != nil {
+		for p, recorded := range previous.Files {
+			if _, ok := tree[p]; ok || current[p] != recorded || !isInsideTree(p) {
+				continue
+			}
+			target := filepath.Join(dir, filepath.FromSlash(p))
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("error removing %s: %w", p, err)
+			}
+			// Remove the directories left empty (fails on the first non-empty one)
+			for parent := filepath.Dir(target); parent != dir; parent = filepath.Dir(parent) {
+				if os.Remove(parent) != nil {
+					break
+				}
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(manifestPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("error writing %s: %w", name+manifestSuffix, err)
+	}
+	return manifest.Conflicts, nil
+}
+
+// ReadRenderConflicts returns the conflicts recorded by the last render of a profile
+func ReadRenderConflicts(profileName string) ([]RenderConflict, error) {
+	manifests, err := filepath.Glob(filepath.Join(getProfileDir(profileName), "*"+manifestSuffix))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(manifests)
+
+	var conflicts []RenderConflict
+	for _, manifestPath := range manifests {
+		data, err := os.ReadFile(manifestPath)
+		if err != nil {
+			return nil, err
+		}
+		var manifest generatedManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("error decoding %s: %w", filepath.Base(manifestPath), err)
+		}
+		conflicts = append(conflicts, manifest.Conflicts...)
+	}
+	return conflicts, nil
+}
+
+// OverlayPath returns where to put a file of a generated directory so that it
+// overrides the generated one (e.g. honeyfs/etc/motd -> honeyfs.d/etc/motd)
+func OverlayPath(conflictPath string) string {
+	name, rest, _ := strings.Cut(conflictPath, "/")
+	return name + overlaySuffix + "/" + rest
+}
+
+// contentHash returns the sha256 of a file content
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sortedPaths returns the paths of a tree in a stable order
+func sortedPaths(tree fileTree) []string {
+	paths := make([]string, 0, len(tree))
+	for p := range tree {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,310 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/otori-lab/otori-cli/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// ImportEntry is one profile read from an import file
+type ImportEntry struct {
+	Source string         // location in the file ("#2", "row 3"...)
+	Config *models.Config // nil if the entry could not be decoded
+	Fields []string       // fields read from the file (CSV columns), nil for whole profiles
+	Err    error          // decoding error
+}
+
+// DetectFormat guesses the format of an import file from its name, then from its content.
+// name may be empty (stdin).
+func DetectFormat(name string, data []byte) ExportFormat {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return FormatBundle
+	case strings.HasSuffix(lower, ".json"):
+		return FormatJSON
+	case strings.HasSuffix(lower, ".yaml"), strings.HasSuffix(lower, ".yml"):
+		return FormatYAML
+	case strings.HasSuffix(lower, ".csv"):
+		return FormatCSV
+	}
+
+	// gzip magic number
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		return FormatBundle
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return FormatJSON
+	}
+
+	// CSV: a header line with commas and no YAML key separator
+	firstLine, _, _ := strings.Cut(string(trimmed), "\n")
+	if strings.Contains(firstLine, ",") && !strings.Contains(firstLine, ":") {
+		return FormatCSV
+	}
+
+	return FormatYAML
+}
+
+// ReadImportFile reads an import file ("-" for stdin) and returns its content and format
+func ReadImportFile(path string) ([]byte, ExportFormat, error) {
+	var data []byte
+	var err error
+	name := path
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+		name = ""
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading file: %w", err)
+	}
+
+	return data, DetectFormat(name, data), nil
+}
+
+// ParseProfiles decodes every profile of a JSON, YAML or CSV document.
+// JSON and YAML accept a single profile, a list of profiles or a {"profiles": [...]} object.
+// An error is returned only if the document itself is unreadable: entries that cannot
+// be decoded are returned with their error.
+func ParseProfiles(data []byte, format ExportFormat, csvOpts CSVOptions) ([]ImportEntry, error) {
+	switch format {
+	case FormatJSON:
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("error decoding JSON: %w", err)
+		}
+		return documentEntries(doc)
+	case FormatYAML:
+		var docs []interface{}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var doc interface{}
+			err := decoder.Decode(&doc)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error decoding YAML: %w", err)
+			}
+			if doc != nil {
+				docs = append(docs, doc)
+			}
+		}
+		if len(docs) == 1 {
+			return documentEntries(docs[0])
+		}
+		return documentEntries(docs)
+	case FormatCSV:
+		return parseCSV(data, csvOpts)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// documentEntries splits a decoded JSON/YAML document into profile entries
+func documentEntries(doc interface{}) ([]ImportEntry, error) {
+	var items []interface{}
+	switch v := doc.(type) {
+	case []interface{}:
+		items = v
+	case map[string]interface{}:
+		if list, ok := v["profiles"].([]interface{}); ok {
+			items = list
+		} else {
+			items = []interface{}{v}
+		}
+	default:
+		return nil, fmt.Errorf("expected a profile or a list of profiles")
+	}
+
+	entries := make([]ImportEntry, 0, len(items))
+	for i, item := range items {
+		entry := ImportEntry{Source: fmt.Sprintf("#%d", i+1)}
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			entry.Err = fmt.Errorf("expected a profile object")
+			entries = append(entries, entry)
+			continue
+		}
+
+		data, err := json.Marshal(canonicalKeys(fields))
+		if err != nil {
+			entry.Err = err
+		} else {
+			// Same decoding path as the profiles on disk: older schema versions are migrated
+			entry.Config, _, entry.Err = decodeConfig(data)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// profileKeys maps lowercased field names to the JSON field names of models.Config.
+// YAML exports written before fields had explicit tags used lowercased names.
+var profileKeys = map[string]string{
+	"schemaversion":   "schemaVersion",
+	"extends":         "extends",
+	"type":            "type",
+	"servername":      "serverName",
+	"profilename":     "profileName",
+	"name":            "profileName", // manifest entries
+	"company":         "company",
+	"users":           "users",
+	"passwords":       "passwords",
+	"persona":         "persona",
+	"ports":           "ports",
+	"sinks":           "sinks",
+	"baitfiles":       "baitFiles",
+	"commands":        "commands",
+	"cowrieoverrides": "cowrieOverrides",
+	"createdat":       "createdAt",
+}
+
+// canonicalKeys renames the top-level keys of a profile to their JSON names
+func canonicalKeys(fields map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if canonical, ok := profileKeys[strings.ToLower(key)]; ok {
+			key = canonical
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// ImportOptions controls ImportProfiles
+type ImportOptions struct {
+	Name       string // profile name, only for files holding a single profile
+	OnConflict string // rename, overwrite or skip (default: rename)
+	Origin     string // file name used to name a profile without profileName
+	DryRun     bool   // compute the results without writing anything
+}
+
+// Import statuses of an entry
+const (
+	ImportCreated   = "created"
+	ImportRenamed   = "renamed"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+	ImportSkipped   = "skipped"
+	ImportFailed    = "failed"
+)
+
+// ImportResult is the outcome of one entry
+type ImportResult struct {
+	Source  string
+	Profile string   // name of the written (or skipped) profile
+	Status  string   // one of the Import* statuses
+	Errors  []string // decoding or validation errors
+}
+
+// ImportProfiles validates and writes decoded entries, one result per entry.
+// An invalid entry does not prevent the others from being imported.
+func ImportProfiles(entries []ImportEntry, opts ImportOptions) ([]ImportResult, error) {
+	if opts.Name != "" && len(entries) > 1 {
+		return nil, fmt.Errorf("--name can only be used with a file holding a single profile (%d found)", len(entries))
+	}
+
+	results := make([]ImportResult, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		result := ImportResult{Source: entry.Source, Status: ImportFailed}
+		if entry.Err != nil {
+			result.Errors = []string{entry.Err.Error()}
+			results = append(results, result)
+			continue
+		}
+
+		cfg := entry.Config
+		if opts.Name != "" {
+			cfg.ProfileName = opts.Name
+		}
+		if cfg.ProfileName == "" && opts.Origin != "" && len(entries) == 1 {
+			cfg.ProfileName = strings.TrimSuffix(filepath.Base(opts.Origin), filepath.Ext(opts.Origin))
+		}
+		result.Profile = cfg.ProfileName
+
+		// A CSV row only overwrites its columns, the rest of the profile is kept
+		if entry.Fields != nil && opts.OnConflict == ConflictOverwrite && ProfileExists(cfg.ProfileName) {
+			if current, err := ReadConfig(cfg.ProfileName); err == nil {
+				cfg = mergeCSVFields(current, cfg, entry.Fields)
+			}
+		}
+
+		// Validate with the templates applied, like init does
+		effective, _, err := ResolveConfig(cfg)
+		if err != nil {
+			result.Errors = []string{err.Error()}
+			results = append(results, result)
+			continue
+		}
+		effective.ProfileName = cfg.ProfileName
+		for _, verr := range ValidateConfig(effective) {
+			result.Errors = append(result.Errors, verr.Field+": "+verr.Message)
+		}
+		if seen[cfg.ProfileName] {
+			result.Errors = append(result.Errors, fmt.Sprintf("ProfileName: '%s' appears twice in the file", cfg.ProfileName))
+		}
+		if len(result.Errors) > 0 {
+			results = append(results, result)
+			continue
+		}
+		seen[cfg.ProfileName] = true
+
+		result.Status = ImportCreated
+		if ProfileExists(cfg.ProfileName) {
+			switch opts.OnConflict {
+			case ConflictSkip:
+				result.Status = ImportSkipped
+				results = append(results, result)
+				continue
+			case ConflictOverwrite:
+				result.Status = ImportUpdated
+				if current, err := ReadConfig(cfg.ProfileName); err == nil {
+					// Keep the seed of the replaced profile unless the file sets one
+					if cfg.Seed == "" {
+						cfg.Seed = current.Seed
+					}
+					if SameConfig(current, cfg) {
+						result.Status = ImportUnchanged
+						results = append(results, result)
+						continue
+					}
+				}
+			case ConflictRename, "":
+				base := cfg.ProfileName
+				for i := 2; ProfileExists(cfg.ProfileName) || seen[cfg.ProfileName]; i++ {
+					cfg.ProfileName = fmt.Sprintf("%s-%d", base, i)
+				}
+				seen[cfg.ProfileName] = true
+				result.Profile = cfg.ProfileName
+				result.Status = ImportRenamed
+			default:
+				return nil, fmt.Errorf("unknown conflict policy '%s' (use rename, overwrite or skip)", opts.OnConflict)
+			}
+		}
+
+		if opts.DryRun {
+			results = append(results, result)
+			continue
+		}
+		if err := WriteConfig(cfg); err != nil {
+			result.Status = ImportFailed
+			result.Errors = []string{err.Error()}
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
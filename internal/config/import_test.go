@@ -0,0 +1,81 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/otori-lab/otori-cli/internal/testutil"
+)
+
+// importCSV parses a CSV export and imports it over the existing profiles
+func importCSV(t *testing.T, data []byte) config.ImportResult {
+	t.Helper()
+	entries, err := config.ParseProfiles(data, config.FormatCSV, config.CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := config.ImportProfiles(entries, config.ImportOptions{OnConflict: config.ConflictOverwrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	return results[0]
+}
+
+func TestImportCSVRoundTrip(t *testing.T) {
+	testutil.OtoriHome(t)
+
+	cfg := models.NewConfig()
+	cfg.Type = "classic"
+	cfg.ServerName = "srv-web"
+	cfg.ProfileName = "web"
+	cfg.Company = "Acme"
+	cfg.Users = []string{"admin"}
+	cfg.Ports = &models.Ports{SSH: 2022}
+	cfg.Persona = &models.Persona{Hardware: "aarch64"}
+	if err := config.WriteConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "web.csv")
+	if err := config.ExportConfig("web", config.FormatCSV, path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An unedited export changes nothing
+	if result := importCSV(t, data); result.Status != config.ImportUnchanged {
+		t.Fatalf("status = %s (%v), want %s", result.Status, result.Errors, config.ImportUnchanged)
+	}
+
+	// An edited column is written, the fields outside the CSV are kept
+	edited := []byte(strings.Replace(string(data), "Acme", "Globex", 1))
+	if result := importCSV(t, edited); result.Status != config.ImportUpdated {
+		t.Fatalf("status = %s (%v), want %s", result.Status, result.Errors, config.ImportUpdated)
+	}
+	got, err := config.ReadConfig("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Company != "Globex" {
+		t.Errorf("company = %q, want Globex", got.Company)
+	}
+	if got.Ports == nil || got.Ports.SSH != 2022 {
+		t.Errorf("ports = %+v, want the SSH port 2022 kept", got.Ports)
+	}
+	if got.Persona == nil || got.Persona.Hardware != "aarch64" {
+		t.Errorf("persona = %+v, want the hardware kept", got.Persona)
+	}
+	if got.Seed != cfg.Seed {
+		t.Errorf("seed = %q, want %q", got.Seed, cfg.Seed)
+	}
+}
@@ -0,0 +1,334 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/otori-lab/otori-cli/internal/models"
+)
+
+// Value sources reported by ResolveConfig
+const (
+	SourceProfile = "profile"
+	SourceDefault = "default"
+)
+
+// Provenance maps a field ("type", "users.admin", "cowrie.ssh.version"...)
+// to where its effective value comes from: "profile", "template:<name>", "settings" or "default"
+type Provenance map[string]string
+
+// GetTemplatesDir returns the templates directory (~/.otori/templates)
+func GetTemplatesDir() string {
+	return filepath.Join(GetOtoriDir(), "templates")
+}
+
+// ReadTemplate reads a template (~/.otori/templates/{name}/{name}.json).
+// A template is a partial profile: every field is optional.
+func ReadTemplate(name string) (*models.Config, error) {
+	if !IsValidProfileName(name) {
+		return nil, fmt.Errorf("invalid template name '%s'", name)
+	}
+
+	filename := filepath.Join(GetTemplatesDir(), name, name+".json")
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("template '%s' not found: %w", name, err)
+	}
+
+	tmpl, _, err := decodeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding template '%s': %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// ListTemplates lists the available templates
+func ListTemplates() ([]string, error) {
+	entries, err := os.ReadDir(GetTemplatesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	var templates []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		jsonFile := filepath.Join(GetTemplatesDir(), entry.Name(), entry.Name()+".json")
+		if _, err := os.Stat(jsonFile); err == nil {
+			templates = append(templates, entry.Name())
+		}
+	}
+	return templates, nil
+}
+
+// templateChain returns the templates inherited by a config, root template first
+func templateChain(config *models.Config) ([]string, []*models.Config, error) {
+	var names []string
+	var templates []*models.Config
+	seen := make(map[string]bool)
+
+	for name := config.Extends; name != ""; {
+		if seen[name] {
+			return nil, nil, fmt.Errorf("template inheritance cycle on '%s'", name)
+		}
+		seen[name] = true
+
+		tmpl, err := ReadTemplate(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		names = append([]string{name}, names...)
+		templates = append([]*models.Config{tmpl}, templates...)
+		name = tmpl.Extends
+	}
+
+	return names, templates, nil
+}
+
+// ResolveConfig merges a profile with the templates it extends.
+// Scalar fields of the profile override the template ones when set,
+// users are merged (template users first) and cowrie overrides are merged per key.
+// The sinks of the global settings come first, templates and profile override them by type.
+func ResolveConfig(config *models.Config) (*models.Config, Provenance, error) {
+	names, templates, err := templateChain(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	defaultSinks, err := DefaultSinks()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	effective := &models.Config{
+		Extends:     config.Extends,
+		ProfileName: config.ProfileName,
+		Seed:        config.Seed,
+		CreatedAt:   config.CreatedAt,
+	}
+	prov := Provenance{
+		"type":       SourceDefault,
+		"serverName": SourceDefault,
+		"company":    SourceDefault,
+	}
+	for _, sink := range defaultSinks {
+		effective.Sinks = mergeSink(effective.Sinks, sink)
+		prov["sinks."+sink.Type] = SourceSettings
+	}
+
+	layers := append(templates, config)
+	for i, layer := range layers {
+		source := SourceProfile
+		if i < len(names) {
+			source = "template:" + names[i]
+		}
+
+		if layer.Type != "" {
+			effective.Type = layer.Type
+			prov["type"] = source
+		}
+		if layer.ServerName != "" {
+			effective.ServerName = layer.ServerName
+			prov["serverName"] = source
+		}
+		if layer.Company != "" {
+			effective.Company = layer.Company
+			prov["company"] = source
+		}
+
+		for _, user := range layer.Users {
+			if _, exists := prov["users."+user]; exists {
+				continue
+			}
+			effective.Users = append(effective.Users, user)
+			prov["users."+user] = source
+		}
+
+		for user, passwords := range layer.Passwords {
+			if effective.Passwords == nil {
+				effective.Passwords = make(map[string][]string)
+			}
+			effective.Passwords[user] = passwords
+			prov["passwords."+user] = source
+		}
+
+		if p := layer.Persona; p != nil {
+			if effective.Persona == nil {
+				effective.Persona = &models.Persona{}
+			}
+			for key, field := range map[string][2]*string{
+				"operatingSystem": {&effective.Persona.OperatingSystem, &p.OperatingSystem},
+				"kernelVersion":   {&effective.Persona.KernelVersion, &p.KernelVersion},
+				"kernelBuild":     {&effective.Persona.KernelBuild, &p.KernelBuild},
+				"hardware":        {&effective.Persona.Hardware, &p.Hardware},
+				"sshVersion":      {&effective.Persona.SSHVersion, &p.SSHVersion},
+			} {
+				if *field[1] != "" {
+					*field[0] = *field[1]
+					prov["persona."+key] = source
+				}
+			}
+			// Services and neighbours are merged by name
+			for _, service := range p.Services {
+				effective.Persona.Services = mergeService(effective.Persona.Services, service)
+				prov["persona.services."+service.Name] = source
+			}
+			for _, neighbor := range p.Neighbors {
+				effective.Persona.Neighbors = mergeNeighbor(effective.Persona.Neighbors, neighbor)
+				prov["persona.neighbors."+neighbor.Name] = source
+			}
+		}
+
+		if layer.Image != "" {
+			effective.Image = layer.Image
+			prov["image"] = source
+		}
+		if layer.Baked {
+			effective.Baked = true
+			prov["baked"] = source
+		}
+
+		if p := layer.Ports; p != nil {
+			if effective.Ports == nil {
+				effective.Ports = &models.Ports{}
+			}
+			if p.SSH != 0 {
+				effective.Ports.SSH = p.SSH
+				prov["ports.ssh"] = source
+			}
+			if p.Telnet != 0 {
+				effective.Ports.Telnet = p.Telnet
+				prov["ports.telnet"] = source
+			}
+		}
+
+		// Sinks are merged by type, bait files and commands by path: the most specific layer wins
+		for _, sink := range layer.Sinks {
+			effective.Sinks = mergeSink(effective.Sinks, sink)
+			prov["sinks."+sink.Type] = source
+		}
+		for _, bait := range layer.BaitFiles {
+			effective.BaitFiles = mergeBaitFile(effective.BaitFiles, bait)
+			prov["baitFiles."+bait.Path] = source
+		}
+		for _, command := range layer.Commands {
+			effective.Commands = mergeCommand(effective.Commands, command)
+			prov["commands."+command.Path] = source
+		}
+
+		for section, values := range layer.CowrieOverrides {
+			if effective.CowrieOverrides == nil {
+				effective.CowrieOverrides = make(map[string]map[string]string)
+			}
+			if effective.CowrieOverrides[section] == nil {
+				effective.CowrieOverrides[section] = make(map[string]string)
+			}
+			for key, value := range values {
+				effective.CowrieOverrides[section][key] = value
+				prov["cowrie."+section+"."+key] = source
+			}
+		}
+	}
+
+	return effective, prov, nil
+}
+
+// mergeSink adds a sink or replaces the one of the same type
+func mergeSink(sinks []models.Sink, sink models.Sink) []models.Sink {
+	for i := range sinks {
+		if sinks[i].Type == sink.Type {
+			sinks[i] = sink
+			return sinks
+		}
+	}
+	return append(sinks, sink)
+}
+
+// mergeBaitFile adds a bait file or replaces the one at the same path
+func mergeBaitFile(files []models.BaitFile, file models.BaitFile) []models.BaitFile {
+	for i := range files {
+		if files[i].Path == file.Path {
+			files[i] = file
+			return files
+		}
+	}
+	return append(files, file)
+}
+
+// mergeService adds a service or replaces the one of the same name
+func mergeService(services []models.Service, service models.Service) []models.Service {
+	for i := range services {
+		if services[i].Name == service.Name {
+			services[i] = service
+			return services
+		}
+	}
+	return append(services, service)
+}
+
+// mergeNeighbor adds a neighbour host or replaces the one of the same name
+func mergeNeighbor(neighbors []models.Neighbor, neighbor models.Neighbor) []models.Neighbor {
+	for i := range neighbors {
+		if neighbors[i].Name == neighbor.Name {
+			neighbors[i] = neighbor
+			return neighbors
+		}
+	}
+	return append(neighbors, neighbor)
+}
+
+// mergeCommand adds a command output or replaces the one at the same path
+func mergeCommand(commands []models.Command, command models.Command) []models.Command {
+	for i := range commands {
+		if commands[i].Path == command.Path {
+			commands[i] = command
+			return commands
+		}
+	}
+	return append(commands, command)
+}
+
+// ReadEffectiveConfig reads a profile and resolves its templates
+func ReadEffectiveConfig(profileName string) (*models.Config, error) {
+	cfg, err := ReadConfig(profileName)
+	if err != nil {
+		return nil, err
+	}
+	effective, _, err := ResolveConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return effective, nil
+}
+
+// templateOverlays returns the overlay directories (honeyfs, txtcmds) of the inherited templates, root first
+func templateOverlays(config *models.Config, name string) ([]string, error) {
+	names, _, err := templateChain(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var overlays []string
+	for _, template := range names {
+		dir := filepath.Join(GetTemplatesDir(), template, name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			overlays = append(overlays, dir)
+		}
+	}
+	return overlays, nil
+}
+
+// SortedKeys returns the keys of a provenance map in a stable order
+func (p Provenance) SortedKeys() []string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
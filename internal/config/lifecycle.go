@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkProfileMove checks the source and destination names of a clone or rename
+func checkProfileMove(src, dst string) error {
+	if !IsValidProfileName(dst) {
+		return fmt.Errorf("invalid profile name '%s'", dst)
+	}
+	if !ProfileExists(src) {
+		return fmt.Errorf("profile '%s' %w", src, ErrNotFound)
+	}
+	if isLegacyProfile(src) {
+		return fmt.Errorf("profile '%s' uses the legacy layout, run 'otori profiles migrate %s' first", src, src)
+	}
+	if ProfileExists(dst) {
+		return fmt.Errorf("profile '%s' %w", dst, ErrExists)
+	}
+	if _, err := os.Stat(getProfileDir(dst)); err == nil {
+		return fmt.Errorf("directory %s %w", getProfileDir(dst), ErrExists)
+	}
+	return nil
+}
+
+// CloneProfile copies a whole profile directory (hand-tuned honeyfs and cowrie.cfg included)
+// under a new name. The JSON is renamed and the compose file regenerated for the new name.
+func CloneProfile(src, dst string) error {
+	locks, err := LockProfiles(src, dst)
+	if err != nil {
+		return err
+	}
+	defer locks.Unlock()
+
+	if err := checkProfileMove(src, dst); err != nil {
+		return err
+	}
+
+	// Copy into a staging directory so a failed clone leaves nothing behind
+	staging, err := os.MkdirTemp(getConfigDir(), ".clone-")
+	if err != nil {
+		return fmt.Errorf("error creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if err := os.Chmod(staging, 0755); err != nil {
+		return fmt.Errorf("error creating staging directory: %w", err)
+	}
+
+	if err := copyDir(getProfileDir(src), staging); err != nil {
+		return fmt.Errorf("error copying profile: %w", err)
+	}
+	if err := renameStagedProfile(staging, src, dst, time.Now().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	if err := os.Rename(staging, getProfileDir(dst)); err != nil {
+		return fmt.Errorf("error installing profile: %w", err)
+	}
+	return nil
+}
+
+// RenameProfile renames a profile directory, its JSON and regenerates its compose file.
+// The container and volumes are not touched (see the profiles rename command).
+func RenameProfile(oldName, newName string) error {
+	locks, err := LockProfiles(oldName, newName)
+	if err != nil {
+		return err
+	}
+	defer locks.Unlock()
+
+	if err := checkProfileMove(oldName, newName); err != nil {
+		return err
+	}
+
+	oldDir, newDir := getProfileDir(oldName), getProfileDir(newName)
+	if err := os.Rename(oldDir, newDir); err != nil {
+		return fmt.Errorf("error renaming profile directory: %w", err)
+	}
+	if err := renameStagedProfile(newDir, oldName, newName, ""); err != nil {
+		// Put the profile back under its old name
+		if _, statErr := os.Stat(filepath.Join(newDir, oldName+".json")); statErr == nil {
+			os.Rename(newDir, oldDir)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LockTimeout is how long LockProfile waits for another otori process to release a profile
+var LockTimeout = 30 * time.Second
+
+// lockRetryInterval is the delay between two attempts to take a busy lock
+const lockRetryInterval = 200 * time.Millisecond
+
+// ProfileLock is an exclusive lock on a profile, held across otori processes.
+// Locks are re-entrant within a process: nested LockProfile calls share the same lock.
+type ProfileLock struct {
+	name  string
+	file  *os.File
+	count int
+}
+
+var (
+	heldLocks   = make(map[string]*ProfileLock)
+	heldLocksMu sync.Mutex
+)
+
+// GetLocksDir returns the directory of the profile lock files (~/.otori/locks).
+// Lock files live outside the profile directories, which are replaced on every write.
+func GetLocksDir() string {
+	return filepath.Join(GetOtoriDir(), "locks")
+}
+
+// LockProfile takes the lock of a profile, waiting up to LockTimeout if another
+// otori process holds it. A profile write interrupted by a crash is recovered
+// once the lock is taken.
+func LockProfile(profileName string) (*ProfileLock, error) {
+	if !IsValidProfileName(profileName) {
+		return nil, fmt.Errorf("invalid profile name '%s'", profileName)
+	}
+
+	heldLocksMu.Lock()
+	if lock, ok := heldLocks[profileName]; ok {
+		lock.count++
+		heldLocksMu.Unlock()
+		return lock, nil
+	}
+	heldLocksMu.Unlock()
+
+	if err := os.MkdirAll(GetLocksDir(), 0755); err != nil {
+		return nil, fmt.Errorf("error creating locks directory: %w", err)
+	}
+	path := filepath.Join(GetLocksDir(), profileName+".lock")
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock file: %w", err)
+	}
+
+	// Wait without heldLocksMu, the other profiles stay available meanwhile
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		err = lockFile(file)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			holder := lockHolder(path)
+			file.Close()
+			return nil, fmt.Errorf("profile '%s' %w%s", profileName, ErrLocked, holder)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+
+	// Record the holder for the error message of the other processes
+	file.Truncate(0)
+	fmt.Fprintf(file, "pid=%d\ncommand=%s\nsince=%s\n",
+		os.Getpid(), strings.Join(os.Args, " "), time.Now().Format(time.RFC3339))
+
+	lock := &ProfileLock{name: profileName, file: file, count: 1}
+	if err := recoverProfileDir(profileName); err != nil {
+		unlockFile(file)
+		file.Close()
+		return nil, err
+	}
+
+	heldLocksMu.Lock()
+	heldLocks[profileName] = lock
+	heldLocksMu.Unlock()
+	return lock, nil
+}
+
+// Unlock releases the lock (the last call of nested locks releases the file lock)
+func (l *ProfileLock) Unlock() {
+	heldLocksMu.Lock()
+	defer heldLocksMu.Unlock()
+
+	l.count--
+	if l.count > 0 {
+		return
+	}
+	delete(heldLocks, l.name)
+	unlockFile(l.file)
+	l.file.Close()
+}
+
+// ProfileLocks are the locks of several profiles, see LockProfiles
+type ProfileLocks []*ProfileLock
+
+// LockProfiles takes the locks of several profiles in name order, so that two
+// processes locking the same profiles (rename a b, rename b a) never wait for each other
+func LockProfiles(profileNames ...string) (ProfileLocks, error) {
+	names := slices.Clone(profileNames)
+	slices.Sort(names)
+
+	var locks ProfileLocks
+	for _, name := range slices.Compact(names) {
+		lock, err := LockProfile(name)
+		if err != nil {
+			locks.Unlock()
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// Unlock releases the locks in the reverse order
+func (l ProfileLocks) Unlock() {
+	for i := len(l) - 1; i >= 0; i-- {
+		l[i].Unlock()
+	}
+}
+
+// lockHolder describes the process holding a lock file, from what it recorded
+func lockHolder(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	fields := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			fields[key] = value
+		}
+	}
+	if fields["pid"] == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (pid %s: %s, since %s)", fields["pid"], fields["command"], fields["since"])
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package config
+
+import "os"
+
+// lockFile is a no-op on platforms without flock: concurrent otori processes
+// are only serialized on Unix
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without flock
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,221 @@
+//go:build unix
+
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/otori-lab/otori-cli/internal/testutil"
+)
+
+// holdLock takes the lock file of a profile like another otori process would
+func holdLock(t *testing.T, profileName string) {
+	t.Helper()
+
+	if err := os.MkdirAll(config.GetLocksDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(filepath.Join(config.GetLocksDir(), profileName+".lock"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("pid=1\ncommand=otori deploy\nsince=2025-03-02T10:00:00Z\n")
+}
+
+func TestLockProfile(t *testing.T) {
+	testutil.OtoriHome(t)
+
+	lock, err := config.LockProfile("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := config.LockProfile("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested.Unlock()
+	lock.Unlock()
+
+	// Released by the last Unlock: another process can take it
+	holdLock(t, "web")
+}
+
+func TestLockProfileBusy(t *testing.T) {
+	testutil.OtoriHome(t)
+	timeout := config.LockTimeout
+	config.LockTimeout = time.Second
+	t.Cleanup(func() { config.LockTimeout = timeout })
+
+	holdLock(t, "web")
+	waited := make(chan error)
+	go func() {
+		_, err := config.LockProfile("web")
+		waited <- err
+	}()
+
+	// Waiting for one profile never blocks the others
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	lock, err := config.LockProfile("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.Unlock()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("locking another profile took %s", elapsed)
+	}
+
+	err = <-waited
+	if !errors.Is(err, config.ErrLocked) {
+		t.Fatalf("error %v, want ErrLocked", err)
+	}
+	if want := "(pid 1: otori deploy, since 2025-03-02T10:00:00Z)"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q does not name the holder", err)
+	}
+}
+
+func TestInterruptedSwap(t *testing.T) {
+	testutil.OtoriHome(t)
+	cfg := models.NewConfig()
+	cfg.Type = "classic"
+	cfg.ServerName = "srv-web"
+	cfg.ProfileName = "web"
+	if err := config.WriteConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash between the two renames of a swap leaves the profile aside
+	profileDir := filepath.Join(config.GetConfigDir(), "web")
+	if err := os.Rename(profileDir, filepath.Join(config.GetConfigDir(), ".web.old")); err != nil {
+		t.Fatal(err)
+	}
+
+	if read, err := config.ReadConfig("web"); err != nil || read.ServerName != "srv-web" {
+		t.Fatalf("profile aside not read: %v, %v", read, err)
+	}
+	if !config.ProfileExists("web") {
+		t.Error("profile aside not listed")
+	}
+
+	// Put back by the next lock holder
+	lock, err := config.LockProfile("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.Unlock()
+	if _, err := os.Stat(filepath.Join(profileDir, "web.json")); err != nil {
+		t.Errorf("profile not recovered: %v", err)
+	}
+	if profiles, _ := config.ListConfigs(); len(profiles) != 1 {
+		t.Errorf("profiles: %v", profiles)
+	}
+}
+
+func TestWriteConfigSwap(t *testing.T) {
+	testutil.OtoriHome(t)
+	cfg := models.NewConfig()
+	cfg.Type = "classic"
+	cfg.ServerName = "srv-web"
+	cfg.ProfileName = "web"
+	if err := config.WriteConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files otori does not generate survive a rewrite, nothing is left beside the profile
+	notes := filepath.Join(config.GetConfigDir(), "web", "notes.txt")
+	if err := os.WriteFile(notes, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.ServerName = "srv-web-2"
+	if err := config.WriteConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(notes); err != nil {
+		t.Error(err)
+	}
+	if read, err := config.ReadConfig("web"); err != nil || read.ServerName != "srv-web-2" {
+		t.Errorf("profile not rewritten: %v, %v", read, err)
+	}
+	entries, err := os.ReadDir(config.GetConfigDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("profiles directory: %v", entries)
+	}
+	// The container reads the mounted files as another user
+	if err := config.CloneProfile("web", "copy"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"web", "copy"} {
+		info, err := os.Stat(filepath.Join(config.GetConfigDir(), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != 0755 {
+			t.Errorf("mode of profile %s = %o, want 755", name, mode)
+		}
+	}
+}
+
+func TestLockProfiles(t *testing.T) {
+	testutil.OtoriHome(t)
+	timeout := config.LockTimeout
+	config.LockTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { config.LockTimeout = timeout })
+
+	// Taken in name order: the busy "a" is waited for before "b" is locked
+	holdLock(t, "a")
+	if _, err := config.LockProfiles("b", "a"); !errors.Is(err, config.ErrLocked) {
+		t.Fatalf("error %v, want ErrLocked", err)
+	}
+	holdLock(t, "b")
+
+	locks, err := config.LockProfiles("d", "c", "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locks) != 2 {
+		t.Errorf("%d locks for 2 profiles", len(locks))
+	}
+	locks.Unlock()
+	holdLock(t, "c")
+	holdLock(t, "d")
+}
+
+func TestMigrateProfileLocked(t *testing.T) {
+	testutil.OtoriHome(t)
+	timeout := config.LockTimeout
+	config.LockTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { config.LockTimeout = timeout })
+
+	profileDir := filepath.Join(config.GetConfigDir(), "web")
+	if err := os.MkdirAll(profileDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(profileDir, "web.json"), []byte(`{"type": "classic", "serverName": "srv-web"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// No backup is taken while another process may change the profile
+	holdLock(t, "web")
+	if _, err := config.MigrateProfile("web"); !errors.Is(err, config.ErrLocked) {
+		t.Fatalf("error %v, want ErrLocked", err)
+	}
+	if _, err := os.Stat(config.GetBackupsDir()); !os.IsNotExist(err) {
+		t.Errorf("backup taken without the lock: %v", err)
+	}
+}
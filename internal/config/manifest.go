@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/otori-lab/otori-cli/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestVersion is the version of the otori.yaml format read by this version of otori
+const ManifestVersion = 1
+
+// Manifest describes a whole honeypot estate (otori.yaml)
+type Manifest struct {
+	Version  int               `yaml:"version"`
+	Profiles []ManifestProfile `yaml:"profiles"`
+}
+
+// ManifestProfile is a profile entry of the manifest: a name plus the profile fields
+type ManifestProfile struct {
+	Name          string `yaml:"name"`
+	models.Config `yaml:",inline"`
+}
+
+// LoadManifest reads and checks a manifest file ("-" reads stdin)
+func LoadManifest(path string) (*Manifest, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest: %w", err)
+	}
+
+	return ParseManifest(data)
+}
+
+// ParseManifest decodes a manifest and checks its structure.
+// Unknown fields are rejected so that typos don't silently drop settings.
+func ParseManifest(data []byte) (*Manifest, error) {
+	var manifest Manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error decoding manifest: %w", err)
+	}
+
+	if manifest.Version == 0 {
+		manifest.Version = ManifestVersion
+	}
+	if manifest.Version > ManifestVersion {
+		return nil, fmt.Errorf("manifest version %d is not supported (max %d)", manifest.Version, ManifestVersion)
+	}
+
+	seen := make(map[string]bool)
+	for i, entry := range manifest.Profiles {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("profile #%d: name is required", i+1)
+		}
+		if entry.ProfileName != "" && entry.ProfileName != entry.Name {
+			return nil, fmt.Errorf("profile '%s': profileName '%s' does not match name", entry.Name, entry.ProfileName)
+		}
+		if seen[entry.Name] {
+			return nil, fmt.Errorf("profile '%s' is declared twice", entry.Name)
+		}
+		seen[entry.Name] = true
+	}
+
+	return &manifest, nil
+}
+
+// Configs returns the profile configurations declared by the manifest
+func (m *Manifest) Configs() []*models.Config {
+	configs := make([]*models.Config, 0, len(m.Profiles))
+	for _, entry := range m.Profiles {
+		cfg := entry.Config
+		cfg.SchemaVersion = models.CurrentSchemaVersion
+		cfg.ProfileName = entry.Name
+		cfg.CreatedAt = ""
+		if cfg.Users == nil {
+			cfg.Users = []string{}
+		}
+		configs = append(configs, &cfg)
+	}
+	return configs
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/models"
+)
+
+// Migration upgrades a profile JSON document from one schema version to the next.
+// Migrations work on the raw document so that renamed or removed fields can be handled.
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(doc map[string]interface{}) error
+}
+
+// migrations is the ordered registry of schema upgrades.
+// To change the format: bump models.CurrentSchemaVersion and add a migration here.
+var migrations = []Migration{
+	{
+		From:        1,
+		Description: "normalize type, clean users and add schemaVersion",
+		Apply:       migrateV1ToV2,
+	},
+}
+
+// migrateV1ToV2 upgrades profiles written before schemaVersion existed
+func migrateV1ToV2(doc map[string]interface{}) error {
+	if t, ok := doc["type"].(string); ok {
+		doc["type"] = strings.ToLower(strings.TrimSpace(t))
+	}
+
+	users := []interface{}{}
+	if list, ok := doc["users"].([]interface{}); ok {
+		for _, u := range list {
+			if s, ok := u.(string); ok {
+				if cleaned := removeNullChars(s); cleaned != "" {
+					users = append(users, cleaned)
+				}
+			}
+		}
+	}
+	doc["users"] = users
+
+	return nil
+}
+
+// MigrationResult describes how a profile document was upgraded
+type MigrationResult struct {
+	FromVersion int
+	Applied     []string
+}
+
+// decodeConfig decodes a profile JSON document, applying pending migrations
+func decodeConfig(data []byte) (*models.Config, MigrationResult, error) {
+	var result MigrationResult
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, result, fmt.Errorf("error decoding JSON: %w", err)
+	}
+
+	version := 1 // documents without schemaVersion predate versioning
+	if v, ok := doc["schemaVersion"].(float64); ok && v > 0 {
+		version = int(v)
+	}
+	result.FromVersion = version
+
+	if version > models.CurrentSchemaVersion {
+		return nil, result, fmt.Errorf("schema version %d is newer than supported version %d (upgrade otori)",
+			version, models.CurrentSchemaVersion)
+	}
+
+	for _, m := range migrations {
+		if m.From != version {
+			continue
+		}
+		if err := m.Apply(doc); err != nil {
+			return nil, result, fmt.Errorf("migration from v%d failed: %w", m.From, err)
+		}
+		version = m.From + 1
+		result.Applied = append(result.Applied, fmt.Sprintf("v%d → v%d: %s", m.From, version, m.Description))
+	}
+	doc["schemaVersion"] = version
+
+	if version != models.CurrentSchemaVersion {
+		return nil, result, fmt.Errorf("no migration path from schema version %d", version)
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, result, err
+	}
+
+	var config models.Config
+	if err := json.Unmarshal(migrated, &config); err != nil {
+		return nil, result, fmt.Errorf("error decoding JSON: %w", err)
+	}
+	return &config, result, nil
+}
+
+// legacyProfilePath returns the path of a profile in the old flat layout (profiles/{name}.json)
+func legacyProfilePath(profileName string) string {
+	return filepath.Join(getConfigDir(), profileName+".json")
+}
+
+// isLegacyProfile returns true if a profile only exists in the old flat layout
+func isLegacyProfile(profileName string) bool {
+	if _, err := os.Stat(filepath.Join(getProfileDir(profileName), profileName+".json")); err == nil {
+		return false
+	}
+	_, err := os.Stat(legacyProfilePath(profileName))
+	return err == nil
+}
+
+// readProfileFile returns the raw JSON of a profile, whatever its layout
+func readProfileFile(profileName string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(getProfileDir(profileName), profileName+".json"))
+	if err == nil {
+		return data, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	// A swap interrupted by a crash leaves the profile aside until the next lock recovers it
+	if data, err := os.ReadFile(filepath.Join(previousProfileDir(profileName), profileName+".json")); err == nil {
+		return data, nil
+	}
+
+	data, legacyErr := os.ReadFile(legacyProfilePath(profileName))
+	if legacyErr != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return data, nil
+}
+
+// MigrationPlan describes the pending upgrade of a profile
+type MigrationPlan struct {
+	Profile     string
+	Legacy      bool // stored in the old flat layout
+	FromVersion int
+	Steps       []string
+}
+
+// Pending returns true if the profile needs to be rewritten
+func (p MigrationPlan) Pending() bool {
+	return p.Legacy || p.FromVersion < models.CurrentSchemaVersion
+}
+
+// PlanMigration inspects a profile without modifying it
+func PlanMigration(profileName string) (MigrationPlan, error) {
+	plan := MigrationPlan{Profile: profileName, Legacy: isLegacyProfile(profileName)}
+
+	data, err := readProfileFile(profileName)
+	if err != nil {
+		return plan, err
+	}
+	_, result, err := decodeConfig(data)
+	if err != nil {
+		return plan, err
+	}
+
+	plan.FromVersion = result.FromVersion
+	plan.Steps = result.Applied
+	if plan.Legacy {
+		plan.Steps = append(plan.Steps, "move profiles/"+profileName+".json to profiles/"+profileName+"/")
+	}
+	return plan, nil
+}
+
+// GetBackupsDir returns the directory holding pre-migration backups (~/.otori/backups)
+func GetBackupsDir() string {
+	return filepath.Join(GetOtoriDir(), "backups")
+}
+
+// MigrateProfile backs up a profile then rewrites it in the current schema and layout,
+// under the profile lock so that the backup matches what is migrated.
+// It returns the backup directory.
+func MigrateProfile(profileName string) (string, error) {
+	lock, err := LockProfile(profileName)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Unlock()
+
+	plan, err := PlanMigration(profileName)
+	if err != nil {
+		return "", err
+	}
+	if !plan.Pending() {
+		return "", nil
+	}
+
+	// Back up the current files before touching anything
+	backupDir := filepath.Join(GetBackupsDir(), time.Now().Format("20060102-150405"), profileName)
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", fmt.Errorf("error creating backup directory: %w", err)
+	}
+	if plan.Legacy {
+		data, err := os.ReadFile(legacyProfilePath(profileName))
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(backupDir, profileName+".json"), data, 0644); err != nil {
+			return "", fmt.Errorf("error writing backup: %w", err)
+		}
+	} else if err := copyDir(getProfileDir(profileName), backupDir); err != nil {
+		return "", fmt.Errorf("error writing backup: %w", err)
+	}
+
+	cfg, err := ReadConfig(profileName)
+	if err != nil {
+		return backupDir, err
+	}
+	cfg.ProfileName = profileName
+	cfg.SchemaVersion = models.CurrentSchemaVersion
+
+	if plan.Legacy {
+		// Legacy profiles never had generated files: render them now
+		if err := WriteConfigWithName(profileName, cfg); err != nil {
+			// Keep the legacy file as the only copy so the migration can be retried
+			os.RemoveAll(getProfileDir(profileName))
+			return backupDir, err
+		}
+		if err := os.Remove(legacyProfilePath(profileName)); err != nil {
+			return backupDir, fmt.Errorf("error removing legacy file: %w", err)
+		}
+		return backupDir, nil
+	}
+
+	if err := writeProfileJSON(profileName, cfg); err != nil {
+		return backupDir, err
+	}
+	return backupDir, nil
+}
@@ -0,0 +1,112 @@
+package config_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/otori-lab/otori-cli/internal/testutil"
+)
+
+// v1Profile is a profile written before schemaVersion existed
+const v1Profile = `{"type": " Classic ", "serverName": "srv-web", "profileName": "web", "users": ["root\u0000", "", "admin"], "createdAt": "2024-06-01T10:00:00Z"}`
+
+// checkMigrated checks a migrated profile and its backup
+func checkMigrated(t *testing.T, backupDir string) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(config.GetConfigDir(), "web", "web.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg models.Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SchemaVersion != models.CurrentSchemaVersion || cfg.Type != "classic" || !slices.Equal(cfg.Users, []string{"root", "admin"}) {
+		t.Errorf("migrated profile: %+v", cfg)
+	}
+	if cfg.ServerName != "srv-web" || cfg.CreatedAt != "2024-06-01T10:00:00Z" {
+		t.Errorf("fields lost by the migration: %+v", cfg)
+	}
+
+	backup, err := os.ReadFile(filepath.Join(backupDir, "web.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(backup) != v1Profile {
+		t.Errorf("backup:\n%s", backup)
+	}
+
+	// Nothing left to do
+	if plan, err := config.PlanMigration("web"); err != nil || plan.Pending() {
+		t.Errorf("plan after migration: %+v, %v", plan, err)
+	}
+	if dir, err := config.MigrateProfile("web"); err != nil || dir != "" {
+		t.Errorf("second migration: %q, %v", dir, err)
+	}
+}
+
+func TestMigrateProfileV1(t *testing.T) {
+	testutil.OtoriHome(t)
+	profileDir := filepath.Join(config.GetConfigDir(), "web")
+	if err := os.MkdirAll(profileDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(profileDir, "web.json"), []byte(v1Profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := config.PlanMigration("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Pending() || plan.Legacy || plan.FromVersion != 1 || len(plan.Steps) != 1 {
+		t.Errorf("plan: %+v", plan)
+	}
+
+	backupDir, err := config.MigrateProfile("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, backupDir)
+}
+
+func TestMigrateProfileLegacyLayout(t *testing.T) {
+	testutil.OtoriHome(t)
+	if err := os.MkdirAll(config.GetConfigDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := filepath.Join(config.GetConfigDir(), "web.json")
+	if err := os.WriteFile(legacy, []byte(v1Profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := config.PlanMigration("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Pending() || !plan.Legacy || len(plan.Steps) != 2 {
+		t.Errorf("plan: %+v", plan)
+	}
+
+	backupDir, err := config.MigrateProfile("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMigrated(t, backupDir)
+
+	// Moved to its own directory, with the generated files
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("legacy file kept: %v", err)
+	}
+	for _, name := range []string{"cowrie.cfg", "userdb.txt", "docker-compose.yml"} {
+		if _, err := os.Stat(filepath.Join(config.GetConfigDir(), "web", name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,562 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/models"
+)
+
+// The persona describes the machine behind the shell: simulated services (processes,
+// listening sockets, configuration files) and neighbour hosts. The same process table
+// and network are rendered in the honeyfs (/etc/hosts, /proc/net/arp, /proc/net/tcp,
+// service configs) and in txtcmds (ps, netstat, ss, systemctl), so they agree.
+
+// postgresVersion is the PostgreSQL cluster version used in paths and process names
+const postgresVersion = "13"
+
+// serviceSpec describes a simulated service
+type serviceSpec struct {
+	Port        int    // default listening port (0 = unix socket only)
+	Program     string // program name in netstat and ss
+	Unit        string // systemd unit
+	Description string // systemd unit description
+	Processes   []processSpec
+}
+
+// processSpec is a process of a service, the first one owns the listening socket
+type processSpec struct {
+	User     string
+	Command  string
+	VSZ, RSS int64 // kB
+}
+
+// serviceSpecs are the services a persona can simulate
+var serviceSpecs = map[string]serviceSpec{
+	"sshd": {
+		Port: 22, Program: "sshd", Unit: "ssh.service", Description: "OpenBSD Secure Shell server",
+		Processes: []processSpec{
+			{"root", "/usr/sbin/sshd -D", 15852, 6740},
+		},
+	},
+	"nginx": {
+		Port: 80, Program: "nginx", Unit: "nginx.service", Description: "A high performance web server and a reverse proxy server",
+		Processes: []processSpec{
+			{"root", "nginx: master process /usr/sbin/nginx -g daemon on; master_process on;", 55280, 1644},
+			{"www-data", "nginx: worker process", 55904, 5572},
+			{"www-data", "nginx: worker process", 55904, 5412},
+		},
+	},
+	"postgres": {
+		Port: 5432, Program: "postgres", Unit: "postgresql@" + postgresVersion + "-main.service", Description: "PostgreSQL Cluster " + postgresVersion + "-main",
+		Processes: []processSpec{
+			{"postgres", "/usr/lib/postgresql/" + postgresVersion + "/bin/postgres -D /var/lib/postgresql/" + postgresVersion + "/main -c config_file=/etc/postgresql/" + postgresVersion + "/main/postgresql.conf", 215708, 29092},
+			{"postgres", "postgres: " + postgresVersion + "/main: checkpointer", 215820, 8316},
+			{"postgres", "postgres: " + postgresVersion + "/main: background writer", 215708, 6132},
+			{"postgres", "postgres: " + postgresVersion + "/main: walwriter", 215708, 10128},
+			{"postgres", "postgres: " + postgresVersion + "/main: autovacuum launcher", 216260, 8624},
+			{"postgres", "postgres: " + postgresVersion + "/main: stats collector", 70432, 6148},
+			{"postgres", "postgres: " + postgresVersion + "/main: logical replication launcher", 216132, 6820},
+		},
+	},
+	"docker": {
+		Port: 0, Program: "dockerd", Unit: "docker.service", Description: "Docker Application Container Engine",
+		Processes: []processSpec{
+			{"root", "/usr/bin/dockerd -H fd:// --containerd=/run/containerd/containerd.sock", 1428360, 81244},
+			{"root", "/usr/bin/containerd", 1354028, 46512},
+		},
+	},
+}
+
+// systemServices are the services running on every simulated machine
+var systemServices = []serviceSpec{
+	{Unit: "systemd-journald.service", Description: "Journal Service", Processes: []processSpec{{"root", "/lib/systemd/systemd-journald", 40416, 13512}}},
+	{Unit: "systemd-udevd.service", Description: "udev Kernel Device Manager", Processes: []processSpec{{"root", "/lib/systemd/systemd-udevd", 21940, 5408}}},
+	{Unit: "cron.service", Description: "Regular background program processing daemon", Processes: []processSpec{{"root", "/usr/sbin/cron -f", 6684, 2788}}},
+	{Unit: "rsyslog.service", Description: "System Logging Service", Processes: []processSpec{{"root", "/usr/sbin/rsyslogd -n -iNONE", 220796, 4352}}},
+	{Unit: "systemd-logind.service", Description: "User Login Management", Processes: []processSpec{{"root", "/lib/systemd/systemd-logind", 13820, 6076}}},
+}
+
+// kernelThreads are the first processes of ps after init, with their state
+var kernelThreads = [][2]string{
+	{"[kthreadd]", "S"}, {"[rcu_gp]", "I<"}, {"[rcu_par_gp]", "I<"}, {"[kworker/0:0H-events_highpri]", "I<"},
+	{"[mm_percpu_wq]", "I<"}, {"[rcu_tasks_rude_]", "S"}, {"[rcu_tasks_trace]", "S"}, {"[ksoftirqd/0]", "S"},
+	{"[rcu_sched]", "I"}, {"[migration/0]", "S"}, {"[cpuhp/0]", "S"}, {"[kdevtmpfs]", "S"}, {"[netns]", "I<"},
+	{"[kauditd]", "S"}, {"[khungtaskd]", "S"}, {"[oom_reaper]", "S"}, {"[writeback]", "I<"}, {"[kcompactd0]", "S"},
+	{"[ksmd]", "SN"}, {"[khugepaged]", "SN"}, {"[kintegrityd]", "I<"}, {"[kblockd]", "I<"}, {"[kswapd0]", "S"},
+	{"[ata_sff]", "I<"}, {"[scsi_eh_0]", "S"}, {"[jbd2/sda1-8]", "S"}, {"[ext4-rsv-conver]", "I<"},
+}
+
+// serviceUIDs are the uids of the accounts running services (see appendServiceAccounts)
+var serviceUIDs = map[string]int{"root": 0, "www-data": 33, "postgres": 102}
+
+// ServiceNames returns the services a persona can simulate
+func ServiceNames() []string {
+	names := make([]string, 0, len(serviceSpecs))
+	for name := range serviceSpecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// servicePort returns the listening port of a service (0 = none)
+func servicePort(service models.Service) int {
+	if service.Port != 0 {
+		return service.Port
+	}
+	return serviceSpecs[service.Name].Port
+}
+
+// personaServices returns the simulated services: sshd is always running
+// (the attacker is connected to it), the others come from the persona
+func personaServices(config *models.Config) []models.Service {
+	services := []models.Service{{Name: "sshd"}}
+	if config.Persona == nil {
+		return services
+	}
+	for _, service := range config.Persona.Services {
+		if _, ok := serviceSpecs[service.Name]; !ok {
+			continue
+		}
+		if service.Name == "sshd" {
+			services[0] = service
+			continue
+		}
+		services = append(services, service)
+	}
+	return services
+}
+
+// hasService returns true if the persona simulates a service
+func hasService(config *models.Config, name string) bool {
+	for _, service := range personaServices(config) {
+		if service.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// process is a line of the simulated process table
+type process struct {
+	PID      int
+	User     string
+	Command  string
+	VSZ, RSS int64
+	TTY      string
+	Stat     string
+	Boot     bool   // started at boot (START shows the boot date)
+	Time     string // cumulated CPU time
+	Service  string // service owning the process, if any
+	Listener bool   // owns the listening socket of the service
+}
+
+// personaProcesses returns the process table of the simulated machine: init and kernel
+// threads, system daemons, persona services, then the shell session of the attacker
+func personaProcesses(config *models.Config) []process {
+	key := seedKey(config) + ":ps"
+	cpuTime := func(name string, max int64) string {
+		seconds := stableNumber(key+":time:"+name, 0, max)
+		return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+	}
+
+	processes := []process{{PID: 1, User: "root", Command: "/sbin/init", VSZ: 164452, RSS: 10276, TTY: "?", Stat: "Ss", Boot: true, Time: cpuTime("init", 600)}}
+	pid := 2
+	for i, thread := range kernelThreads {
+		processes = append(processes, process{PID: pid, User: "root", Command: thread[0], TTY: "?", Stat: thread[1], Boot: true, Time: "0:00"})
+		pid += int(stableNumber(fmt.Sprintf("%s:kthread:%d", key, i), 1, 3))
+	}
+
+	pid = int(stableNumber(key+":daemons", 180, 260))
+	for _, spec := range systemServices {
+		for _, p := range spec.Processes {
+			processes = append(processes, process{PID: pid, User: p.User, Command: p.Command, VSZ: p.VSZ, RSS: p.RSS, TTY: "?", Stat: "Ss", Boot: true, Time: cpuTime(p.Command, 300)})
+			pid += int(stableNumber(key+":pid:"+p.Command, 20, 90))
+		}
+	}
+
+	for _, service := range personaServices(config) {
+		spec := serviceSpecs[service.Name]
+		for i, p := range spec.Processes {
+			command := p.Command
+			if service.Name == "docker" && service.Port != 0 {
+				command = strings.Replace(command, "-H fd://", fmt.Sprintf("-H fd:// -H tcp://0.0.0.0:%d", service.Port), 1)
+			}
+			stat := "Ss"
+			if i > 0 {
+				stat = "S"
+			}
+			if service.Name == "docker" {
+				stat = "Ssl"
+			}
+			processes = append(processes, process{
+				PID: pid, User: p.User, Command: command, VSZ: p.VSZ, RSS: p.RSS, TTY: "?", Stat: stat,
+				Boot: true, Time: cpuTime(fmt.Sprintf("%s:%d", service.Name, i), 1800), Service: service.Name, Listener: i == 0,
+			})
+			pid += int(stableNumber(key+":pid:"+service.Name+":"+strconv.Itoa(i), 1, 40))
+		}
+	}
+
+	processes = append(processes, process{PID: pid, User: "root", Command: "/sbin/agetty -o -p -- \\u --noclear tty1 linux", VSZ: 5844, RSS: 1716, TTY: "tty1", Stat: "Ss+", Boot: true, Time: "0:00"})
+
+	// Shell session of the attacker
+	pid = int(stableNumber(key+":session", 20000, 30000))
+	processes = append(processes,
+		process{PID: pid, User: "root", Command: "sshd: root@pts/0", VSZ: 16924, RSS: 10932, TTY: "?", Stat: "Ss", Time: "0:00"},
+		process{PID: pid + 7, User: "root", Command: "-bash", VSZ: 8616, RSS: 5272, TTY: "pts/0", Stat: "Ss", Time: "0:00"},
+		process{PID: pid + int(stableNumber(key+":ps", 40, 400)), User: "root", Command: "ps aux", VSZ: 11492, RSS: 3244, TTY: "pts/0", Stat: "R+", Time: "0:00"},
+	)
+	return processes
+}
+
+// socket is a listening TCP socket of the simulated machine
+type socket struct {
+	Port    int
+	PID     int
+	Program string
+	UID     int
+	Inode   int64
+}
+
+// listeningSockets returns the TCP sockets of the persona services, by port
+func listeningSockets(config *models.Config) []socket {
+	pids := make(map[string]int)
+	for _, p := range personaProcesses(config) {
+		if p.Listener {
+			pids[p.Service] = p.PID
+		}
+	}
+
+	var sockets []socket
+	for _, service := range personaServices(config) {
+		port := servicePort(service)
+		if port == 0 {
+			continue
+		}
+		spec := serviceSpecs[service.Name]
+		sockets = append(sockets, socket{
+			Port:    port,
+			PID:     pids[service.Name],
+			Program: spec.Program,
+			UID:     serviceUIDs[spec.Processes[0].User],
+			Inode:   stableNumber(seedKey(config)+":inode:"+service.Name, 10000, 40000),
+		})
+	}
+	sort.Slice(sockets, func(i, j int) bool { return sockets[i].Port < sockets[j].Port })
+	return sockets
+}
+
+// neighborHost is a host of the simulated local network
+type neighborHost struct {
+	Name string
+	FQDN string
+	IP   string
+	MAC  string
+}
+
+// personaNetwork is the local network of the simulated machine
+type personaNetwork struct {
+	Domain    string
+	Address   string // IPv4 address of the server
+	Subnet    string // /24 of the server, e.g. 10.12.4.0/24
+	Gateway   neighborHost
+	Neighbors []neighborHost
+}
+
+// networkOf returns the local network of a profile. The server lives in the /24 of
+// the first neighbour with an explicit address, or in a /24 derived from its name.
+func networkOf(config *models.Config) personaNetwork {
+	key := seedKey(config) + ":net"
+	var neighbors []models.Neighbor
+	if config.Persona != nil {
+		neighbors = config.Persona.Neighbors
+	}
+
+	prefix := fmt.Sprintf("10.%d.%d", stableNumber(key+":a", 0, 31), stableNumber(key+":b", 0, 254))
+	for _, neighbor := range neighbors {
+		if ip := net.ParseIP(neighbor.IP).To4(); ip != nil {
+			prefix = fmt.Sprintf("%d.%d.%d", ip[0], ip[1], ip[2])
+			break
+		}
+	}
+
+	used := map[string]bool{prefix + ".1": true}
+	for _, neighbor := range neighbors {
+		used[neighbor.IP] = true
+	}
+	// free returns the first unused address of the subnet from a derived host number
+	free := func(name string) string {
+		host := stableNumber(key+":host:"+name, 10, 250)
+		for used[fmt.Sprintf("%s.%d", prefix, host)] {
+			host = host%250 + 1
+		}
+		ip := fmt.Sprintf("%s.%d", prefix, host)
+		used[ip] = true
+		return ip
+	}
+
+	domain := companyDomain(config)
+	network := personaNetwork{
+		Domain:  domain,
+		Address: free(config.ServerName),
+		Subnet:  prefix + ".0/24",
+		Gateway: neighborHost{Name: "gateway", FQDN: "gateway." + domain, IP: prefix + ".1", MAC: stableMAC(key + ":gateway")},
+	}
+	for _, neighbor := range neighbors {
+		ip := neighbor.IP
+		if ip == "" {
+			ip = free(neighbor.Name)
+		}
+		name := strings.ToLower(neighbor.Name)
+		network.Neighbors = append(network.Neighbors, neighborHost{
+			Name: name,
+			FQDN: name + "." + domain,
+			IP:   ip,
+			MAC:  stableMAC(key + ":mac:" + name),
+		})
+	}
+	return network
+}
+
+// stableMAC derives a locally administered MAC address (KVM prefix) from a key
+func stableMAC(key string) string {
+	h := stableHex(key, 6)
+	return "52:54:00:" + h[0:2] + ":" + h[2:4] + ":" + h[4:6]
+}
+
+// referenceTime is the simulated "now" of a profile: its creation date, so outputs
+// stay the same across renders
+func referenceTime(config *models.Config) time.Time {
+	ref, err := time.Parse(time.RFC3339, config.CreatedAt)
+	if err != nil {
+		ref = time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	}
+	return ref.UTC()
+}
+
+// bootTime is the simulated boot date of the machine, a few weeks before referenceTime
+func bootTime(config *models.Config) time.Time {
+	days := stableNumber(seedKey(config)+":uptime", 15, 90)
+	return referenceTime(config).Add(-time.Duration(days)*24*time.Hour - time.Duration(stableNumber(seedKey(config)+":uptime:min", 0, 1439))*time.Minute)
+}
+
+// writePersonaNetwork renders /etc/hosts, /proc/net/arp and /proc/net/tcp
+func writePersonaNetwork(tree fileTree, config *models.Config) {
+	network := networkOf(config)
+	short := config.ServerName
+	if short == "" {
+		short = "svr04"
+	}
+
+	var hosts strings.Builder
+	fmt.Fprintf(&hosts, "127.0.0.1\tlocalhost\n")
+	fmt.Fprintf(&hosts, "%s\t%s.%s\t%s\n", network.Address, short, network.Domain, short)
+	if len(network.Neighbors) > 0 {
+		title := config.Company
+		if title == "" {
+			title = "Local"
+		}
+		fmt.Fprintf(&hosts, "\n# %s network\n", title)
+		for _, neighbor := range network.Neighbors {
+			fmt.Fprintf(&hosts, "%s\t%s\t%s\n", neighbor.IP, neighbor.FQDN, neighbor.Name)
+		}
+	}
+	hosts.WriteString(`
+# The following lines are desirable for IPv6 capable hosts
+::1     localhost ip6-localhost ip6-loopback
+ff02::1 ip6-allnodes
+ff02::2 ip6-allrouters
+`)
+	tree.set("etc/hosts", []byte(hosts.String()))
+
+	var arp strings.Builder
+	fmt.Fprintf(&arp, "%-16s %-11s %-11s %-21s %-8s %s\n", "IP address", "HW type", "Flags", "HW address", "Mask", "Device")
+	for _, host := range append([]neighborHost{network.Gateway}, network.Neighbors...) {
+		fmt.Fprintf(&arp, "%-16s %-11s %-11s %-21s %-8s %s\n", host.IP, "0x1", "0x2", host.MAC, "*", "eth0")
+	}
+	tree.set("proc/net/arp", []byte(arp.String()))
+
+	var tcp strings.Builder
+	tcp.WriteString("  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n")
+	for i, s := range listeningSockets(config) {
+		fmt.Fprintf(&tcp, "%4d: 00000000:%04X 00000000:0000 0A 00000000:00000000 00:00000000 00000000 %5d        0 %d 1 0000000000000000 100 0 0 10 0\n",
+			i, s.Port, s.UID, s.Inode)
+	}
+	tree.set("proc/net/tcp", []byte(tcp.String()))
+}
+
+// appendServiceAccounts adds the system accounts and groups of the persona services
+// (postgres user, docker group) to passwd, shadow and group
+func appendServiceAccounts(tree fileTree, config *models.Config) {
+	if hasService(config, "postgres") {
+		appendLines(tree, "etc/passwd", []string{"postgres:x:102:105:PostgreSQL administrator,,,:/var/lib/postgresql:/bin/bash"})
+		appendLines(tree, "etc/shadow", []string{"postgres:*:15800:0:99999:7:::"})
+		appendLines(tree, "etc/group", []string{"postgres:x:105:"})
+	}
+	if hasService(config, "docker") {
+		var members []string
+		for _, user := range config.Users {
+			if user != "root" {
+				members = append(members, user)
+			}
+		}
+		appendLines(tree, "etc/group", []string{"docker:x:998:" + strings.Join(members, ",")})
+	}
+}
+
+// writeServiceConfigs renders the configuration files of the persona services
+func writeServiceConfigs(tree fileTree, config *models.Config) {
+	network := networkOf(config)
+	fqdn := config.ServerName + "." + network.Domain
+
+	for _, service := range personaServices(config) {
+		port := servicePort(service)
+		switch service.Name {
+		case "sshd":
+			sshdConfig := fmt.Sprintf(sshdConfigTemplate, port)
+			if config.Company != "" {
+				sshdConfig += "Banner /etc/issue.net\n"
+			}
+			tree.set("etc/ssh/sshd_config", []byte(sshdConfig))
+		case "nginx":
+			site := fmt.Sprintf(nginxSiteTemplate, port, port, fqdn, config.ServerName)
+			tree.set("etc/nginx/nginx.conf", []byte(nginxConfig))
+			tree.set("etc/nginx/sites-available/default", []byte(site))
+			tree.set("etc/nginx/sites-enabled/default", []byte(site))
+		case "postgres":
+			dir := "etc/postgresql/" + postgresVersion + "/main/"
+			tree.set(dir+"postgresql.conf", []byte(strings.NewReplacer("{version}", postgresVersion, "{port}", strconv.Itoa(port)).Replace(postgresConfigTemplate)))
+			tree.set(dir+"pg_hba.conf", []byte(fmt.Sprintf(pgHBATemplate, network.Subnet)))
+		case "docker":
+			tree.set("etc/docker/daemon.json", []byte(dockerDaemonConfig))
+		}
+	}
+}
+
+const sshdConfigTemplate = `# See the sshd_config(5) manpage for details
+
+Port %d
+Protocol 2
+HostKey /etc/ssh/ssh_host_rsa_key
+HostKey /etc/ssh/ssh_host_ecdsa_key
+HostKey /etc/ssh/ssh_host_ed25519_key
+
+SyslogFacility AUTH
+LogLevel INFO
+
+PermitRootLogin yes
+PubkeyAuthentication yes
+PasswordAuthentication yes
+ChallengeResponseAuthentication no
+UsePAM yes
+
+X11Forwarding yes
+PrintMotd no
+AcceptEnv LANG LC_*
+Subsystem sftp /usr/lib/openssh/sftp-server
+`
+
+const nginxConfig = `user www-data;
+worker_processes auto;
+pid /run/nginx.pid;
+include /etc/nginx/modules-enabled/*.conf;
+
+events {
+	worker_connections 768;
+}
+
+http {
+	sendfile on;
+	tcp_nopush on;
+	types_hash_max_size 2048;
+	server_tokens off;
+
+	include /etc/nginx/mime.types;
+	default_type application/octet-stream;
+
+	ssl_protocols TLSv1.2 TLSv1.3;
+	ssl_prefer_server_ciphers on;
+
+	access_log /var/log/nginx/access.log;
+	error_log /var/log/nginx/error.log;
+
+	gzip on;
+
+	include /etc/nginx/conf.d/*.conf;
+	include /etc/nginx/sites-enabled/*;
+}
+`
+
+const nginxSiteTemplate = `server {
+	listen %d default_server;
+	listen [::]:%d default_server;
+
+	root /var/www/html;
+	index index.html index.htm index.nginx-debian.html;
+
+	server_name %s %s;
+
+	location / {
+		try_files $uri $uri/ =404;
+	}
+}
+`
+
+const postgresConfigTemplate = `# PostgreSQL configuration file
+
+data_directory = '/var/lib/postgresql/{version}/main'
+hba_file = '/etc/postgresql/{version}/main/pg_hba.conf'
+ident_file = '/etc/postgresql/{version}/main/pg_ident.conf'
+external_pid_file = '/var/run/postgresql/{version}-main.pid'
+
+listen_addresses = '*'
+port = {port}
+max_connections = 100
+unix_socket_directories = '/var/run/postgresql'
+
+ssl = on
+ssl_cert_file = '/etc/ssl/certs/ssl-cert-snakeoil.pem'
+ssl_key_file = '/etc/ssl/private/ssl-cert-snakeoil.key'
+
+shared_buffers = 128MB
+dynamic_shared_memory_type = posix
+max_wal_size = 1GB
+min_wal_size = 80MB
+
+log_line_prefix = '%m [%p] %q%u@%d '
+log_timezone = 'Etc/UTC'
+cluster_name = '{version}/main'
+stats_temp_directory = '/var/run/postgresql/{version}-main.pg_stat_tmp'
+
+datestyle = 'iso, mdy'
+timezone = 'Etc/UTC'
+lc_messages = 'C.UTF-8'
+default_text_search_config = 'pg_catalog.english'
+
+include_dir = 'conf.d'
+`
+
+const pgHBATemplate = `# PostgreSQL Client Authentication Configuration File
+
+# TYPE  DATABASE        USER            ADDRESS                 METHOD
+local   all             postgres                                peer
+local   all             all                                     peer
+host    all             all             127.0.0.1/32            md5
+host    all             all             %-23s md5
+host    all             all             ::1/128                 md5
+local   replication     all                                     peer
+`
+
+const dockerDaemonConfig = `{
+  "log-driver": "json-file",
+  "log-opts": {
+    "max-size": "10m",
+    "max-file": "3"
+  }
+}
+`
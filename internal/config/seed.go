@@ -0,0 +1,51 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	mathrand "math/rand/v2"
+
+	"github.com/otori-lab/otori-cli/internal/models"
+)
+
+// Every generated value (PIDs, addresses, sizes, passwords...) is derived from the
+// seed of the profile: rendering the same configuration with the same seed gives
+// byte-identical files. The seed is drawn once when the profile is created.
+
+// NewSeed returns a random profile seed
+func NewSeed() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// seedKey returns the prefix of the keys of stableNumber and stableHex for a profile.
+// Profiles created before seeds existed fall back to their name.
+func seedKey(config *models.Config) string {
+	seed := config.Seed
+	if seed == "" {
+		seed = config.ProfileName
+	}
+	return seed + ":" + config.ServerName
+}
+
+// stableNumber derives a number in [min, max] from a key, so outputs stay the same across renders
+func stableNumber(key string, min, max int64) int64 {
+	sum := sha256.Sum256([]byte(key))
+	return min + int64(binary.BigEndian.Uint64(sum[:8])%uint64(max-min+1))
+}
+
+// stableHex derives a hex string of up to 64 characters from a key (machine IDs...)
+func stableHex(key string, n int) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:n]
+}
+
+// personaRand returns a random generator seeded by the profile and a purpose,
+// for generators drawing many values (bait files...)
+func personaRand(config *models.Config, purpose string) *mathrand.Rand {
+	sum := sha256.Sum256([]byte(seedKey(config) + "\x00" + config.Company + "\x00" + purpose))
+	return mathrand.New(mathrand.NewPCG(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16])))
+}
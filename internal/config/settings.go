@@ -0,0 +1,428 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/otori-lab/otori-cli/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of a setting, from the strongest to the weakest
+const (
+	SettingFromFlag    = "flag"
+	SettingFromEnv     = "env"
+	SettingFromFile    = "file"
+	SettingFromDefault = "default"
+)
+
+// SourceSettings is the provenance of the values coming from the global settings (see ResolveConfig)
+const SourceSettings = "settings"
+
+// Settings are the global defaults of otori, written in ~/.otori/config.yaml
+type Settings struct {
+	DefaultProfile string        `yaml:"defaultProfile,omitempty"`
+	Runtime        string        `yaml:"runtime,omitempty"`
+	Image          string        `yaml:"image,omitempty"`
+	PortRange      string        `yaml:"portRange,omitempty"`
+	Output         string        `yaml:"output,omitempty"`
+	Color          string        `yaml:"color,omitempty"`
+	Sinks          []models.Sink `yaml:"sinks,omitempty"`
+}
+
+// Setting is the effective value of a setting and where it comes from
+type Setting struct {
+	Key    string `json:"key" yaml:"key"`
+	Value  string `json:"value" yaml:"value"`
+	Source string `json:"source" yaml:"source"`
+}
+
+// settingDef describes a setting: its default, how to check it and its field in config.yaml
+type settingDef struct {
+	def      string
+	validate func(string) error
+	get      func(*Settings) string
+	set      func(*Settings, string)
+}
+
+// settingDefs are the known settings, by key
+var settingDefs = map[string]settingDef{
+	"defaultProfile": {
+		def:      "default",
+		validate: validateProfileSetting,
+		get:      func(s *Settings) string { return s.DefaultProfile },
+		set:      func(s *Settings, v string) { s.DefaultProfile = v },
+	},
+	"runtime": {
+		def:      "docker",
+		validate: oneOf("docker", "podman"),
+		get:      func(s *Settings) string { return s.Runtime },
+		set:      func(s *Settings, v string) { s.Runtime = v },
+	},
+	"image": {
+		def:      "cowrie/cowrie:latest",
+		validate: validateImageSetting,
+		get:      func(s *Settings) string { return s.Image },
+		set:      func(s *Settings, v string) { s.Image = v },
+	},
+	"portRange": {
+		def:      "",
+		validate: validatePortRange,
+		get:      func(s *Settings) string { return s.PortRange },
+		set:      func(s *Settings, v string) { s.PortRange = v },
+	},
+	"output": {
+		def:      "table",
+		validate: oneOf("table", "json", "yaml"),
+		get:      func(s *Settings) string { return s.Output },
+		set:      func(s *Settings, v string) { s.Output = v },
+	},
+	"color": {
+		def:      "auto",
+		validate: oneOf("auto", "always", "never"),
+		get:      func(s *Settings) string { return s.Color },
+		set:      func(s *Settings, v string) { s.Color = v },
+	},
+	"sinks": {
+		def:      "",
+		validate: validateSinksSetting,
+		get: func(s *Settings) string {
+			if len(s.Sinks) == 0 {
+				return ""
+			}
+			data, _ := json.Marshal(s.Sinks)
+			return string(data)
+		},
+		set: func(s *Settings, v string) {
+			s.Sinks, _ = parseSinks(v)
+		},
+	},
+}
+
+// settingFlags are the values given on the command line (see SetSettingFlag)
+var settingFlags = make(map[string]string)
+
+// SettingsPath returns the path of the global settings file (~/.otori/config.yaml)
+func SettingsPath() string {
+	return filepath.Join(GetOtoriDir(), "config.yaml")
+}
+
+// SettingKeys returns the keys of the known settings, sorted
+func SettingKeys() []string {
+	keys := make([]string, 0, len(settingDefs))
+	for key := range settingDefs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SettingEnv returns the environment variable of a setting (OTORI_DEFAULT_PROFILE for defaultProfile)
+func SettingEnv(key string) string {
+	var b strings.Builder
+	b.WriteString("OTORI_")
+	for i, r := range key {
+		if r >= 'A' && r <= 'Z' && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteString(strings.ToUpper(string(r)))
+	}
+	return b.String()
+}
+
+// ReadSettings reads the global settings file (empty settings if it doesn't exist)
+func ReadSettings() (*Settings, error) {
+	settings := &Settings{}
+	data, err := os.ReadFile(SettingsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+		return nil, fmt.Errorf("error reading settings: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(settings); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error decoding %s: %w", SettingsPath(), err)
+	}
+
+	for _, key := range SettingKeys() {
+		def := settingDefs[key]
+		if value := def.get(settings); value != "" {
+			if err := def.validate(value); err != nil {
+				return nil, fmt.Errorf("invalid %s in %s: %w", key, SettingsPath(), err)
+			}
+		}
+	}
+	return settings, nil
+}
+
+// SetSettingFlag sets the value of a setting given on the command line
+func SetSettingFlag(key, value string) error {
+	def, ok := settingDefs[key]
+	if !ok {
+		return unknownSetting(key)
+	}
+	if err := def.validate(value); err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	settingFlags[key] = value
+	return nil
+}
+
+// ResetSettingFlags forgets the values given on the command line
+func ResetSettingFlags() {
+	settingFlags = make(map[string]string)
+}
+
+// GetSetting returns the effective value of a setting: flag > environment > config.yaml > default
+func GetSetting(key string) (Setting, error) {
+	def, ok := settingDefs[key]
+	if !ok {
+		return Setting{}, unknownSetting(key)
+	}
+
+	if value, ok := settingFlags[key]; ok {
+		return Setting{Key: key, Value: value, Source: SettingFromFlag}, nil
+	}
+	if value := os.Getenv(SettingEnv(key)); value != "" {
+		if err := def.validate(value); err != nil {
+			return Setting{}, fmt.Errorf("invalid %s: %w", SettingEnv(key), err)
+		}
+		return Setting{Key: key, Value: value, Source: SettingFromEnv}, nil
+	}
+
+	settings, err := ReadSettings()
+	if err != nil {
+		return Setting{}, err
+	}
+	if value := def.get(settings); value != "" {
+		return Setting{Key: key, Value: value, Source: SettingFromFile}, nil
+	}
+	return Setting{Key: key, Value: def.def, Source: SettingFromDefault}, nil
+}
+
+// ListSettings returns the effective value of every setting
+func ListSettings() ([]Setting, error) {
+	var settings []Setting
+	for _, key := range SettingKeys() {
+		setting, err := GetSetting(key)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+// SetSetting writes a setting in config.yaml (an empty value restores the default)
+func SetSetting(key, value string) error {
+	def, ok := settingDefs[key]
+	if !ok {
+		return unknownSetting(key)
+	}
+	if value != "" {
+		if err := def.validate(value); err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	settings, err := ReadSettings()
+	if err != nil {
+		return err
+	}
+	def.set(settings, value)
+
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("error encoding settings: %w", err)
+	}
+	if err := os.MkdirAll(GetOtoriDir(), 0755); err != nil {
+		return fmt.Errorf("error creating otori directory: %w", err)
+	}
+	tmp := SettingsPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing settings: %w", err)
+	}
+	if err := os.Rename(tmp, SettingsPath()); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing settings: %w", err)
+	}
+	return nil
+}
+
+// settingValue returns the effective value of a setting, or its default if the settings can't be read
+func settingValue(key string) string {
+	setting, err := GetSetting(key)
+	if err != nil {
+		return settingDefs[key].def
+	}
+	return setting.Value
+}
+
+// DefaultProfile returns the profile used when a command gets no -p flag
+func DefaultProfile() string {
+	return settingValue("defaultProfile")
+}
+
+// ContainerRuntime returns the container CLI used by otori (docker or podman)
+func ContainerRuntime() string {
+	return settingValue("runtime")
+}
+
+// CowrieImage returns the image of the honeypot containers
+func CowrieImage() string {
+	return settingValue("image")
+}
+
+// ProfileImage returns the image reference of a resolved profile: its image
+// field (set by the profile or a template), then the image setting
+func ProfileImage(config *models.Config) string {
+	if config.Image != "" {
+		return config.Image
+	}
+	return CowrieImage()
+}
+
+// OutputFormat returns the default output format of the commands (table, json or yaml)
+func OutputFormat() string {
+	return settingValue("output")
+}
+
+// ColorMode returns when the output is coloured (auto, always or never)
+func ColorMode() string {
+	return settingValue("color")
+}
+
+// PortRange returns the host ports given to new profiles (ok is false when no range is set)
+func PortRange() (first, last int, ok bool) {
+	value := settingValue("portRange")
+	if value == "" {
+		return 0, 0, false
+	}
+	first, last, err := parsePortRange(value)
+	return first, last, err == nil
+}
+
+// DefaultSinks returns the Cowrie outputs added to every profile
+func DefaultSinks() ([]models.Sink, error) {
+	setting, err := GetSetting("sinks")
+	if err != nil || setting.Value == "" {
+		return nil, err
+	}
+	return parseSinks(setting.Value)
+}
+
+// AllocatePorts gives a profile without ports the first free SSH/Telnet pair of the
+// port range setting. Ports used by the other profiles are skipped.
+func AllocatePorts(config *models.Config) error {
+	first, last, ok := PortRange()
+	if !ok || config.Ports != nil {
+		return nil
+	}
+
+	used := make(map[int]bool)
+	names, err := ListConfigs()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == config.ProfileName {
+			continue
+		}
+		other, err := ReadEffectiveConfig(name)
+		if err != nil {
+			continue
+		}
+		used[other.SSHPort()] = true
+		used[other.TelnetPort()] = true
+	}
+
+	for port := first; port < last; port++ {
+		if !used[port] && !used[port+1] {
+			config.Ports = &models.Ports{SSH: port, Telnet: port + 1}
+			return nil
+		}
+	}
+	return fmt.Errorf("no free ports left in the range %d-%d", first, last)
+}
+
+// unknownSetting returns the error of an unknown setting key
+func unknownSetting(key string) error {
+	return fmt.Errorf("unknown setting '%s' (known settings: %s)", key, strings.Join(SettingKeys(), ", "))
+}
+
+// oneOf returns a validator accepting the given values
+func oneOf(values ...string) func(string) error {
+	return func(value string) error {
+		for _, v := range values {
+			if value == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("'%s' is not one of %s", value, strings.Join(values, ", "))
+	}
+}
+
+// validateProfileSetting checks a profile name setting
+func validateProfileSetting(value string) error {
+	if !IsValidProfileName(value) {
+		return fmt.Errorf("invalid profile name '%s'", value)
+	}
+	return nil
+}
+
+// validateImageSetting checks an image reference (repository[:tag][@sha256:<digest>][@id=sha256:<image ID>])
+func validateImageSetting(value string) error {
+	_, err := container.ParseImageRef(value)
+	return err
+}
+
+// validatePortRange checks a "min-max" port range
+func validatePortRange(value string) error {
+	_, _, err := parsePortRange(value)
+	return err
+}
+
+// parsePortRange parses a "min-max" range of at least two ports
+func parsePortRange(value string) (int, int, error) {
+	lo, hi, ok := strings.Cut(value, "-")
+	first, err1 := strconv.Atoi(strings.TrimSpace(lo))
+	last, err2 := strconv.Atoi(strings.TrimSpace(hi))
+	if !ok || err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("'%s' is not a port range (e.g. 2222-2299)", value)
+	}
+	if first < 1 || last > 65535 || first >= last {
+		return 0, 0, fmt.Errorf("port range '%s' must hold at least two ports between 1 and 65535", value)
+	}
+	return first, last, nil
+}
+
+// validateSinksSetting checks a sinks setting (JSON or YAML list of sinks)
+func validateSinksSetting(value string) error {
+	_, err := parseSinks(value)
+	return err
+}
+
+// parseSinks parses a list of sinks written in JSON or YAML
+func parseSinks(value string) ([]models.Sink, error) {
+	var sinks []models.Sink
+	if err := yaml.Unmarshal([]byte(value), &sinks); err != nil {
+		return nil, fmt.Errorf("sinks must be a list of {type, options}: %w", err)
+	}
+	for _, sink := range sinks {
+		if !IsValidProfileName(sink.Type) {
+			return nil, fmt.Errorf("invalid sink type '%s'", sink.Type)
+		}
+	}
+	return sinks, nil
+}
@@ -0,0 +1,137 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/otori-lab/otori-cli/internal/testutil"
+)
+
+func TestSettingPrecedence(t *testing.T) {
+	testutil.OtoriHome(t)
+	t.Cleanup(config.ResetSettingFlags)
+
+	check := func(value, source string) {
+		t.Helper()
+		setting, err := config.GetSetting("runtime")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if setting.Value != value || setting.Source != source {
+			t.Errorf("runtime = %s (%s), want %s (%s)", setting.Value, setting.Source, value, source)
+		}
+	}
+
+	check("docker", config.SettingFromDefault)
+
+	if err := config.SetSetting("runtime", "podman"); err != nil {
+		t.Fatal(err)
+	}
+	check("podman", config.SettingFromFile)
+
+	t.Setenv("OTORI_RUNTIME", "docker")
+	check("docker", config.SettingFromEnv)
+
+	if err := config.SetSettingFlag("runtime", "podman"); err != nil {
+		t.Fatal(err)
+	}
+	check("podman", config.SettingFromFlag)
+
+	// An empty value removes the setting from the file
+	config.ResetSettingFlags()
+	os.Unsetenv("OTORI_RUNTIME")
+	if err := config.SetSetting("runtime", ""); err != nil {
+		t.Fatal(err)
+	}
+	check("docker", config.SettingFromDefault)
+}
+
+func TestSettingErrors(t *testing.T) {
+	home := testutil.OtoriHome(t)
+
+	if err := config.SetSetting("colour", "never"); err == nil {
+		t.Error("unknown key accepted")
+	}
+	for key, value := range map[string]string{
+		"runtime":        "lxc",
+		"output":         "xml",
+		"portRange":      "3000",
+		"image":          "cowrie@sha256:1234",
+		"defaultProfile": "../etc",
+		"sinks":          "[{type: ''}]",
+	} {
+		if err := config.SetSetting(key, value); err == nil {
+			t.Errorf("%s=%q accepted", key, value)
+		}
+	}
+
+	t.Setenv("OTORI_COLOR", "rainbow")
+	if _, err := config.GetSetting("color"); err == nil {
+		t.Error("invalid OTORI_COLOR accepted")
+	}
+
+	// A broken config.yaml is reported, not ignored
+	if err := os.WriteFile(filepath.Join(home, "config.yaml"), []byte("runtme: podman\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.ListSettings(); err == nil {
+		t.Error("unknown key of config.yaml accepted")
+	}
+}
+
+func TestOtoriHomeFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("OTORI_HOME", dir)
+
+	if got := config.GetOtoriDir(); got != dir {
+		t.Errorf("GetOtoriDir() = %s, want %s", got, dir)
+	}
+	if got := config.GetConfigDir(); got != filepath.Join(dir, "profiles") {
+		t.Errorf("GetConfigDir() = %s", got)
+	}
+
+	// --home wins over OTORI_HOME
+	config.SetOtoriDir(filepath.Join(dir, "flag"))
+	t.Cleanup(func() { config.SetOtoriDir("") })
+	if got := config.GetOtoriDir(); got != filepath.Join(dir, "flag") {
+		t.Errorf("GetOtoriDir() = %s, want the --home directory", got)
+	}
+}
+
+func TestAllocatePorts(t *testing.T) {
+	testutil.OtoriHome(t)
+	if err := config.SetSetting("portRange", "3000-3003"); err != nil {
+		t.Fatal(err)
+	}
+
+	var allocated [][2]int
+	for _, name := range []string{"first", "second"} {
+		cfg := models.NewConfig()
+		cfg.Type = "classic"
+		cfg.ServerName = "srv-" + name
+		cfg.ProfileName = name
+		if err := config.AllocatePorts(cfg); err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Ports == nil {
+			t.Fatalf("no ports given to %s", name)
+		}
+		allocated = append(allocated, [2]int{cfg.SSHPort(), cfg.TelnetPort()})
+		if err := config.WriteConfig(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if allocated[0] != [2]int{3000, 3001} || allocated[1] != [2]int{3002, 3003} {
+		t.Errorf("allocated ports %v", allocated)
+	}
+
+	// The range is full
+	cfg := models.NewConfig()
+	cfg.ProfileName = "third"
+	if err := config.AllocatePorts(cfg); err == nil {
+		t.Errorf("ports %v given out of a full range", cfg.Ports)
+	}
+}
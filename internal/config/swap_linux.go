@@ -0,0 +1,11 @@
+//go:build linux
+
+package config
+
+import "golang.org/x/sys/unix"
+
+// exchangeDirs swaps two existing directories in a single rename (RENAME_EXCHANGE),
+// so that a crash leaves one of them or the other in place, never none
+func exchangeDirs(a, b string) error {
+	return unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package config
+
+import "errors"
+
+// exchangeDirs is not supported outside Linux: installProfileDir moves the
+// current directory aside instead
+func exchangeDirs(a, b string) error {
+	return errors.ErrUnsupported
+}
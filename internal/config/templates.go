@@ -3,10 +3,15 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/otori-lab/otori-cli/internal/container"
 	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/otori-lab/otori-cli/internal/version"
 )
 
 // CowrieConfigTemplate is the template for cowrie.cfg
@@ -59,6 +64,8 @@ const UserDBHeader = `# Cowrie User Database
 // WriteCowrieConfig generates and writes cowrie.cfg for a profile
 func WriteCowrieConfig(profileDir string, config *models.Config) error {
 	content := fmt.Sprintf(CowrieConfigTemplate, config.ProfileName, config.ServerName)
+	content = applyCowrieOverrides(content, personaSettings(config))
+	content = applyCowrieOverrides(content, config.CowrieOverrides)
 
 	filename := filepath.Join(profileDir, "cowrie.cfg")
 	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
@@ -68,6 +75,129 @@ func WriteCowrieConfig(profileDir string, config *models.Config) error {
 	return nil
 }
 
+// personaSettings returns the cowrie.cfg keys derived from the persona and sinks.
+// Explicit cowrieOverrides are applied afterwards and take precedence.
+func personaSettings(config *models.Config) map[string]map[string]string {
+	settings := make(map[string]map[string]string)
+	set := func(section, key, value string) {
+		if value == "" {
+			return
+		}
+		if settings[section] == nil {
+			settings[section] = make(map[string]string)
+		}
+		settings[section][key] = value
+	}
+
+	if p := config.Persona; p != nil {
+		set("shell", "operating_system", p.OperatingSystem)
+		set("shell", "kernel_version", p.KernelVersion)
+		set("shell", "kernel_build_string", p.KernelBuild)
+		set("shell", "hardware_platform", p.Hardware)
+		set("ssh", "version", p.SSHVersion)
+	}
+
+	for _, sink := range config.Sinks {
+		section := "output_" + sink.Type
+		set(section, "enabled", "true")
+		for key, value := range sink.Options {
+			set(section, key, value)
+		}
+	}
+
+	return settings
+}
+
+// applyCowrieOverrides sets the overridden keys in a cowrie.cfg content.
+// Existing keys are replaced in place, missing keys and sections are appended.
+func applyCowrieOverrides(content string, overrides map[string]map[string]string) string {
+	if len(overrides) == 0 {
+		return content
+	}
+
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	applied := make(map[string]map[string]bool)
+
+	// sectionEnd returns the index after the last non-empty line of a section
+	sectionEnd := func(start int) int {
+		end := start + 1
+		for i := start + 1; i < len(lines); i++ {
+			trimmed := strings.TrimSpace(lines[i])
+			if strings.HasPrefix(trimmed, "[") {
+				break
+			}
+			if trimmed != "" {
+				end = i + 1
+			}
+		}
+		return end
+	}
+
+	// Replace existing keys
+	section := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = trimmed[1 : len(trimmed)-1]
+			continue
+		}
+		key, _, found := strings.Cut(trimmed, "=")
+		if !found || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if value, ok := overrides[section][key]; ok {
+			lines[i] = key + " = " + value
+			if applied[section] == nil {
+				applied[section] = make(map[string]bool)
+			}
+			applied[section][key] = true
+		}
+	}
+
+	// Append missing keys, in a stable order
+	sections := make([]string, 0, len(overrides))
+	for name := range overrides {
+		sections = append(sections, name)
+	}
+	sort.Strings(sections)
+
+	for _, name := range sections {
+		var missing []string
+		for key := range overrides[name] {
+			if !applied[name][key] {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		sort.Strings(missing)
+
+		var added []string
+		for _, key := range missing {
+			added = append(added, key+" = "+overrides[name][key])
+		}
+
+		start := -1
+		for i, line := range lines {
+			if strings.TrimSpace(line) == "["+name+"]" {
+				start = i
+				break
+			}
+		}
+		if start == -1 {
+			lines = append(lines, "", "["+name+"]")
+			lines = append(lines, added...)
+			continue
+		}
+		end := sectionEnd(start)
+		lines = append(lines[:end], append(added, lines[end:]...)...)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
 // WriteUserDB generates and writes userdb.txt for a profile
 func WriteUserDB(profileDir string, config *models.Config) error {
 	var content strings.Builder
@@ -76,8 +206,14 @@ func WriteUserDB(profileDir string, config *models.Config) error {
 	// Add users from config
 	if len(config.Users) > 0 {
 		for _, user := range config.Users {
-			// Default: allow user with any password (wildcard)
-			content.WriteString(fmt.Sprintf("%s:x:*\n", user))
+			passwords := config.Passwords[user]
+			if len(passwords) == 0 {
+				// Default: allow user with any password (wildcard)
+				passwords = []string{"*"}
+			}
+			for _, password := range passwords {
+				content.WriteString(fmt.Sprintf("%s:x:%s\n", user, password))
+			}
 		}
 	} else {
 		// Default users if none specified
@@ -100,17 +236,19 @@ const DockerComposeTemplate = `# Docker Compose for Cowrie Honeypot
 
 services:
   cowrie:
-    image: cowrie/cowrie:latest
+    image: %s
     container_name: otori-%s
     restart: unless-stopped
+    labels:
+      otori.profile: "%s"
+      otori.config-hash: "%s"
+      otori.version: "%s"
+      otori.image: "%s"
     ports:
-      - "2222:2222"   # SSH
-      - "2223:2223"   # Telnet
+      - "%d:2222"   # SSH
+      - "%d:2223"   # Telnet
     volumes:
-      - ./cowrie.cfg:/cowrie/cowrie-git/etc/cowrie.cfg:ro
-      - ./userdb.txt:/cowrie/cowrie-git/etc/userdb.txt:ro
-      - ./honeyfs:/cowrie/cowrie-git/honeyfs:ro
-      - cowrie-logs:/cowrie/cowrie-git/var/log/cowrie
+%s      - cowrie-logs:/cowrie/cowrie-git/var/log/cowrie
       - cowrie-downloads:/cowrie/cowrie-git/var/lib/cowrie/downloads
     environment:
       - COWRIE_HOSTNAME=%s
@@ -122,11 +260,50 @@ volumes:
     name: otori-%s-downloads
 `
 
-// WriteDockerCompose generates and writes docker-compose.yml for a profile
+// profileMounts are the bind mounts of the profile files in docker-compose.yml
+const profileMounts = `      - ./cowrie.cfg:/cowrie/cowrie-git/etc/cowrie.cfg:ro
+      - ./userdb.txt:/cowrie/cowrie-git/etc/userdb.txt:ro
+      - ./honeyfs:/cowrie/cowrie-git/honeyfs:ro
+      - ./txtcmds:/cowrie/cowrie-git/txtcmds:ro
+`
+
+// DeployImage returns the image run by the honeypot of a profile: its local
+// repository:tag name and its full reference (digest included). A baked
+// profile runs the image built by "otori build" (see BakedImage).
+func DeployImage(config *models.Config) (name, reference string) {
+	if config.Baked {
+		image := BakedImage(config.ProfileName)
+		return image, image
+	}
+	reference = ProfileImage(config)
+	if ref, err := container.ParseImageRef(reference); err == nil {
+		return ref.Tagged(), reference
+	}
+	return reference, reference
+}
+
+// WriteDockerCompose generates and writes docker-compose.yml for a profile.
+// The compose file names the image by repository:tag, a pinned digest is kept
+// in the otori.image label and checked by deploy. A baked profile runs the
+// image built by "otori build" and mounts none of its files.
 func WriteDockerCompose(profileDir string, config *models.Config) error {
+	composeImage, image := DeployImage(config)
+	mounts := profileMounts
+	if config.Baked {
+		mounts = ""
+	}
+
 	content := fmt.Sprintf(DockerComposeTemplate,
 		config.ProfileName,
+		composeImage,
 		config.ProfileName,
+		config.ProfileName,
+		ConfigHash(config),
+		version.Version,
+		image,
+		config.SSHPort(),
+		config.TelnetPort(),
+		mounts,
 		config.ServerName,
 		config.ProfileName,
 		config.ProfileName,
@@ -140,13 +317,38 @@ func WriteDockerCompose(profileDir string, config *models.Config) error {
 	return nil
 }
 
-// GetOtoriDir returns the otori config directory (~/.otori)
-func GetOtoriDir() string {
+// otoriDir overrides the otori config directory when set (see SetOtoriDir)
+var otoriDir string
+
+// SetOtoriDir makes otori use another config directory than ~/.otori
+// (--home flag; an empty dir restores the default)
+func SetOtoriDir(dir string) {
+	otoriDir = dir
+}
+
+// ResolveOtoriDir returns the otori config directory: --home flag, then the
+// OTORI_HOME environment variable, then ~/.otori
+func ResolveOtoriDir() (string, error) {
+	if otoriDir != "" {
+		return otoriDir, nil
+	}
+	if dir := os.Getenv("OTORI_HOME"); dir != "" {
+		return filepath.Abs(dir)
+	}
 	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("no home directory to store otori files, use --home or OTORI_HOME: %w", err)
+	}
+	return filepath.Join(homeDir, ".otori"), nil
+}
+
+// GetOtoriDir returns the otori config directory (~/.otori, see ResolveOtoriDir)
+func GetOtoriDir() string {
+	dir, err := ResolveOtoriDir()
 	if err != nil {
 		return ".otori"
 	}
-	return filepath.Join(homeDir, ".otori")
+	return dir
 }
 
 // GetBaseHoneyFSDir returns the base honeyfs directory (~/.otori/cowrie-honeyfs-base)
@@ -154,9 +356,12 @@ func GetBaseHoneyFSDir() string {
 	return filepath.Join(GetOtoriDir(), "cowrie-honeyfs-base")
 }
 
-// WriteHoneyFS copies the base honeyfs and adds custom users
+// WriteHoneyFS renders the honeyfs of a profile: base honeyfs, template overlays,
+// custom users, hostname, network and services of the persona, company branding
+// and bait files. Rendering starts from the base every time,
+// so it is idempotent; files of honeyfs.d/ override the generated ones and files
+// changed by hand in honeyfs/ are kept (see syncGeneratedDir).
 func WriteHoneyFS(profileDir string, config *models.Config) error {
-	honeyfsDir := filepath.Join(profileDir, "honeyfs")
 	baseHoneyFS := GetBaseHoneyFSDir()
 
 	// Check if base honeyfs exists
@@ -164,59 +369,67 @@ func WriteHoneyFS(profileDir string, config *models.Config) error {
 		return fmt.Errorf("base honeyfs not found at %s (run 'make install' first)", baseHoneyFS)
 	}
 
-	// Copy base honeyfs to profile
-	if err := copyDir(baseHoneyFS, honeyfsDir); err != nil {
-		return fmt.Errorf("error copying base honeyfs: %w", err)
+	tree := make(fileTree)
+	if err := tree.loadDir(baseHoneyFS); err != nil {
+		return fmt.Errorf("error reading base honeyfs: %w", err)
 	}
 
-	// Add custom users to passwd and shadow
-	users := config.Users
-	if len(users) == 0 {
-		users = []string{"root", "admin"}
-	}
-
-	// Append custom users to passwd
-	passwdPath := filepath.Join(honeyfsDir, "etc", "passwd")
-	if err := appendUsersToPasswd(passwdPath, users); err != nil {
-		return fmt.Errorf("error updating passwd: %w", err)
+	// Apply the honeyfs overlays of inherited templates (root template first)
+	overlays, err := templateOverlays(config, "honeyfs")
+	if err != nil {
+		return err
 	}
-
-	// Append custom users to shadow
-	shadowPath := filepath.Join(honeyfsDir, "etc", "shadow")
-	if err := appendUsersToShadow(shadowPath, users); err != nil {
-		return fmt.Errorf("error updating shadow: %w", err)
+	for _, overlay := range overlays {
+		if err := tree.loadDir(overlay); err != nil {
+			return fmt.Errorf("error reading template honeyfs: %w", err)
+		}
 	}
 
-	// Append custom users to group
-	groupPath := filepath.Join(honeyfsDir, "etc", "group")
-	if err := appendUsersToGroup(groupPath, users); err != nil {
-		return fmt.Errorf("error updating group: %w", err)
+	// Add custom users to passwd, shadow and group
+	users := config.Users
+	if len(users) == 0 {
+		users = []string{"root", "admin"}
 	}
+	appendServiceAccounts(tree, config)
+	accounts := profileAccounts(tree, users)
+	appendUsersToPasswd(tree, "etc/passwd", accounts, config)
+	appendUsersToShadow(tree, "etc/shadow", accounts)
+	appendUsersToGroup(tree, "etc/group", accounts)
 
 	// Update hostname
-	hostnamePath := filepath.Join(honeyfsDir, "etc", "hostname")
 	hostname := config.ServerName
 	if hostname == "" {
 		hostname = "svr04"
 	}
-	if err := os.WriteFile(hostnamePath, []byte(hostname+"\n"), 0644); err != nil {
-		return fmt.Errorf("error updating hostname: %w", err)
-	}
+	tree.set("etc/hostname", []byte(hostname+"\n"))
 
-	// Create custom bait file
-	shareDir := filepath.Join(honeyfsDir, "etc", "share")
-	if err := os.MkdirAll(shareDir, 0755); err != nil {
-		return fmt.Errorf("error creating share directory: %w", err)
-	}
-	secretContent := `# Confidential - Do Not Share
+	// Network and services of the persona
+	writePersonaNetwork(tree, config)
+	writeServiceConfigs(tree, config)
+	writeCompanyFiles(tree, config)
+
+	if len(config.BaitFiles) > 0 {
+		// Bait files of the profile replace the default one
+		for _, bait := range config.BaitFiles {
+			tree.set(strings.TrimPrefix(path.Clean(bait.Path), "/"), []byte(bait.Content))
+		}
+	} else if config.Company != "" {
+		// Bait files branded by the company
+		for _, bait := range companyBaitFiles(config) {
+			tree.set(strings.TrimPrefix(bait.Path, "/"), []byte(bait.Content))
+		}
+	} else {
+		// Create custom bait file
+		tree.set("etc/share/secret.txt", []byte(`# Confidential - Do Not Share
 DB_HOST=192.168.1.100
 DB_USER=admin
 DB_PASS=SuperSecret123!
-`
-	if err := os.WriteFile(filepath.Join(shareDir, "secret.txt"), []byte(secretContent), 0644); err != nil {
-		return fmt.Errorf("error writing secret.txt: %w", err)
+`))
 	}
 
+	if _, err := syncGeneratedDir(profileDir, "honeyfs", tree); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -247,66 +460,91 @@ func copyDir(src, dst string) error {
 	})
 }
 
-// appendUsersToPasswd adds custom users to passwd file
-func appendUsersToPasswd(path string, users []string) error {
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
+// appendLines appends lines to a file of the tree (created if missing)
+func appendLines(tree fileTree, p string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	f, ok := tree[p]
+	if !ok {
+		f = treeFile{Mode: 0644}
 	}
-	defer f.Close()
+	data := append([]byte{}, f.Data...)
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+	data = append(data, strings.Join(lines, "\n")+"\n"...)
+	tree[p] = treeFile{Data: data, Mode: f.Mode}
+}
 
-	uid := 1000
-	for _, user := range users {
-		if user != "root" {
-			line := fmt.Sprintf("%s:x:%d:%d:%s:/home/%s:/bin/bash\n",
-				user, uid, uid, capitalize(user), user)
-			if _, err := f.WriteString(line); err != nil {
-				return err
+// userAccount is a profile user added to passwd, shadow and group (uid = gid)
+type userAccount struct {
+	Name string
+	ID   int
+}
+
+// profileAccounts assigns ids to the profile users. Users are sorted so ids do not
+// depend on their order in the profile, and numbered after the last regular account
+// of the base passwd and group. Root and accounts that already exist are skipped.
+func profileAccounts(tree fileTree, users []string) []userAccount {
+	existing := make(map[string]bool)
+	next := 1000
+	for _, p := range []string{"etc/passwd", "etc/group"} {
+		for _, line := range strings.Split(string(tree[p].Data), "\n") {
+			fields := strings.Split(line, ":")
+			if len(fields) < 3 {
+				continue
+			}
+			if p == "etc/passwd" {
+				existing[fields[0]] = true
+			}
+			if id, err := strconv.Atoi(fields[2]); err == nil && id >= next && id < 60000 {
+				next = id + 1
 			}
-			uid++
 		}
 	}
-	return nil
-}
 
-// appendUsersToShadow adds custom users to shadow file
-func appendUsersToShadow(path string, users []string) error {
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+	sorted := append([]string{}, users...)
+	sort.Strings(sorted)
 
-	for _, user := range users {
-		if user != "root" {
-			line := fmt.Sprintf("%s:*:15800:0:99999:7:::\n", user)
-			if _, err := f.WriteString(line); err != nil {
-				return err
-			}
+	var accounts []userAccount
+	for _, user := range sorted {
+		if user == "root" || existing[user] {
+			continue
 		}
+		existing[user] = true
+		accounts = append(accounts, userAccount{Name: user, ID: next})
+		next++
 	}
-	return nil
+	return accounts
 }
 
-// appendUsersToGroup adds custom user groups to group file
-func appendUsersToGroup(path string, users []string) error {
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
+// appendUsersToPasswd adds custom users to passwd file
+func appendUsersToPasswd(tree fileTree, p string, accounts []userAccount, config *models.Config) {
+	var lines []string
+	for _, account := range accounts {
+		lines = append(lines, fmt.Sprintf("%s:x:%d:%d:%s:/home/%s:/bin/bash",
+			account.Name, account.ID, account.ID, userGecos(config, account.Name), account.Name))
 	}
-	defer f.Close()
+	appendLines(tree, p, lines)
+}
 
-	gid := 1000
-	for _, user := range users {
-		if user != "root" {
-			line := fmt.Sprintf("%s:x:%d:\n", user, gid)
-			if _, err := f.WriteString(line); err != nil {
-				return err
-			}
-			gid++
-		}
+// appendUsersToShadow adds custom users to shadow file
+func appendUsersToShadow(tree fileTree, p string, accounts []userAccount) {
+	var lines []string
+	for _, account := range accounts {
+		lines = append(lines, fmt.Sprintf("%s:*:15800:0:99999:7:::", account.Name))
 	}
-	return nil
+	appendLines(tree, p, lines)
+}
+
+// appendUsersToGroup adds custom user groups to group file
+func appendUsersToGroup(tree fileTree, p string, accounts []userAccount) {
+	var lines []string
+	for _, account := range accounts {
+		lines = append(lines, fmt.Sprintf("%s:x:%d:", account.Name, account.ID))
+	}
+	appendLines(tree, p, lines)
 }
 
 // capitalize returns the string with first letter capitalized
@@ -0,0 +1,268 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/otori-lab/otori-cli/internal/testutil"
+)
+
+// profiles are the configurations rendered by the golden tests
+var profiles = map[string]*models.Config{
+	// minimal is a profile created with "otori init -t classic -s srv01"
+	"minimal": {
+		SchemaVersion: models.CurrentSchemaVersion,
+		Type:          "classic",
+		ServerName:    "srv01",
+		ProfileName:   "minimal",
+		Users:         []string{},
+		Seed:          "5eed5eed5eed5eed",
+		CreatedAt:     "2025-03-02T10:00:00Z",
+	},
+	// persona uses every field of the profile
+	"persona": {
+		SchemaVersion: models.CurrentSchemaVersion,
+		Type:          "classic",
+		ServerName:    "web42",
+		ProfileName:   "persona",
+		Company:       "Société Générale & Co",
+		Users:         []string{"root", "zed", "alice"},
+		Passwords: map[string][]string{
+			"root":  {"123456", "toor"},
+			"alice": {"!"},
+		},
+		Persona: &models.Persona{
+			OperatingSystem: "GNU/Linux",
+			KernelVersion:   "5.15.0-91-generic",
+			KernelBuild:     "#101-Ubuntu SMP",
+			Hardware:        "x86_64",
+			SSHVersion:      "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6",
+			Services: []models.Service{
+				{Name: "nginx"},
+				{Name: "postgres", Port: 5433},
+				{Name: "docker", Port: 2375},
+			},
+			Neighbors: []models.Neighbor{
+				{Name: "db01", IP: "10.20.30.15"},
+				{Name: "backup"},
+			},
+		},
+		Ports: &models.Ports{SSH: 2022, Telnet: 2023},
+		Image: "registry.local:5000/cowrie:2.5.0@sha256:0b3c1f6a9d1e4b7a8c2d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b",
+		Sinks: []models.Sink{
+			{Type: "syslog", Options: map[string]string{"facility": "USER"}},
+		},
+		Commands: []models.Command{
+			{Path: "/usr/bin/uptime", Output: " 10:00:00 up 42 days,  1 user\n"},
+		},
+		CowrieOverrides: map[string]map[string]string{
+			"honeypot": {"timezone": "Europe/Paris"},
+		},
+		Seed:      "0123456789abcdef",
+		CreatedAt: "2025-03-02T10:00:00Z",
+	},
+}
+
+// render writes a file of a profile with fn and returns its content
+func render(t *testing.T, fn func(string, *models.Config) error, cfg *models.Config, file string) []byte {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := fn(dir, cfg); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestWriteCowrieConfig(t *testing.T) {
+	for name, cfg := range profiles {
+		t.Run(name, func(t *testing.T) {
+			testutil.Golden(t, name+"/cowrie.cfg", render(t, config.WriteCowrieConfig, cfg, "cowrie.cfg"))
+		})
+	}
+}
+
+func TestWriteUserDB(t *testing.T) {
+	for name, cfg := range profiles {
+		t.Run(name, func(t *testing.T) {
+			testutil.Golden(t, name+"/userdb.txt", render(t, config.WriteUserDB, cfg, "userdb.txt"))
+		})
+	}
+}
+
+func TestWriteDockerCompose(t *testing.T) {
+	for name, cfg := range profiles {
+		t.Run(name, func(t *testing.T) {
+			testutil.Golden(t, name+"/docker-compose.yml", render(t, config.WriteDockerCompose, cfg, "docker-compose.yml"))
+		})
+	}
+}
+
+func TestWriteDockerComposeBaked(t *testing.T) {
+	baked := *profiles["minimal"]
+	baked.Baked = true
+	compose := render(t, config.WriteDockerCompose, &baked, "docker-compose.yml")
+	testutil.Golden(t, "minimal/docker-compose.baked.yml", compose)
+
+	// deploy tags the build of the current files with this name
+	if !strings.Contains(string(compose), "image: "+config.BakedImage("minimal")+"\n") {
+		t.Errorf("docker-compose.yml does not use %s", config.BakedImage("minimal"))
+	}
+}
+
+func TestBuildImage(t *testing.T) {
+	testutil.OtoriHome(t)
+	profileDir := t.TempDir()
+	if err := config.RenderProfile(profileDir, profiles["minimal"]); err != nil {
+		t.Fatal(err)
+	}
+	const baseID = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+	// build returns the image name of the build context of the profile files
+	build := func(cfg *models.Config, baseID string) string {
+		t.Helper()
+		contextDir := t.TempDir()
+		if err := config.WriteBuildContext(contextDir, profileDir, cfg, []string{"touch /etc/motd"}); err != nil {
+			t.Fatal(err)
+		}
+		image, err := config.BuildImage(cfg.ProfileName, contextDir, baseID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return image
+	}
+
+	image := build(profiles["minimal"], baseID)
+	if !strings.HasPrefix(image, "otori/minimal:") || build(profiles["minimal"], baseID) != image {
+		t.Fatalf("image %s is not stable", image)
+	}
+	baked := *profiles["minimal"]
+	baked.Baked = true
+	if build(&baked, baseID) != image {
+		t.Error("baking a profile changes the name of its image")
+	}
+
+	// Anything changing the built image changes its name
+	if build(profiles["minimal"], "sha256:2222222222222222222222222222222222222222222222222222222222222222") == image {
+		t.Error("another base image gives the same name")
+	}
+	pinned := *profiles["minimal"]
+	pinned.Image = "cowrie/cowrie:2.5.0"
+	if build(&pinned, baseID) == image {
+		t.Error("another base image reference gives the same name")
+	}
+	for _, file := range []string{"honeyfs/etc/motd", "txtcmds/usr/bin/uptime"} {
+		path := filepath.Join(profileDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("edited by hand\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		changed := build(profiles["minimal"], baseID)
+		if changed == image {
+			t.Errorf("editing %s gives the same name", file)
+		}
+		image = changed
+	}
+}
+
+func TestWriteDockerfile(t *testing.T) {
+	for name, cfg := range profiles {
+		t.Run(name, func(t *testing.T) {
+			// Only the persona profile has entries to add to fs.pickle
+			write := func(dir string, cfg *models.Config) error {
+				return config.WriteDockerfile(dir, cfg, name == "persona")
+			}
+			testutil.Golden(t, name+"/Dockerfile", render(t, write, cfg, "Dockerfile"))
+		})
+	}
+
+	// The hostname is a quoted word, a space or a "$" cannot add an instruction
+	cfg := *profiles["minimal"]
+	cfg.ServerName = `srv 01 "$HOME" \`
+	dockerfile := render(t, func(dir string, cfg *models.Config) error { return config.WriteDockerfile(dir, cfg, false) }, &cfg, "Dockerfile")
+	if want := `ENV COWRIE_HOSTNAME="srv 01 \"\$HOME\" \\"` + "\n"; !strings.Contains(string(dockerfile), want) {
+		t.Errorf("Dockerfile without %q:\n%s", want, dockerfile)
+	}
+}
+
+func TestWriteHoneyFS(t *testing.T) {
+	testutil.OtoriHome(t)
+
+	for name, cfg := range profiles {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := config.WriteHoneyFS(dir, cfg); err != nil {
+				t.Fatal(err)
+			}
+			honeyfs := testutil.DumpDir(t, filepath.Join(dir, "honeyfs"))
+			testutil.Golden(t, name+"/honeyfs.txt", honeyfs)
+
+			// Rendering again gives the same tree
+			if err := config.WriteHoneyFS(dir, cfg); err != nil {
+				t.Fatal(err)
+			}
+			if again := testutil.DumpDir(t, filepath.Join(dir, "honeyfs")); string(again) != string(honeyfs) {
+				t.Error("second rendering differs from the first one")
+			}
+		})
+	}
+}
+
+func TestWriteHoneyFSWithoutBase(t *testing.T) {
+	config.SetOtoriDir(t.TempDir())
+	t.Cleanup(func() { config.SetOtoriDir("") })
+
+	if err := config.WriteHoneyFS(t.TempDir(), profiles["minimal"]); err == nil {
+		t.Fatal("expected an error when the base honeyfs is missing")
+	}
+}
+
+func TestWriteHoneyFSUnsafePaths(t *testing.T) {
+	testutil.OtoriHome(t)
+
+	for name, cfg := range map[string]*models.Config{
+		"company user": {Type: "classic", ServerName: "web02", ProfileName: "p2", Company: "Acme",
+			Users: []string{"../../../../../../../../tmp/pwnx"}},
+		"bait file": {Type: "classic", ServerName: "web02", ProfileName: "p2",
+			BaitFiles: []models.BaitFile{{Path: "../../escape.txt", Content: "escaped"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "a", "b", "profile")
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := config.WriteHoneyFS(dir, cfg); err == nil || !strings.Contains(err.Error(), "unsafe path") {
+				t.Errorf("error %v, want an unsafe path", err)
+			}
+			if _, err := os.Stat(filepath.Join(root, "a", "escape.txt")); err == nil {
+				t.Error("file written outside the profile")
+			}
+		})
+	}
+}
+
+func TestValidateUsers(t *testing.T) {
+	for _, user := range []string{"../../tmp/pwnx", "a/b", "a:b", "..", "a b", "a\tb", "a\x00b", "a\nb"} {
+		cfg := &models.Config{Type: "classic", ServerName: "web02", ProfileName: "p2", Users: []string{user}}
+		errs := config.ValidateConfig(cfg)
+		if len(errs) != 1 || errs[0].Field != "Users" {
+			t.Errorf("user %q: %v", user, errs)
+		}
+	}
+
+	cfg := &models.Config{Type: "classic", ServerName: "web02", ProfileName: "p2", Users: []string{"root", "j.doe", "svc-backup", "Admin_2"}}
+	if errs := config.ValidateConfig(cfg); len(errs) != 0 {
+		t.Errorf("valid users rejected: %v", errs)
+	}
+}
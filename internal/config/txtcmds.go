@@ -0,0 +1,471 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/models"
+)
+
+// txtcmds are the outputs of the commands Cowrie does not implement itself: it prints
+// txtcmds/<command path> whatever the arguments. Commands implemented in Python by
+// Cowrie (uname, ps, free, w...) take precedence over these files.
+
+// WriteTxtCmds renders the txtcmds directory of a profile from its rendered honeyfs
+// (/proc, /etc) and its configuration. Must run after WriteHoneyFS.
+// Layers: generated defaults, txtcmds/ of the templates, "commands" of the profile,
+// then txtcmds.d/ (see syncGeneratedDir).
+func WriteTxtCmds(profileDir string, config *models.Config) error {
+	honeyfs := make(fileTree)
+	if err := honeyfs.loadDir(filepath.Join(profileDir, "honeyfs")); err != nil {
+		return fmt.Errorf("error reading honeyfs: %w", err)
+	}
+
+	tree := defaultTxtCmds(config, honeyfs)
+
+	overlays, err := templateOverlays(config, "txtcmds")
+	if err != nil {
+		return err
+	}
+	for _, overlay := range overlays {
+		if err := tree.loadDir(overlay); err != nil {
+			return fmt.Errorf("error reading template txtcmds: %w", err)
+		}
+	}
+
+	for _, command := range config.Commands {
+		output := command.Output
+		if output != "" && !strings.HasSuffix(output, "\n") {
+			output += "\n"
+		}
+		tree.set(strings.TrimPrefix(path.Clean(command.Path), "/"), []byte(output))
+	}
+
+	if _, err := syncGeneratedDir(profileDir, "txtcmds", tree); err != nil {
+		return err
+	}
+	return nil
+}
+
+// TxtCmdPaths returns the commands of a rendered txtcmds directory, as absolute paths
+func TxtCmdPaths(profileDir string) ([]string, error) {
+	tree := make(fileTree)
+	if err := tree.loadDir(filepath.Join(profileDir, "txtcmds")); err != nil {
+		return nil, err
+	}
+	paths := sortedPaths(tree)
+	for i, p := range paths {
+		paths[i] = "/" + p
+	}
+	return paths, nil
+}
+
+// defaultTxtCmds generates the default command outputs, consistent with the honeyfs
+func defaultTxtCmds(config *models.Config, honeyfs fileTree) fileTree {
+	cpus := parseCPUInfo(string(honeyfs["proc/cpuinfo"].Data))
+	mounts := parseMounts(string(honeyfs["proc/mounts"].Data))
+	memTotal := meminfoValue(string(honeyfs["proc/meminfo"].Data), "MemTotal")
+
+	tree := make(fileTree)
+	tree.set("usr/bin/nproc", []byte(strconv.Itoa(max(len(cpus), 1))+"\n"))
+	tree.set("usr/bin/lscpu", []byte(renderLscpu(config, cpus)))
+	tree.set("bin/mount", []byte(renderMount(mounts)))
+	tree.set("bin/df", []byte(renderDf(config, mounts, memTotal)))
+	tree.set("usr/bin/hostnamectl", []byte(renderHostnamectl(config, honeyfs)))
+	tree.set("usr/bin/lastlog", []byte(renderLastlog(config, string(honeyfs["etc/passwd"].Data))))
+	tree.set("bin/dmesg", []byte(renderDmesg(config, honeyfs, cpus, mounts, memTotal)))
+	tree.set("bin/ps", []byte(renderPs(config, memTotal)))
+	tree.set("bin/netstat", []byte(renderNetstat(config)))
+	tree.set("bin/ss", []byte(renderSs(config)))
+	tree.set("bin/systemctl", []byte(renderSystemctl(config)))
+	return tree
+}
+
+// parseCPUInfo splits /proc/cpuinfo into one key/value map per processor
+func parseCPUInfo(content string) []map[string]string {
+	var cpus []map[string]string
+	var current map[string]string
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if key == "processor" {
+			current = make(map[string]string)
+			cpus = append(cpus, current)
+		}
+		if current != nil {
+			current[key] = value
+		}
+	}
+	return cpus
+}
+
+// mountEntry is a line of /proc/mounts
+type mountEntry struct {
+	Device, Point, Type, Options string
+}
+
+// parseMounts parses /proc/mounts
+func parseMounts(content string) []mountEntry {
+	var mounts []mountEntry
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		mounts = append(mounts, mountEntry{Device: fields[0], Point: fields[1], Type: fields[2], Options: fields[3]})
+	}
+	return mounts
+}
+
+// meminfoValue returns a value of /proc/meminfo in kB (0 if missing)
+func meminfoValue(content, key string) int64 {
+	for _, line := range strings.Split(content, "\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(k) == key {
+			n, _ := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(v), " kB"), 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// architecture returns the simulated machine hardware name (uname -m)
+func architecture(config *models.Config) string {
+	if config.Persona != nil && config.Persona.Hardware != "" {
+		return config.Persona.Hardware
+	}
+	return "x86_64"
+}
+
+// kernelRelease returns the simulated kernel release: persona first, then /proc/version
+func kernelRelease(config *models.Config, honeyfs fileTree) string {
+	if config.Persona != nil && config.Persona.KernelVersion != "" {
+		return config.Persona.KernelVersion
+	}
+	fields := strings.Fields(string(honeyfs["proc/version"].Data))
+	if len(fields) >= 3 {
+		return fields[2]
+	}
+	return "3.2.0-4-amd64"
+}
+
+// renderLscpu renders lscpu from /proc/cpuinfo
+func renderLscpu(config *models.Config, cpus []map[string]string) string {
+	if len(cpus) == 0 {
+		cpus = []map[string]string{{}}
+	}
+	cpu := cpus[0]
+	sockets := make(map[string]bool)
+	for _, c := range cpus {
+		sockets[c["physical id"]] = true
+	}
+	cores, _ := strconv.Atoi(cpu["cpu cores"])
+	siblings, _ := strconv.Atoi(cpu["siblings"])
+	threads := 1
+	if cores > 0 && siblings >= cores {
+		threads = siblings / cores
+	}
+	if cores == 0 {
+		cores = len(cpus)
+	}
+	online := "0"
+	if len(cpus) > 1 {
+		online = fmt.Sprintf("0-%d", len(cpus)-1)
+	}
+
+	lines := [][2]string{
+		{"Architecture", architecture(config)},
+		{"CPU op-mode(s)", "32-bit, 64-bit"},
+		{"Byte Order", "Little Endian"},
+		{"CPU(s)", strconv.Itoa(len(cpus))},
+		{"On-line CPU(s) list", online},
+		{"Thread(s) per core", strconv.Itoa(threads)},
+		{"Core(s) per socket", strconv.Itoa(cores)},
+		{"Socket(s)", strconv.Itoa(len(sockets))},
+		{"Vendor ID", cpu["vendor_id"]},
+		{"CPU family", cpu["cpu family"]},
+		{"Model", cpu["model"]},
+		{"Model name", strings.Join(strings.Fields(cpu["model name"]), " ")},
+		{"Stepping", cpu["stepping"]},
+		{"CPU MHz", cpu["cpu MHz"]},
+		{"BogoMIPS", cpu["bogomips"]},
+	}
+	if strings.Contains(" "+cpu["flags"]+" ", " vmx ") {
+		lines = append(lines, [2]string{"Virtualization", "VT-x"})
+	}
+	if cache := strings.TrimSuffix(cpu["cache size"], " KB"); cache != "" {
+		lines = append(lines, [2]string{"L2 cache", cache + "K"})
+	}
+	lines = append(lines, [2]string{"Flags", cpu["flags"]})
+
+	var b strings.Builder
+	for _, line := range lines {
+		if line[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "%-23s%s\n", line[0]+":", line[1])
+	}
+	return b.String()
+}
+
+// renderMount renders mount from /proc/mounts
+func renderMount(mounts []mountEntry) string {
+	var b strings.Builder
+	for _, m := range mounts {
+		if m.Device == "rootfs" {
+			continue
+		}
+		fmt.Fprintf(&b, "%s on %s type %s (%s)\n", m.Device, m.Point, m.Type, m.Options)
+	}
+	return b.String()
+}
+
+// renderDf renders df: disks get stable sizes, tmpfs use their size= option
+func renderDf(config *models.Config, mounts []mountEntry, memTotal int64) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-20s %10s %10s %10s %4s %s\n", "Filesystem", "1K-blocks", "Used", "Available", "Use%", "Mounted on")
+	for _, m := range mounts {
+		var size, used int64
+		switch {
+		case strings.HasPrefix(m.Device, "/dev/"):
+			key := seedKey(config) + ":" + m.Point
+			size = stableNumber(key+":size", 8, 120) * 1024 * 1024
+			if m.Point == "/boot" {
+				size = stableNumber(key+":size", 240, 480) * 1024
+			}
+			used = size * stableNumber(key+":used", 12, 78) / 100
+		case m.Type == "tmpfs" || m.Type == "devtmpfs":
+			size = mountSize(m.Options)
+			if size == 0 {
+				size = memTotal / 2
+			}
+			if m.Point == "/run" {
+				used = size * stableNumber(seedKey(config)+":run", 1, 3) / 100
+			}
+		default:
+			continue
+		}
+		name := m.Device
+		if m.Type == "devtmpfs" {
+			name = "udev"
+		} else if m.Type == "tmpfs" {
+			name = "tmpfs"
+		}
+		pct := int64(0)
+		if size > 0 {
+			pct = (used*100 + size - 1) / size
+		}
+		fmt.Fprintf(&b, "%-20s %10d %10d %10d %3d%% %s\n", name, size, used, size-used, pct, m.Point)
+	}
+	return b.String()
+}
+
+// mountSize returns the size= option of a tmpfs mount in kB
+func mountSize(options string) int64 {
+	for _, option := range strings.Split(options, ",") {
+		if value, ok := strings.CutPrefix(option, "size="); ok {
+			n, _ := strconv.ParseInt(strings.TrimSuffix(value, "k"), 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// renderHostnamectl renders hostnamectl from the server name, /etc/issue and the kernel
+func renderHostnamectl(config *models.Config, honeyfs fileTree) string {
+	osName := "GNU/Linux"
+	if issue := strings.TrimSpace(string(honeyfs["etc/issue"].Data)); issue != "" {
+		osName = strings.TrimSpace(strings.NewReplacer(`\n`, "", `\l`, "").Replace(strings.SplitN(issue, "\n", 2)[0]))
+	}
+	arch := strings.ReplaceAll(architecture(config), "_", "-")
+	if arch == "aarch64" {
+		arch = "arm64"
+	}
+
+	lines := [][2]string{
+		{"Static hostname", config.ServerName},
+		{"Icon name", "computer-vm"},
+		{"Chassis", "vm"},
+		{"Machine ID", stableHex(seedKey(config)+":machine-id", 32)},
+		{"Boot ID", stableHex(seedKey(config)+":boot-id", 32)},
+		{"Virtualization", "kvm"},
+		{"Operating System", osName},
+		{"Kernel", "Linux " + kernelRelease(config, honeyfs)},
+		{"Architecture", arch},
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		fmt.Fprintf(&b, "%18s: %s\n", line[0], line[1])
+	}
+	return b.String()
+}
+
+// renderLastlog renders lastlog for the accounts of /etc/passwd: root and the
+// profile users have logged in, system accounts never did
+func renderLastlog(config *models.Config, passwd string) string {
+	logged := map[string]bool{"root": true}
+	for _, user := range config.Users {
+		logged[user] = true
+	}
+
+	// Last logins are placed before the profile creation, so they stay stable
+	ref := referenceTime(config)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-16s %-8s %-16s %s\n", "Username", "Port", "From", "Latest")
+	for _, line := range strings.Split(passwd, "\n") {
+		user, _, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			continue
+		}
+		if !logged[user] {
+			fmt.Fprintf(&b, "%-16s %-8s %-16s %s\n", user, "", "", "**Never logged in**")
+			continue
+		}
+		key := seedKey(config) + ":" + user
+		at := ref.Add(-time.Duration(stableNumber(key+":ago", 30, 14*24*60)) * time.Minute)
+		from := fmt.Sprintf("10.%d.%d.%d", stableNumber(key+":a", 0, 20), stableNumber(key+":b", 0, 254), stableNumber(key+":c", 2, 254))
+		fmt.Fprintf(&b, "%-16s %-8s %-16s %s\n", user, "pts/"+strconv.FormatInt(stableNumber(key+":pts", 0, 3), 10), from, at.Format("Mon Jan _2 15:04:05 -0700 2006"))
+	}
+	return b.String()
+}
+
+// renderDmesg renders the first lines of a boot log consistent with /proc and the hostname
+func renderDmesg(config *models.Config, honeyfs fileTree, cpus []map[string]string, mounts []mountEntry, memTotal int64) string {
+	version := strings.TrimSpace(string(honeyfs["proc/version"].Data))
+	if version == "" {
+		version = "Linux version " + kernelRelease(config, honeyfs)
+	}
+	model := "Virtual CPU"
+	if len(cpus) > 0 && cpus[0]["model name"] != "" {
+		model = strings.Join(strings.Fields(cpus[0]["model name"]), " ")
+	}
+	root, rootType := "/dev/sda1", "ext4"
+	for _, m := range mounts {
+		if m.Point == "/" && strings.HasPrefix(m.Device, "/dev/") {
+			root, rootType = m.Device, m.Type
+		}
+	}
+
+	lines := []string{
+		version,
+		"Command line: BOOT_IMAGE=/vmlinuz-" + kernelRelease(config, honeyfs) + " root=" + root + " ro quiet",
+		"BIOS-provided physical RAM map:",
+		fmt.Sprintf("Memory: %dk/%dk available", memTotal*97/100, memTotal),
+		fmt.Sprintf("smpboot: Allowing %d CPUs, 0 hotplug CPUs", max(len(cpus), 1)),
+		"smpboot: CPU0: " + model,
+		fmt.Sprintf("Brought up %d CPUs", max(len(cpus), 1)),
+		"NET: Registered protocol family 2",
+		"e1000: eth0 NIC Link is Up 1000 Mbps Full Duplex, Flow Control: RX",
+		strings.ToUpper(rootType) + "-fs (" + path.Base(root) + "): mounted filesystem with ordered data mode. Opts: (null)",
+		"systemd[1]: Set hostname to <" + config.ServerName + ">.",
+	}
+
+	var b strings.Builder
+	at := int64(0)
+	for i, line := range lines {
+		if i > 4 {
+			at += stableNumber(fmt.Sprintf("%s:dmesg:%d", seedKey(config), i), 50000, 900000)
+		}
+		fmt.Fprintf(&b, "[%5d.%06d] %s\n", at/1000000, at%1000000, line)
+	}
+	return b.String()
+}
+
+// renderPs renders ps aux from the process table of the persona
+func renderPs(config *models.Config, memTotal int64) string {
+	boot := bootTime(config).Format("Jan02")
+	now := referenceTime(config).Format("15:04")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-8s %7s %4s %4s %6s %5s %-8s %-4s %5s %6s %s\n", "USER", "PID", "%CPU", "%MEM", "VSZ", "RSS", "TTY", "STAT", "START", "TIME", "COMMAND")
+	for _, p := range personaProcesses(config) {
+		mem := 0.0
+		if memTotal > 0 {
+			mem = float64(p.RSS) * 100 / float64(memTotal)
+		}
+		start := now
+		if p.Boot {
+			start = boot
+		}
+		fmt.Fprintf(&b, "%-8s %7d %4.1f %4.1f %6d %5d %-8s %-4s %5s %6s %s\n", p.User, p.PID, 0.0, mem, p.VSZ, p.RSS, p.TTY, p.Stat, start, p.Time, p.Command)
+	}
+	return b.String()
+}
+
+// socketBacklog returns the accept queue size shown by ss for a program
+func socketBacklog(program string) int {
+	switch program {
+	case "nginx":
+		return 511
+	case "postgres":
+		return 244
+	case "dockerd":
+		return 4096
+	}
+	return 128
+}
+
+// renderNetstat renders netstat -tlnp from the listening sockets of the persona
+func renderNetstat(config *models.Config) string {
+	var b strings.Builder
+	b.WriteString("Active Internet connections (only servers)\n")
+	fmt.Fprintf(&b, "%-5s %6s %6s %-23s %-23s %-11s %s\n", "Proto", "Recv-Q", "Send-Q", "Local Address", "Foreign Address", "State", "PID/Program name")
+	for _, s := range listeningSockets(config) {
+		fmt.Fprintf(&b, "%-5s %6d %6d %-23s %-23s %-11s %d/%s\n", "tcp", 0, 0, fmt.Sprintf("0.0.0.0:%d", s.Port), "0.0.0.0:*", "LISTEN", s.PID, s.Program)
+	}
+	return b.String()
+}
+
+// renderSs renders ss -tlnp from the listening sockets of the persona
+func renderSs(config *models.Config) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-8s %-8s %-8s %22s %22s  %s\n", "State", "Recv-Q", "Send-Q", "Local Address:Port", "Peer Address:Port", "Process")
+	for _, s := range listeningSockets(config) {
+		fmt.Fprintf(&b, "%-8s %-8d %-8d %22s %22s  users:((\"%s\",pid=%d,fd=%d))\n",
+			"LISTEN", 0, socketBacklog(s.Program), fmt.Sprintf("0.0.0.0:%d", s.Port), "0.0.0.0:*", s.Program, s.PID, stableNumber(seedKey(config)+":fd:"+s.Program, 3, 9))
+	}
+	return b.String()
+}
+
+// renderSystemctl renders systemctl list-units --type=service --state=running
+func renderSystemctl(config *models.Config) string {
+	units := [][2]string{{"getty@tty1.service", "Getty on tty1"}}
+	for _, spec := range systemServices {
+		units = append(units, [2]string{spec.Unit, spec.Description})
+	}
+	for _, service := range personaServices(config) {
+		spec := serviceSpecs[service.Name]
+		units = append(units, [2]string{spec.Unit, spec.Description})
+		if service.Name == "docker" {
+			units = append(units, [2]string{"containerd.service", "containerd container runtime"})
+		}
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i][0] < units[j][0] })
+
+	width := len("UNIT")
+	for _, unit := range units {
+		width = max(width, len(unit[0]))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %-*s LOAD   ACTIVE SUB     DESCRIPTION\n", width, "UNIT")
+	for _, unit := range units {
+		fmt.Fprintf(&b, "  %-*s loaded active running %s\n", width, unit[0], unit[1])
+	}
+	b.WriteString(`
+LOAD   = Reflects whether the unit definition was properly loaded.
+ACTIVE = The high-level unit activation state, i.e. generalization of SUB.
+SUB    = The low-level unit activation state, values depend on unit type.
+`)
+	fmt.Fprintf(&b, "\n%d loaded units listed.\n", len(units))
+	return b.String()
+}
@@ -2,15 +2,18 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"strings"
+	"unicode"
 
+	"github.com/otori-lab/otori-cli/internal/container"
 	"github.com/otori-lab/otori-cli/internal/models"
 )
 
 // ValidationError represents a validation error
 type ValidationError struct {
-	Field   string
-	Message string
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // ValidateConfig validates a configuration
@@ -56,9 +59,25 @@ func ValidateConfig(config *models.Config) []ValidationError {
 		})
 	}
 
-	// Check for duplicate users
+	// Check image reference
+	if config.Image != "" {
+		if _, err := container.ParseImageRef(config.Image); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "Image",
+				Message: err.Error(),
+			})
+		}
+	}
+
+	// Check for invalid and duplicate users
 	uniqueUsers := make(map[string]bool)
 	for _, user := range config.Users {
+		if user != "" && !isValidUserName(user) {
+			errors = append(errors, ValidationError{
+				Field:   "Users",
+				Message: fmt.Sprintf("Invalid user '%s' (no '/', ':', '..', spaces or control characters)", user),
+			})
+		}
 		if user != "" {
 			lowerUser := strings.ToLower(user)
 			if uniqueUsers[lowerUser] {
@@ -71,9 +90,197 @@ func ValidateConfig(config *models.Config) []ValidationError {
 		}
 	}
 
+	// Check passwords (one userdb.txt line per password)
+	for user, passwords := range config.Passwords {
+		if !uniqueUsers[strings.ToLower(user)] {
+			errors = append(errors, ValidationError{
+				Field:   "Passwords",
+				Message: fmt.Sprintf("Password set for unknown user '%s'", user),
+			})
+		}
+		for _, password := range passwords {
+			if password == "" || removeNullChars(password) != password {
+				errors = append(errors, ValidationError{
+					Field:   "Passwords",
+					Message: fmt.Sprintf("Invalid password for user '%s' (empty or control characters)", user),
+				})
+			}
+		}
+	}
+
+	// Check ports
+	if config.Ports != nil {
+		for _, port := range []struct {
+			name  string
+			value int
+		}{{"SSH", config.Ports.SSH}, {"Telnet", config.Ports.Telnet}} {
+			if port.value < 0 || port.value > 65535 {
+				errors = append(errors, ValidationError{
+					Field:   "Ports",
+					Message: fmt.Sprintf("%s port must be between 1 and 65535", port.name),
+				})
+			}
+		}
+		if config.SSHPort() == config.TelnetPort() {
+			errors = append(errors, ValidationError{
+				Field:   "Ports",
+				Message: "SSH and Telnet ports must be different",
+			})
+		}
+	}
+
+	// Check sinks (rendered as [output_<type>] sections)
+	for _, sink := range config.Sinks {
+		if !IsValidProfileName(sink.Type) {
+			errors = append(errors, ValidationError{
+				Field:   "Sinks",
+				Message: fmt.Sprintf("Invalid sink type '%s'", sink.Type),
+			})
+		}
+	}
+
+	// Check bait files (must stay inside the honeyfs)
+	for _, bait := range config.BaitFiles {
+		if !isValidHoneyFSPath(bait.Path) {
+			errors = append(errors, ValidationError{
+				Field:   "BaitFiles",
+				Message: fmt.Sprintf("Bait file path '%s' must be absolute and must not contain '..'", bait.Path),
+			})
+		}
+	}
+
+	// Check simulated services and neighbour hosts
+	if config.Persona != nil {
+		errors = append(errors, validatePersonaNetwork(config.Persona)...)
+	}
+
+	// Check command outputs (must stay inside the txtcmds directory)
+	for _, command := range config.Commands {
+		if !isValidHoneyFSPath(command.Path) || strings.HasSuffix(command.Path, "/") {
+			errors = append(errors, ValidationError{
+				Field:   "Commands",
+				Message: fmt.Sprintf("Command path '%s' must be an absolute file path and must not contain '..'", command.Path),
+			})
+		}
+	}
+
 	return errors
 }
 
+// validatePersonaNetwork checks the services and neighbour hosts of a persona
+func validatePersonaNetwork(persona *models.Persona) []ValidationError {
+	var errors []ValidationError
+
+	services := make(map[string]bool)
+	for _, service := range persona.Services {
+		if _, ok := serviceSpecs[service.Name]; !ok {
+			errors = append(errors, ValidationError{
+				Field:   "Persona",
+				Message: fmt.Sprintf("Unknown service '%s' (use: %s)", service.Name, strings.Join(ServiceNames(), ", ")),
+			})
+			continue
+		}
+		if services[service.Name] {
+			errors = append(errors, ValidationError{
+				Field:   "Persona",
+				Message: fmt.Sprintf("Duplicate service '%s'", service.Name),
+			})
+		}
+		services[service.Name] = true
+		if service.Port < 0 || service.Port > 65535 {
+			errors = append(errors, ValidationError{
+				Field:   "Persona",
+				Message: fmt.Sprintf("Port of service '%s' must be between 1 and 65535", service.Name),
+			})
+		}
+	}
+
+	// sshd is always simulated, even when not listed
+	ports := make(map[int]string)
+	for _, service := range personaServices(&models.Config{Persona: persona}) {
+		port := servicePort(service)
+		if port == 0 {
+			continue
+		}
+		if other, taken := ports[port]; taken {
+			errors = append(errors, ValidationError{
+				Field:   "Persona",
+				Message: fmt.Sprintf("Services '%s' and '%s' both listen on port %d", other, service.Name, port),
+			})
+		}
+		ports[port] = service.Name
+	}
+
+	neighbors := make(map[string]bool)
+	for _, neighbor := range persona.Neighbors {
+		if !isValidHostLabel(neighbor.Name) {
+			errors = append(errors, ValidationError{
+				Field:   "Persona",
+				Message: fmt.Sprintf("Invalid neighbor name '%s' (letters, digits and hyphens)", neighbor.Name),
+			})
+		} else if neighbors[strings.ToLower(neighbor.Name)] {
+			errors = append(errors, ValidationError{
+				Field:   "Persona",
+				Message: fmt.Sprintf("Duplicate neighbor '%s'", neighbor.Name),
+			})
+		}
+		neighbors[strings.ToLower(neighbor.Name)] = true
+		if neighbor.IP != "" {
+			if ip := net.ParseIP(neighbor.IP); ip == nil || ip.To4() == nil {
+				errors = append(errors, ValidationError{
+					Field:   "Persona",
+					Message: fmt.Sprintf("Invalid IPv4 address '%s' for neighbor '%s'", neighbor.IP, neighbor.Name),
+				})
+			}
+		}
+	}
+
+	return errors
+}
+
+// isValidUserName checks that a user name can be used in /etc/passwd and as
+// a home directory name (/home/<user>)
+func isValidUserName(name string) bool {
+	if strings.Contains(name, "..") || strings.ContainsAny(name, "/:") {
+		return false
+	}
+	for _, ch := range name {
+		if unicode.IsSpace(ch) || unicode.IsControl(ch) {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidHostLabel checks that a name can be used as a DNS label
+func isValidHostLabel(name string) bool {
+	if name == "" || len(name) > 63 || name[0] == '-' || name[len(name)-1] == '-' {
+		return false
+	}
+	for _, ch := range name {
+		if !((ch >= 'a' && ch <= 'z') ||
+			(ch >= 'A' && ch <= 'Z') ||
+			(ch >= '0' && ch <= '9') ||
+			ch == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidHoneyFSPath checks that a path is absolute and cannot escape the honeyfs
+func isValidHoneyFSPath(p string) bool {
+	if !strings.HasPrefix(p, "/") || p == "/" {
+		return false
+	}
+	for _, part := range strings.Split(p, "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
 // IsValidProfileName checks if a profile name is valid (exported for reuse)
 func IsValidProfileName(name string) bool {
 	if name == "" || len(name) > 100 {
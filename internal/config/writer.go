@@ -1,6 +1,8 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -14,73 +16,86 @@ import (
 // WriteConfig writes the configuration to a profile directory
 // For "classic" type: creates profile folder with JSON + cowrie.cfg + userdb.txt
 // For "ia" type: creates profile folder with JSON only
+// Templates are resolved for the generated files, the JSON keeps only the profile's own values
 func WriteConfig(config *models.Config) error {
-	// Add timestamp
-	config.CreatedAt = time.Now().Format(time.RFC3339)
+	// Creation date and seed are set once: rewriting a profile renders the same files
+	if config.CreatedAt == "" {
+		config.CreatedAt = time.Now().Format(time.RFC3339)
+	}
+	if config.Seed == "" {
+		config.Seed = NewSeed()
+	}
 
 	// Clean users (remove null and empty characters)
-	var cleanedUsers []string
-	for _, user := range config.Users {
-		cleaned := strings.TrimSpace(user)
-		// Remove all null and control characters
-		cleaned = removeNullChars(cleaned)
-		if cleaned != "" {
-			cleanedUsers = append(cleanedUsers, cleaned)
-		}
-	}
-	config.Users = cleanedUsers
+	config.Users = cleanUsers(config.Users)
 
-	// Create profile directory (profiles/{profileName}/)
-	profileDir := getProfileDir(config.ProfileName)
-	if err := os.MkdirAll(profileDir, 0755); err != nil {
-		return fmt.Errorf("error creating profile directory: %w", err)
-	}
+	return writeProfile(config.ProfileName, config)
+}
 
-	// Build JSON filename
-	filename := filepath.Join(profileDir, config.ProfileName+".json")
+// WriteConfigWithName writes a configuration with a specific profile name (for editing)
+func WriteConfigWithName(profileName string, config *models.Config) error {
+	// Clean users (remove null and empty characters)
+	config.Users = cleanUsers(config.Users)
 
-	// Encode configuration to JSON
+	return writeProfile(profileName, config)
+}
+
+// writeProfile renders a whole profile into a staging directory, then swaps it
+// with the profile directory, under the profile lock. A crash or a failed render
+// leaves the previous files untouched.
+func writeProfile(profileName string, config *models.Config) error {
+	lock, err := LockProfile(profileName)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	config.SchemaVersion = models.CurrentSchemaVersion
 	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return fmt.Errorf("error encoding JSON: %w", err)
 	}
 
-	// Write JSON file
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		return fmt.Errorf("error writing file: %w", err)
+	if err := os.MkdirAll(getConfigDir(), 0755); err != nil {
+		return fmt.Errorf("error creating profile directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(getConfigDir(), stagingPrefix(profileName)+"*")
+	if err != nil {
+		return fmt.Errorf("error creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	// MkdirTemp creates the directory 0700, it becomes the profile directory
+	if err := os.Chmod(staging, 0755); err != nil {
+		return fmt.Errorf("error creating staging directory: %w", err)
 	}
 
-	// For classic type, also generate Cowrie config files
-	if config.Type == "classic" {
-		if err := WriteCowrieConfig(profileDir, config); err != nil {
-			return fmt.Errorf("error writing cowrie.cfg: %w", err)
-		}
-		if err := WriteUserDB(profileDir, config); err != nil {
-			return fmt.Errorf("error writing userdb.txt: %w", err)
-		}
-		if err := WriteHoneyFS(profileDir, config); err != nil {
-			return fmt.Errorf("error writing honeyfs: %w", err)
-		}
-		if err := WriteDockerCompose(profileDir, config); err != nil {
-			return fmt.Errorf("error writing docker-compose.yml: %w", err)
+	// Start from the current files: files otori does not generate are kept
+	profileDir := getProfileDir(profileName)
+	if info, err := os.Stat(profileDir); err == nil && info.IsDir() {
+		if err := copyDir(profileDir, staging); err != nil {
+			return fmt.Errorf("error copying profile: %w", err)
 		}
 	}
 
-	return nil
+	if err := os.WriteFile(filepath.Join(staging, profileName+".json"), data, 0644); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	if err := renderProfile(staging, config); err != nil {
+		return err
+	}
+
+	return installProfileDir(staging, profileName)
 }
 
-// WriteConfigWithName writes a configuration with a specific profile name (for editing)
-func WriteConfigWithName(profileName string, config *models.Config) error {
-	// Clean users (remove null and empty characters)
-	var cleanedUsers []string
-	for _, user := range config.Users {
-		cleaned := strings.TrimSpace(user)
-		cleaned = removeNullChars(cleaned)
-		if cleaned != "" {
-			cleanedUsers = append(cleanedUsers, cleaned)
-		}
+// writeProfileJSON rewrites only profiles/{profileName}/{profileName}.json in the current schema version
+func writeProfileJSON(profileName string, config *models.Config) error {
+	lock, err := LockProfile(profileName)
+	if err != nil {
+		return err
 	}
-	config.Users = cleanedUsers
+	defer lock.Unlock()
+
+	config.SchemaVersion = models.CurrentSchemaVersion
 
 	// Create profile directory (profiles/{profileName}/)
 	profileDir := getProfileDir(profileName)
@@ -88,32 +103,114 @@ func WriteConfigWithName(profileName string, config *models.Config) error {
 		return fmt.Errorf("error creating profile directory: %w", err)
 	}
 
-	// Build filename with specified name
-	filename := filepath.Join(profileDir, profileName+".json")
-
 	// Encode configuration to JSON
 	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return fmt.Errorf("error encoding JSON: %w", err)
 	}
 
-	// Write JSON file
-	if err := os.WriteFile(filename, data, 0644); err != nil {
+	// Write to a temporary file then rename, so readers never see a partial JSON
+	path := filepath.Join(profileDir, profileName+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
 		return fmt.Errorf("error writing file: %w", err)
 	}
 
+	return nil
+}
+
+// stagingPrefix is the name prefix of the staging directories of a profile.
+// Dots are not allowed in profile names, so prefixes of two profiles never overlap.
+func stagingPrefix(profileName string) string {
+	return ".render." + profileName + "."
+}
+
+// previousProfileDir is where the current files of a profile are moved during a swap
+func previousProfileDir(profileName string) string {
+	return filepath.Join(getConfigDir(), "."+profileName+".old")
+}
+
+// installProfileDir replaces a profile directory with a fully written staging directory.
+// Both directories are exchanged atomically where the kernel supports it (staging
+// then holds the previous files, removed by the caller). Otherwise the current
+// directory is moved aside first and put back if the swap fails; until then
+// readers and the next lock holder find the profile in the aside copy.
+func installProfileDir(staging, profileName string) error {
+	profileDir := getProfileDir(profileName)
+	previous := previousProfileDir(profileName)
+	os.RemoveAll(previous)
+
+	replaced := false
+	if _, err := os.Stat(profileDir); err == nil {
+		if err := exchangeDirs(staging, profileDir); err == nil {
+			return nil
+		}
+		if err := os.Rename(profileDir, previous); err != nil {
+			return fmt.Errorf("error replacing profile: %w", err)
+		}
+		replaced = true
+	}
+	if err := os.Rename(staging, profileDir); err != nil {
+		if replaced {
+			os.Rename(previous, profileDir)
+		}
+		return fmt.Errorf("error installing profile: %w", err)
+	}
+	if replaced {
+		os.RemoveAll(previous)
+	}
+	return nil
+}
+
+// recoverProfileDir cleans up after a profile write interrupted by a crash:
+// the previous files are put back if the swap did not complete, and leftover
+// staging directories are removed. Called with the profile lock held.
+func recoverProfileDir(profileName string) error {
+	previous := previousProfileDir(profileName)
+	if _, err := os.Stat(previous); err == nil {
+		if _, err := os.Stat(getProfileDir(profileName)); os.IsNotExist(err) {
+			if err := os.Rename(previous, getProfileDir(profileName)); err != nil {
+				return fmt.Errorf("error recovering profile '%s': %w", profileName, err)
+			}
+		} else {
+			os.RemoveAll(previous)
+		}
+	}
+
+	stale, _ := filepath.Glob(filepath.Join(getConfigDir(), stagingPrefix(profileName)+"*"))
+	for _, dir := range stale {
+		os.RemoveAll(dir)
+	}
+	return nil
+}
+
+// renderProfile generates the Cowrie files of a profile from its effective
+// configuration (profile merged with the templates it extends)
+func renderProfile(profileDir string, config *models.Config) error {
+	effective, _, err := ResolveConfig(config)
+	if err != nil {
+		return fmt.Errorf("error resolving templates: %w", err)
+	}
+
 	// For classic type, also generate Cowrie config files
-	if config.Type == "classic" {
-		if err := WriteCowrieConfig(profileDir, config); err != nil {
+	if effective.Type == "classic" {
+		if err := WriteCowrieConfig(profileDir, effective); err != nil {
 			return fmt.Errorf("error writing cowrie.cfg: %w", err)
 		}
-		if err := WriteUserDB(profileDir, config); err != nil {
+		if err := WriteUserDB(profileDir, effective); err != nil {
 			return fmt.Errorf("error writing userdb.txt: %w", err)
 		}
-		if err := WriteHoneyFS(profileDir, config); err != nil {
+		if err := WriteHoneyFS(profileDir, effective); err != nil {
 			return fmt.Errorf("error writing honeyfs: %w", err)
 		}
-		if err := WriteDockerCompose(profileDir, config); err != nil {
+		if err := WriteTxtCmds(profileDir, effective); err != nil {
+			return fmt.Errorf("error writing txtcmds: %w", err)
+		}
+		if err := WriteDockerCompose(profileDir, effective); err != nil {
 			return fmt.Errorf("error writing docker-compose.yml: %w", err)
 		}
 	}
@@ -121,32 +218,51 @@ func WriteConfigWithName(profileName string, config *models.Config) error {
 	return nil
 }
 
+// RenderMarker is written at the root of the directories rendered by RenderProfile
+const RenderMarker = ".otori-render"
+
+// RenderProfile writes the generated files of a configuration (cowrie.cfg, userdb.txt,
+// honeyfs, txtcmds, docker-compose.yml) to a directory, without touching any profile.
+// The output only depends on the configuration: same config, seed and creation date,
+// same bytes.
+func RenderProfile(outDir string, config *models.Config) error {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	if err := renderProfile(outDir, config); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, RenderMarker), []byte("rendered by otori\n"), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", RenderMarker, err)
+	}
+	return nil
+}
+
+// IsRenderDir returns true if the directory was written by RenderProfile
+func IsRenderDir(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, RenderMarker))
+	return err == nil && info.Mode().IsRegular()
+}
+
 // ReadConfig reads a configuration from a profile directory
 func ReadConfig(profileName string) (*models.Config, error) {
 	if profileName == "" {
-		profileName = "default"
+		profileName = DefaultProfile()
 	}
 
-	// Try new structure first: profiles/{profileName}/{profileName}.json
-	profileDir := getProfileDir(profileName)
-	filename := filepath.Join(profileDir, profileName+".json")
-	data, err := os.ReadFile(filename)
-
-	// Fallback to old structure: profiles/{profileName}.json
+	// New structure first (profiles/{profileName}/{profileName}.json), then the legacy flat file
+	data, err := readProfileFile(profileName)
 	if err != nil {
-		oldFilename := filepath.Join(getConfigDir(), profileName+".json")
-		data, err = os.ReadFile(oldFilename)
-		if err != nil {
-			return nil, fmt.Errorf("error reading file: %w", err)
-		}
+		return nil, err
 	}
 
-	var config models.Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("error decoding JSON: %w", err)
+	// Older schema versions are upgraded in memory; "otori profiles migrate" rewrites them
+	config, _, err := decodeConfig(data)
+	if err != nil {
+		return nil, err
 	}
 
-	return &config, nil
+	return config, nil
 }
 
 // ListConfigs lists all available profiles
@@ -169,6 +285,13 @@ func ListConfigs() ([]string, error) {
 			if _, err := os.Stat(jsonFile); err == nil {
 				profiles = append(profiles, entry.Name())
 			}
+
+			// A profile left aside by an interrupted swap
+			if name, ok := strings.CutSuffix(entry.Name(), ".old"); ok && strings.HasPrefix(name, ".") && IsValidProfileName(name[1:]) {
+				if _, err := os.Stat(getProfileDir(name[1:])); os.IsNotExist(err) {
+					profiles = append(profiles, name[1:])
+				}
+			}
 		}
 		// Fallback: old structure with direct JSON files
 		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
@@ -190,14 +313,25 @@ func ListConfigs() ([]string, error) {
 	return profiles, nil
 }
 
-// getConfigDir returns the config directory path (~/.otori/profiles)
-func getConfigDir() string {
-	homeDir, err := os.UserHomeDir()
+// ConfigHash returns a short fingerprint of a profile configuration.
+// It is written as a container label at deploy time to detect drift
+// between the running honeypot and the profile on disk.
+func ConfigHash(config *models.Config) string {
+	c := *config
+	c.CreatedAt = "" // not part of the honeypot behaviour
+	c.SchemaVersion = 0
+
+	data, err := json.Marshal(&c)
 	if err != nil {
-		// Fallback to current directory if home not found
-		return "profiles"
+		return ""
 	}
-	return filepath.Join(homeDir, ".otori", "profiles")
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// getConfigDir returns the config directory path (~/.otori/profiles)
+func getConfigDir() string {
+	return filepath.Join(GetOtoriDir(), "profiles")
 }
 
 // GetConfigDir exports the config directory path for use by other packages
@@ -210,6 +344,49 @@ func getProfileDir(profileName string) string {
 	return filepath.Join(getConfigDir(), profileName)
 }
 
+// cleanUsers removes null and control characters and drops empty users
+func cleanUsers(users []string) []string {
+	var cleaned []string
+	for _, user := range users {
+		if u := removeNullChars(user); u != "" {
+			cleaned = append(cleaned, u)
+		}
+	}
+	return cleaned
+}
+
+// SameConfig returns true if two profile configurations render the same honeypot
+func SameConfig(a, b *models.Config) bool {
+	ca, cb := *a, *b
+	ca.Users, cb.Users = cleanUsers(a.Users), cleanUsers(b.Users)
+	return ConfigHash(&ca) == ConfigHash(&cb)
+}
+
+// RemoveProfile deletes a profile from disk (directory or legacy flat file)
+func RemoveProfile(profileName string) error {
+	lock, err := LockProfile(profileName)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	profileDir := getProfileDir(profileName)
+	if info, err := os.Stat(profileDir); err == nil && info.IsDir() {
+		if err := os.RemoveAll(profileDir); err != nil {
+			return fmt.Errorf("error deleting profile directory: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := os.Stat(legacyProfilePath(profileName)); os.IsNotExist(err) {
+		return fmt.Errorf("profile '%s' %w", profileName, ErrNotFound)
+	}
+	if err := os.Remove(legacyProfilePath(profileName)); err != nil {
+		return fmt.Errorf("error deleting profile: %w", err)
+	}
+	return nil
+}
+
 // removeNullChars removes null and control characters
 func removeNullChars(s string) string {
 	var result strings.Builder
@@ -0,0 +1,584 @@
+// Package containertest provides a fake docker runtime for tests
+package containertest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/container"
+	"gopkg.in/yaml.v3"
+)
+
+// Call is a command received by the fake
+type Call struct {
+	Name  string   // program: docker, podman or systemctl
+	Args  []string // arguments of the command
+	Dir   string   // working directory
+	Stdin string   // content piped to the command
+}
+
+// String returns the command line of the call (without "docker", with "systemctl")
+func (c Call) String() string {
+	if c.Name == "systemctl" {
+		return "systemctl " + strings.Join(c.Args, " ")
+	}
+	return strings.Join(c.Args, " ")
+}
+
+// Fake is a container.Runner simulating the docker commands used by otori.
+// It records every call and keeps the containers and volumes created by
+// "docker compose up" and the pulled or loaded images in memory, so that
+// ps/inspect report them. systemctl only changes the state of the units.
+type Fake struct {
+	// Now returns the start time of the containers (default: time.Now)
+	Now func() time.Time
+
+	mu         sync.Mutex
+	calls      []Call
+	containers map[string]*fakeContainer // by name
+	volumes    map[string]bool
+	images     map[string]*fakeImage // by name (repository:tag or repository@digest)
+	failures   map[string]error      // by command line prefix
+	outputs    map[string][]byte     // by command line prefix
+	units      map[string]*fakeUnit  // systemd units, by scope and name ("user/otori-web.service")
+}
+
+// fakeUnit is the state of a systemd unit
+type fakeUnit struct {
+	active  bool
+	enabled bool
+}
+
+// fakeImage is a local image
+type fakeImage struct {
+	id          string
+	repoDigests []string
+}
+
+// fakeContainer is a container created from a compose file
+type fakeContainer struct {
+	id        string
+	name      string
+	image     string
+	imageID   string
+	labels    map[string]string
+	ports     []string
+	dir       string // compose project directory
+	state     string
+	startedAt time.Time
+}
+
+// NewFake returns an empty fake runtime
+func NewFake() *Fake {
+	return &Fake{
+		Now:        time.Now,
+		containers: make(map[string]*fakeContainer),
+		volumes:    make(map[string]bool),
+		images:     make(map[string]*fakeImage),
+		failures:   make(map[string]error),
+		outputs:    make(map[string][]byte),
+		units:      make(map[string]*fakeUnit),
+	}
+}
+
+// AddImage makes an image present locally, as if it had been pulled or loaded
+func (f *Fake) AddImage(name, id string, repoDigests ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.images[name] = &fakeImage{id: id, repoDigests: repoDigests}
+}
+
+// Install makes the fake the runner of otori and returns a function restoring the previous one
+func (f *Fake) Install() func() {
+	previous := container.SetRunner(f)
+	return func() { container.SetRunner(previous) }
+}
+
+// FailOn makes every command starting with the given arguments fail with err
+func (f *Fake) FailOn(prefix string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[prefix] = err
+}
+
+// SetOutput makes every command starting with the given arguments print output
+// (the log read by "docker cp" or followed by "docker exec ... tail", for instance)
+func (f *Fake) SetOutput(prefix string, output []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.outputs[prefix] = output
+}
+
+// Calls returns the commands received so far
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Reset forgets the commands received so far
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+// Commands returns the command lines received so far (see Call.String)
+func (f *Fake) Commands() []string {
+	var lines []string
+	for _, call := range f.Calls() {
+		lines = append(lines, call.String())
+	}
+	return lines
+}
+
+// Running returns the names of the running containers
+func (f *Fake) Running() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for name, c := range f.containers {
+		if c.state == "running" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run records the command and simulates it
+func (f *Fake) Run(cmd container.Command) ([]byte, error) {
+	call := Call{Name: cmd.Name, Args: cmd.Args, Dir: cmd.Dir}
+	if cmd.Stdin != nil {
+		data, err := io.ReadAll(cmd.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		call.Stdin = string(data)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+
+	if cmd.Name != "docker" && cmd.Name != "podman" && cmd.Name != "systemctl" {
+		return nil, fmt.Errorf("fake runtime: unsupported program %q", cmd.Name)
+	}
+	line := call.String()
+	for prefix, err := range f.failures {
+		if strings.HasPrefix(line, prefix) {
+			if cmd.Stderr != nil {
+				fmt.Fprintln(cmd.Stderr, err)
+			}
+			return nil, err
+		}
+	}
+
+	var output []byte
+	var err error
+	if cmd.Name == "systemctl" {
+		output, err = f.systemctl(call.Args)
+	} else {
+		output, err = f.docker(call)
+	}
+	for prefix, data := range f.outputs {
+		if err == nil && strings.HasPrefix(line, prefix) {
+			output = data
+		}
+	}
+	if err != nil {
+		if cmd.Stderr != nil {
+			fmt.Fprintln(cmd.Stderr, err)
+		}
+		// systemctl is-active/is-enabled print the state and fail when it is not the expected one
+		return output, err
+	}
+	if cmd.Stdout != nil {
+		_, err := cmd.Stdout.Write(output)
+		return nil, err
+	}
+	return output, nil
+}
+
+// docker simulates a docker command
+func (f *Fake) docker(call Call) ([]byte, error) {
+	args := call.Args
+	if len(args) == 0 {
+		return nil, fmt.Errorf("fake runtime: missing command")
+	}
+
+	switch args[0] {
+	case "compose":
+		return nil, f.compose(call.Dir, args[1:])
+	case "ps":
+		return f.ps(), nil
+	case "inspect":
+		return f.inspect(args[1:])
+	case "exec", "logs", "cp", "run":
+		return nil, nil
+	case "image":
+		return f.image(args[1:])
+	case "pull", "tag", "save", "load":
+		return f.imageTransfer(args)
+	case "build":
+		return nil, f.build(args[1:])
+	case "rm":
+		for _, id := range args[1:] {
+			for name, c := range f.containers {
+				if c.id == id || c.name == id {
+					delete(f.containers, name)
+				}
+			}
+		}
+		return nil, nil
+	case "volume":
+		return nil, f.volume(args[1:])
+	}
+	return nil, fmt.Errorf("fake runtime: unsupported command %q", strings.Join(args, " "))
+}
+
+// composeFile is the part of docker-compose.yml read by the fake
+type composeFile struct {
+	Services map[string]struct {
+		Image         string            `yaml:"image"`
+		ContainerName string            `yaml:"container_name"`
+		Labels        map[string]string `yaml:"labels"`
+		Ports         []string          `yaml:"ports"`
+	} `yaml:"services"`
+	Volumes map[string]struct {
+		Name string `yaml:"name"`
+	} `yaml:"volumes"`
+}
+
+// compose simulates "docker compose up/down/restart" in a project directory
+func (f *Fake) compose(dir string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("fake runtime: missing compose command")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	if err != nil {
+		return fmt.Errorf("no configuration file provided: not found")
+	}
+	var file composeFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid compose file: %w", err)
+	}
+
+	switch args[0] {
+	case "up":
+		for name, v := range file.Volumes {
+			if v.Name != "" {
+				name = v.Name
+			}
+			f.volumes[name] = true
+		}
+		for service, s := range file.Services {
+			name := s.ContainerName
+			if name == "" {
+				name = filepath.Base(dir) + "-" + service + "-1"
+			}
+			existing, ok := f.containers[name]
+			if ok && existing.state == "running" && existing.labels[container.LabelConfigHash] == s.Labels[container.LabelConfigHash] &&
+				!contains(args, "--force-recreate") {
+				continue
+			}
+			// Compose pulls the images that are not present
+			img, ok := f.images[s.Image]
+			if !ok {
+				img = &fakeImage{id: imageID(s.Image)}
+				f.images[s.Image] = img
+			}
+			f.containers[name] = &fakeContainer{
+				id:        containerID(name),
+				name:      name,
+				image:     s.Image,
+				imageID:   img.id,
+				labels:    s.Labels,
+				ports:     s.Ports,
+				dir:       dir,
+				state:     "running",
+				startedAt: f.Now(),
+			}
+		}
+	case "restart":
+		for _, c := range f.containers {
+			if c.dir == dir {
+				c.state = "running"
+				c.startedAt = f.Now()
+			}
+		}
+	case "down":
+		for name, c := range f.containers {
+			if c.dir == dir {
+				delete(f.containers, name)
+			}
+		}
+	default:
+		return fmt.Errorf("fake runtime: unsupported compose command %q", args[0])
+	}
+	return nil
+}
+
+// ps lists the containers in the format used by container.ListContainers
+func (f *Fake) ps() []byte {
+	var b strings.Builder
+	for _, name := range f.names() {
+		c := f.containers[name]
+		fmt.Fprintf(&b, "%s|%s|%s\n", c.id, c.name, c.labels[container.LabelProfile])
+	}
+	return []byte(b.String())
+}
+
+// inspect returns the "docker inspect" JSON of containers
+func (f *Fake) inspect(ids []string) ([]byte, error) {
+	var results []map[string]any
+	for _, id := range ids {
+		var c *fakeContainer
+		for _, candidate := range f.containers {
+			if candidate.id == id || candidate.name == id {
+				c = candidate
+			}
+		}
+		if c == nil {
+			return nil, fmt.Errorf("Error: No such object: %s", id)
+		}
+
+		ports := make(map[string][]map[string]string)
+		for _, p := range c.ports {
+			// "2222:2222   # SSH" style mappings: host:container
+			mapping := strings.Fields(p)[0]
+			host, target, ok := strings.Cut(mapping, ":")
+			if !ok {
+				continue
+			}
+			ports[target+"/tcp"] = append(ports[target+"/tcp"], map[string]string{"HostPort": host})
+		}
+
+		results = append(results, map[string]any{
+			"Id":           c.id,
+			"Name":         "/" + c.name,
+			"Image":        c.imageID,
+			"RestartCount": 0,
+			"State": map[string]any{
+				"Status":     c.state,
+				"ExitCode":   0,
+				"Error":      "",
+				"StartedAt":  c.startedAt.UTC().Format(time.RFC3339Nano),
+				"FinishedAt": "0001-01-01T00:00:00Z",
+			},
+			"Config": map[string]any{
+				"Image":  c.image,
+				"Labels": c.labels,
+			},
+			"NetworkSettings": map[string]any{
+				"Ports": ports,
+			},
+		})
+	}
+	return json.Marshal(results)
+}
+
+// image simulates "docker image ls -q" and "docker image inspect"
+func (f *Fake) image(args []string) ([]byte, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("fake runtime: missing image name")
+	}
+	name := args[len(args)-1]
+	img, ok := f.images[name]
+	switch args[0] {
+	case "ls":
+		if !ok {
+			return nil, nil
+		}
+		return []byte(img.id + "\n"), nil
+	case "inspect":
+		if !ok {
+			return nil, fmt.Errorf("Error: No such image: %s", name)
+		}
+		return json.Marshal([]map[string]any{{
+			"Id":          img.id,
+			"RepoTags":    []string{name},
+			"RepoDigests": img.repoDigests,
+		}})
+	}
+	return nil, fmt.Errorf("fake runtime: unsupported image command %q", args[0])
+}
+
+// imageTransfer simulates "docker pull/tag/save/load"
+func (f *Fake) imageTransfer(args []string) ([]byte, error) {
+	switch {
+	case args[0] == "pull" && len(args) == 2:
+		// A digest reference is its own repo digest, a tag gets the digest of its manifest
+		img := &fakeImage{id: imageID(args[1]), repoDigests: []string{args[1]}}
+		if !strings.Contains(args[1], "@") {
+			ref, _ := container.ParseImageRef(args[1])
+			img.repoDigests = []string{ref.Repository + "@" + ManifestDigest(args[1])}
+		}
+		f.images[args[1]] = img
+		return nil, nil
+	case args[0] == "tag" && len(args) == 3:
+		img, ok := f.images[args[1]]
+		if !ok {
+			return nil, fmt.Errorf("Error response from daemon: No such image: %s", args[1])
+		}
+		f.images[args[2]] = img
+		return nil, nil
+	case args[0] == "save" && len(args) == 4 && args[1] == "-o":
+		img, ok := f.images[args[3]]
+		if !ok {
+			return nil, fmt.Errorf("Error response from daemon: reference does not exist")
+		}
+		// Registry digests are not part of the archive
+		data, _ := json.Marshal(map[string]string{"name": args[3], "id": img.id})
+		return nil, os.WriteFile(args[2], data, 0644)
+	case args[0] == "load" && len(args) == 3 && args[1] == "-i":
+		data, err := os.ReadFile(args[2])
+		if err != nil {
+			return nil, fmt.Errorf("open %s: no such file or directory", args[2])
+		}
+		var archive map[string]string
+		if err := json.Unmarshal(data, &archive); err != nil {
+			return nil, fmt.Errorf("fake runtime: invalid image archive %s", args[2])
+		}
+		f.images[archive["name"]] = &fakeImage{id: archive["id"]}
+		return []byte("Loaded image: " + archive["name"] + "\n"), nil
+	}
+	return nil, fmt.Errorf("fake runtime: unsupported command %q", strings.Join(args, " "))
+}
+
+// build simulates "docker build -t name... context": the image ID depends on the Dockerfile
+func (f *Fake) build(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("fake runtime: missing build context")
+	}
+	dockerfile, err := os.ReadFile(filepath.Join(args[len(args)-1], "Dockerfile"))
+	if err != nil {
+		return fmt.Errorf("failed to read dockerfile: open Dockerfile: no such file or directory")
+	}
+	img := &fakeImage{id: imageID(string(dockerfile))}
+	for i := 0; i < len(args)-2; i++ {
+		if args[i] == "-t" {
+			f.images[args[i+1]] = img
+		}
+	}
+	return nil
+}
+
+// systemctl simulates the systemctl commands of the otori services
+func (f *Fake) systemctl(args []string) ([]byte, error) {
+	scope := "system"
+	if len(args) > 0 && args[0] == "--user" {
+		scope, args = "user", args[1:]
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("fake runtime: missing systemctl command")
+	}
+	if args[0] == "daemon-reload" {
+		return nil, nil
+	}
+
+	name := args[len(args)-1]
+	unit, ok := f.units[scope+"/"+name]
+	if !ok {
+		unit = &fakeUnit{}
+		f.units[scope+"/"+name] = unit
+	}
+	now := contains(args, "--now")
+	switch args[0] {
+	case "enable":
+		unit.enabled = true
+		unit.active = unit.active || now
+	case "disable":
+		unit.enabled = false
+		unit.active = unit.active && !now
+	case "start", "restart", "reload":
+		unit.active = true
+	case "stop":
+		unit.active = false
+	case "is-active":
+		if !unit.active {
+			return []byte("inactive\n"), fmt.Errorf("exit status 3")
+		}
+		return []byte("active\n"), nil
+	case "is-enabled":
+		if !unit.enabled {
+			return []byte("disabled\n"), fmt.Errorf("exit status 1")
+		}
+		return []byte("enabled\n"), nil
+	default:
+		return nil, fmt.Errorf("fake runtime: unsupported systemctl command %q", args[0])
+	}
+	return nil, nil
+}
+
+// volume simulates "docker volume inspect/create/rm"
+func (f *Fake) volume(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("fake runtime: missing volume name")
+	}
+	name := args[1]
+	switch args[0] {
+	case "inspect":
+		if !f.volumes[name] {
+			return fmt.Errorf("Error response from daemon: get %s: no such volume", name)
+		}
+	case "create":
+		f.volumes[name] = true
+	case "rm":
+		if !f.volumes[name] {
+			return fmt.Errorf("Error response from daemon: get %s: no such volume", name)
+		}
+		delete(f.volumes, name)
+	default:
+		return fmt.Errorf("fake runtime: unsupported volume command %q", args[0])
+	}
+	return nil
+}
+
+// names returns the container names in order
+func (f *Fake) names() []string {
+	names := make([]string, 0, len(f.containers))
+	for name := range f.containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// containerID returns a stable 64 hex digits id for a container name
+func containerID(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+// imageID returns a stable image ID for an image name
+func imageID(name string) string {
+	sum := sha256.Sum256([]byte("image:" + name))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ManifestDigest returns the registry digest of an image pulled by tag
+func ManifestDigest(name string) string {
+	sum := sha256.Sum256([]byte("manifest:" + name))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// contains returns true if args contains arg
+func contains(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Labels written on containers at deploy time (see config.DockerComposeTemplate)
+const (
+	LabelProfile    = "otori.profile"
+	LabelConfigHash = "otori.config-hash"
+	LabelVersion    = "otori.version"
+)
+
+// CowrieSSHPort is the port Cowrie listens on for SSH inside its container
+const CowrieSSHPort = 2222
+
+// Container is the state of a honeypot container as reported by Docker
+type Container struct {
+	ID           string
+	Name         string
+	Image        string // image name of the container configuration
+	ImageID      string // ID of the image the container runs
+	Labels       map[string]string
+	State        string // created, running, paused, restarting, exited, dead
+	Health       string // healthy, unhealthy, starting or empty
+	ExitCode     int
+	Error        string
+	RestartCount int
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	Ports        []int       // published host ports
+	PortMap      map[int]int // container port -> published host port
+}
+
+// HostPort returns the host port publishing a TCP port of the container (0 if not published)
+func (c Container) HostPort(containerPort int) int {
+	return c.PortMap[containerPort]
+}
+
+// Profile returns the otori profile of the container (empty if not labelled)
+func (c Container) Profile() string {
+	return c.Labels[LabelProfile]
+}
+
+// inspectResult matches the fields of "docker inspect" used by otori
+type inspectResult struct {
+	ID           string `json:"Id"`
+	Name         string `json:"Name"`
+	Image        string `json:"Image"`
+	RestartCount int    `json:"RestartCount"`
+	State        struct {
+		Status     string `json:"Status"`
+		ExitCode   int    `json:"ExitCode"`
+		Error      string `json:"Error"`
+		StartedAt  string `json:"StartedAt"`
+		FinishedAt string `json:"FinishedAt"`
+		Health     *struct {
+			Status string `json:"Status"`
+			Log    []struct {
+				Output string `json:"Output"`
+			} `json:"Log"`
+		} `json:"Health"`
+	} `json:"State"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	NetworkSettings struct {
+		Ports map[string][]struct {
+			HostPort string `json:"HostPort"`
+		} `json:"Ports"`
+	} `json:"NetworkSettings"`
+}
+
+// ListContainers returns every container (running or not) that belongs to otori.
+// A container belongs to otori when it carries the otori.profile label, or,
+// for deployments made before labels existed, when its name is otori-{profile}
+// for one of the given known profiles.
+func ListContainers(knownProfiles []string) ([]Container, error) {
+	output, err := Run(Docker("ps", "-a", "--no-trunc",
+		"--format", `{{.ID}}|{{.Names}}|{{.Label "`+LabelProfile+`"}}`))
+	if err != nil {
+		return nil, fmt.Errorf("error listing containers: %w", err)
+	}
+
+	legacy := make(map[string]string)
+	for _, p := range knownProfiles {
+		legacy["otori-"+p] = p
+	}
+
+	var ids []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.Split(line, "|")
+		if len(parts) < 3 {
+			continue
+		}
+		if parts[2] != "" || legacy[parts[1]] != "" {
+			ids = append(ids, parts[0])
+		}
+	}
+
+	containers, err := Inspect(ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Give legacy containers their profile label
+	for i := range containers {
+		if containers[i].Profile() == "" {
+			if p, ok := legacy[containers[i].Name]; ok {
+				containers[i].Labels[LabelProfile] = p
+			}
+		}
+	}
+
+	return containers, nil
+}
+
+// Inspect returns the detailed state of the given containers
+func Inspect(ids ...string) ([]Container, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	output, err := Run(Docker(append([]string{"inspect"}, ids...)...))
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting containers: %w", err)
+	}
+
+	var results []inspectResult
+	if err := json.Unmarshal(output, &results); err != nil {
+		return nil, fmt.Errorf("error decoding docker inspect: %w", err)
+	}
+
+	containers := make([]Container, 0, len(results))
+	for _, r := range results {
+		c := Container{
+			ID:           r.ID,
+			Name:         strings.TrimPrefix(r.Name, "/"),
+			Image:        r.Config.Image,
+			ImageID:      r.Image,
+			Labels:       r.Config.Labels,
+			State:        r.State.Status,
+			ExitCode:     r.State.ExitCode,
+			Error:        r.State.Error,
+			RestartCount: r.RestartCount,
+			StartedAt:    parseDockerTime(r.State.StartedAt),
+			FinishedAt:   parseDockerTime(r.State.FinishedAt),
+		}
+		if c.Labels == nil {
+			c.Labels = make(map[string]string)
+		}
+		if r.State.Health != nil {
+			c.Health = r.State.Health.Status
+			// Keep the output of the last failed health check as error
+			if c.Health == "unhealthy" && c.Error == "" && len(r.State.Health.Log) > 0 {
+				c.Error = strings.TrimSpace(r.State.Health.Log[len(r.State.Health.Log)-1].Output)
+			}
+		}
+		for target, bindings := range r.NetworkSettings.Ports {
+			for _, b := range bindings {
+				if port, err := strconv.Atoi(b.HostPort); err == nil {
+					c.Ports = append(c.Ports, port)
+					if number, ok := strings.CutSuffix(target, "/tcp"); ok {
+						if containerPort, err := strconv.Atoi(number); err == nil && c.PortMap[containerPort] == 0 {
+							if c.PortMap == nil {
+								c.PortMap = make(map[int]int)
+							}
+							c.PortMap[containerPort] = port
+						}
+					}
+				}
+			}
+		}
+		sort.Ints(c.Ports)
+		containers = append(containers, c)
+	}
+
+	return containers, nil
+}
+
+// parseDockerTime parses Docker timestamps (zero value "0001-01-01T00:00:00Z" stays zero)
+func parseDockerTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil || t.Year() <= 1 {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,257 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LabelImage is the container label holding the image reference of the profile, digest included
+const LabelImage = "otori.image"
+
+// ErrDigestMismatch is returned when the local image is not the one pinned by a digest
+var ErrDigestMismatch = errors.New("does not match the pinned digest")
+
+// digestPattern matches the digest part of a pinned reference
+var digestPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// ImageRef is an image reference: repository[:tag][@sha256:<digest>][@id=sha256:<image ID>].
+// A pin holds both digests: the registry (manifest) digest to pull the image
+// and the image ID, kept by "docker save/load" which drops the registry digest.
+type ImageRef struct {
+	Repository string // e.g. cowrie/cowrie or registry.local:5000/cowrie
+	Tag        string // latest when not given
+	Digest     string // registry digest sha256:<64 hex digits>, empty when not pinned
+	ID         string // image ID sha256:<64 hex digits>, empty when not pinned
+}
+
+// ParseImageRef parses and checks an image reference
+func ParseImageRef(s string) (ImageRef, error) {
+	var ref ImageRef
+	if s == "" || strings.ContainsAny(s, " \t\n") {
+		return ref, fmt.Errorf("invalid image reference '%s'", s)
+	}
+
+	parts := strings.Split(s, "@")
+	name := parts[0]
+	for _, pin := range parts[1:] {
+		id, isID := strings.CutPrefix(pin, "id=")
+		switch {
+		case !digestPattern.MatchString(id):
+			return ref, fmt.Errorf("invalid image reference '%s': a digest must be sha256:<64 hex digits>", s)
+		case isID && ref.ID == "":
+			ref.ID = id
+		case !isID && ref.Digest == "":
+			ref.Digest = id
+		default:
+			return ref, fmt.Errorf("invalid image reference '%s': digest given twice", s)
+		}
+	}
+
+	// The tag follows the last ':' of the last path element (registry:port/repo:tag)
+	ref.Repository, ref.Tag = name, "latest"
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Repository, ref.Tag = name[:i], name[i+1:]
+	}
+	if ref.Repository == "" || ref.Tag == "" || strings.HasSuffix(ref.Repository, "/") {
+		return ref, fmt.Errorf("invalid image reference '%s'", s)
+	}
+	return ref, nil
+}
+
+// Pinned returns true if the reference holds a digest or an image ID
+func (r ImageRef) Pinned() bool {
+	return r.Digest != "" || r.ID != ""
+}
+
+// String returns the full reference, digests included
+func (r ImageRef) String() string {
+	if !r.Pinned() {
+		return r.Tagged()
+	}
+	return r.Tagged() + "@" + r.pin()
+}
+
+// pin returns the digest part of a pinned reference
+func (r ImageRef) pin() string {
+	switch {
+	case r.ID == "":
+		return r.Digest
+	case r.Digest == "":
+		return "id=" + r.ID
+	}
+	return r.Digest + "@id=" + r.ID
+}
+
+// Tagged returns the repository:tag name of the image, used by docker-compose.yml
+func (r ImageRef) Tagged() string {
+	return r.Repository + ":" + r.Tag
+}
+
+// Image is a local image as reported by "docker image inspect"
+type Image struct {
+	ID          string   `json:"Id"`
+	RepoTags    []string `json:"RepoTags"`
+	RepoDigests []string `json:"RepoDigests"`
+}
+
+// Matches returns true if the image is the one pinned by a reference: its
+// image ID, which survives save/load, or its registry digest (RepoDigests),
+// known when the image was pulled. A single digest may be either.
+func (img Image) Matches(ref ImageRef) bool {
+	if ref.ID != "" && img.ID == ref.ID {
+		return true
+	}
+	if ref.ID == "" && img.ID == ref.Digest {
+		return true
+	}
+	for _, repoDigest := range img.RepoDigests {
+		if ref.Digest != "" && strings.HasSuffix(repoDigest, "@"+ref.Digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pin returns a reference pinned with the registry digest and the ID of the image
+func (img Image) Pin(ref ImageRef) ImageRef {
+	ref.Digest, ref.ID = "", img.ID
+	for _, repoDigest := range img.RepoDigests {
+		repository, digest, _ := strings.Cut(repoDigest, "@")
+		if ref.Digest == "" || repository == ref.Repository {
+			ref.Digest = digest
+		}
+		if repository == ref.Repository {
+			break
+		}
+	}
+	return ref
+}
+
+// InspectImage returns a local image, nil if it is not present
+func InspectImage(name string) (*Image, error) {
+	// "image inspect" fails the same way for a missing image and a stopped daemon
+	ids, err := Run(Docker("image", "ls", "-q", name))
+	if err != nil {
+		return nil, fmt.Errorf("error listing images: %w", err)
+	}
+	if strings.TrimSpace(string(ids)) == "" {
+		return nil, nil
+	}
+
+	output, err := Run(Docker("image", "inspect", name))
+	if err != nil {
+		return nil, fmt.Errorf("error inspecting image %s: %w", name, err)
+	}
+
+	var images []Image
+	if err := json.Unmarshal(output, &images); err != nil {
+		return nil, fmt.Errorf("error decoding docker image inspect: %w", err)
+	}
+	if len(images) == 0 {
+		return nil, nil
+	}
+	return &images[0], nil
+}
+
+// PullImage pulls an image. A pinned image is pulled by its registry digest,
+// then tagged with its repository:tag name so that docker-compose.yml finds it.
+// A reference pinned by its image ID only is pulled by tag and checked by VerifyImage.
+func PullImage(ref ImageRef) error {
+	if ref.Digest == "" {
+		if _, err := Run(Docker("pull", ref.Tagged())); err != nil {
+			return fmt.Errorf("error pulling %s: %w", ref, err)
+		}
+		return nil
+	}
+
+	byDigest := ref.Repository + "@" + ref.Digest
+	if _, err := Run(Docker("pull", byDigest)); err != nil {
+		return fmt.Errorf("error pulling %s: %w", ref, err)
+	}
+	if _, err := Run(Docker("tag", byDigest, ref.Tagged())); err != nil {
+		return fmt.Errorf("error tagging %s: %w", ref.Tagged(), err)
+	}
+	return nil
+}
+
+// VerifyImage checks that the local image of a pinned reference matches its digest.
+// A reference without digest is not checked.
+func VerifyImage(ref ImageRef) (*Image, error) {
+	img, err := InspectImage(ref.Tagged())
+	if err != nil || !ref.Pinned() {
+		return img, err
+	}
+	if img == nil {
+		return nil, fmt.Errorf("image %s is not present (run 'otori image pull' or 'otori image load')", ref.Tagged())
+	}
+	if !img.Matches(ref) {
+		return img, fmt.Errorf("local image %s (%s) %w %s", ref.Tagged(), ShortID(img.ID), ErrDigestMismatch, ref.pin())
+	}
+	return img, nil
+}
+
+// PinImage returns an unpinned reference pinned with its local image, pulled
+// by tag when it is missing. A pinned reference is returned as is.
+func PinImage(ref ImageRef) (ImageRef, error) {
+	if ref.Pinned() {
+		return ref, nil
+	}
+	img, err := InspectImage(ref.Tagged())
+	if err != nil {
+		return ref, err
+	}
+	if img == nil {
+		if err := PullImage(ref); err != nil {
+			return ref, err
+		}
+		if img, err = InspectImage(ref.Tagged()); err != nil {
+			return ref, err
+		}
+		if img == nil {
+			return ref, fmt.Errorf("image %s is not present after pull", ref.Tagged())
+		}
+	}
+	return img.Pin(ref), nil
+}
+
+// SaveImage writes an image to a tar archive ("docker save")
+func SaveImage(name, file string) error {
+	if _, err := Run(Docker("save", "-o", file, name)); err != nil {
+		return fmt.Errorf("error saving %s: %w", name, err)
+	}
+	return nil
+}
+
+// LoadImage loads the images of a tar archive ("docker load") and returns their names
+func LoadImage(file string) ([]string, error) {
+	output, err := Run(Docker("load", "-i", file))
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", file, err)
+	}
+
+	// "Loaded image: cowrie/cowrie:latest" or "Loaded image ID: sha256:..."
+	var names []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if _, name, ok := strings.Cut(line, "Loaded image: "); ok {
+			names = append(names, strings.TrimSpace(name))
+		} else if _, id, ok := strings.Cut(line, "Loaded image ID: "); ok {
+			names = append(names, strings.TrimSpace(id))
+		} else if _, name, ok := strings.Cut(line, "Loaded image(s): "); ok {
+			// podman
+			names = append(names, strings.Split(strings.TrimSpace(name), ",")...)
+		}
+	}
+	return names, nil
+}
+
+// ShortID returns the 12 first hex digits of an image or container ID
+func ShortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
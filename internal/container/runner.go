@@ -0,0 +1,96 @@
+package container
+
+import (
+	"context"
+	"io"
+	"os/exec"
+)
+
+// Command is an external command run by otori (docker, docker compose, systemctl)
+type Command struct {
+	Name    string
+	Args    []string
+	Dir     string          // working directory (empty: current directory)
+	Stdin   io.Reader       // nil: no input
+	Stdout  io.Writer       // nil: the output is captured and returned by Run
+	Stderr  io.Writer       // nil: discarded
+	Context context.Context // kills the process when done (nil: never)
+}
+
+// Runner runs external commands. The default runner starts real processes,
+// tests replace it with a fake (see containertest.Fake).
+type Runner interface {
+	Run(cmd Command) ([]byte, error)
+}
+
+// ExecRunner runs commands as local processes
+type ExecRunner struct{}
+
+// Run starts the command and waits for it.
+// The output is returned when the command has no Stdout.
+func (ExecRunner) Run(c Command) ([]byte, error) {
+	cmd := exec.Command(c.Name, c.Args...)
+	if c.Context != nil {
+		cmd = exec.CommandContext(c.Context, c.Name, c.Args...)
+	}
+	cmd.Dir = c.Dir
+	cmd.Stdin = c.Stdin
+	cmd.Stderr = c.Stderr
+	if c.Stdout != nil {
+		cmd.Stdout = c.Stdout
+		return nil, cmd.Run()
+	}
+	return cmd.Output()
+}
+
+// runner is the Runner used by otori for every docker command
+var runner Runner = ExecRunner{}
+
+// SetRunner replaces the runner of docker commands and returns the previous one
+func SetRunner(r Runner) Runner {
+	previous := runner
+	runner = r
+	return previous
+}
+
+// runtimeName is the container CLI run by otori (see SetRuntime)
+var runtimeName = "docker"
+
+// SetRuntime sets the container CLI: docker or podman (same commands and flags)
+func SetRuntime(name string) {
+	runtimeName = name
+}
+
+// Runtime returns the container CLI run by otori
+func Runtime() string {
+	return runtimeName
+}
+
+// Docker returns a command of the container CLI with the given arguments
+func Docker(args ...string) Command {
+	return Command{Name: runtimeName, Args: args}
+}
+
+// RuntimeError is the failure of a command of the container CLI (or systemctl).
+// Its message is the one of the underlying error.
+type RuntimeError struct {
+	Args []string
+	Err  error
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// Run runs a command with the current runner, its failures are *RuntimeError
+func Run(cmd Command) ([]byte, error) {
+	output, err := runner.Run(cmd)
+	if err != nil {
+		return output, &RuntimeError{Args: cmd.Args, Err: err}
+	}
+	return output, nil
+}
@@ -0,0 +1,105 @@
+package container
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// HelperImage is the small image used to copy data between volumes
+const HelperImage = "alpine:3"
+
+// VolumeKinds are the suffixes of the named volumes of a profile (see config.DockerComposeTemplate)
+var VolumeKinds = []string{"logs", "downloads"}
+
+// ProfileVolume returns the name of a volume of a profile (otori-{profile}-{kind})
+func ProfileVolume(profileName, kind string) string {
+	return "otori-" + profileName + "-" + kind
+}
+
+// ProfileVolumes returns the named volumes of a profile
+func ProfileVolumes(profileName string) []string {
+	volumes := make([]string, 0, len(VolumeKinds))
+	for _, kind := range VolumeKinds {
+		volumes = append(volumes, ProfileVolume(profileName, kind))
+	}
+	return volumes
+}
+
+// VolumeExists returns true if a named volume exists
+func VolumeExists(name string) bool {
+	_, err := Run(Docker("volume", "inspect", name))
+	return err == nil
+}
+
+// CopyVolume copies the content of a volume into another one (created if missing)
+func CopyVolume(src, dst string) error {
+	if err := runDocker("volume", "create", dst); err != nil {
+		return err
+	}
+	return runDocker("run", "--rm",
+		"-v", src+":/from:ro",
+		"-v", dst+":/to",
+		HelperImage, "sh", "-c", "cp -a /from/. /to/")
+}
+
+// ExportVolume writes the content of a volume to a tar.gz file on the host
+func ExportVolume(name, archivePath string) error {
+	dir, file, err := splitHostPath(archivePath)
+	if err != nil {
+		return err
+	}
+	return runDocker("run", "--rm",
+		"-v", name+":/data:ro",
+		"-v", dir+":/backup",
+		HelperImage, "tar", "czf", "/backup/"+file, "-C", "/data", ".")
+}
+
+// ImportVolume creates a volume (if missing) and extracts a tar.gz written by ExportVolume into it
+func ImportVolume(name, archivePath string) error {
+	dir, file, err := splitHostPath(archivePath)
+	if err != nil {
+		return err
+	}
+	if err := runDocker("volume", "create", name); err != nil {
+		return err
+	}
+	return runDocker("run", "--rm",
+		"-v", name+":/data",
+		"-v", dir+":/backup:ro",
+		HelperImage, "tar", "xzf", "/backup/"+file, "-C", "/data")
+}
+
+// splitHostPath returns the absolute directory and the file name of a host path (for bind mounts)
+func splitHostPath(p string) (string, string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Dir(abs), filepath.Base(abs), nil
+}
+
+// Remove force-removes a container (stopping it if needed)
+func Remove(id string) error {
+	return runDocker("rm", "-f", id)
+}
+
+// RemoveVolume deletes a named volume
+func RemoveVolume(name string) error {
+	return runDocker("volume", "rm", name)
+}
+
+// runDocker runs a command of the container CLI and returns its stderr on failure
+func runDocker(args ...string) error {
+	var stderr bytes.Buffer
+	cmd := Docker(args...)
+	cmd.Stderr = &stderr
+	if _, err := Run(cmd); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s %s: %s: %w", Runtime(), args[0], msg, err)
+		}
+		return fmt.Errorf("%s %s: %w", Runtime(), args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Count is the number of events sharing the same aggregation key
+type Count struct {
+	Key       string `json:"key"`
+	Label     string `json:"label,omitempty"`
+	Events    int    `json:"events"`
+	UniqueIPs int    `json:"unique_ips"`
+}
+
+// KeyFunc extracts the aggregation key and a human readable label from an event
+type KeyFunc func(ev Event) (key, label string)
+
+// ByIP aggregates events by source IP
+func ByIP(ev Event) (string, string) {
+	return ev.SrcIP, ""
+}
+
+// ByCountry aggregates events by source country (needs GeoIP enrichment)
+func ByCountry(ev Event) (string, string) {
+	if ev.Country == "" {
+		return "??", "unknown"
+	}
+	return ev.Country, ev.CountryName
+}
+
+// ByASN aggregates events by autonomous system (needs GeoIP enrichment)
+func ByASN(ev Event) (string, string) {
+	if ev.ASN == 0 {
+		return "AS?", "unknown"
+	}
+	return fmt.Sprintf("AS%d", ev.ASN), ev.ASOrg
+}
+
+// CountBy groups events by key, sorted by decreasing number of events.
+// Events without source IP (e.g. sensor messages) are ignored.
+func CountBy(evs []Event, keyFn KeyFunc) []Count {
+	counts := make(map[string]*Count)
+	ips := make(map[string]map[string]bool)
+
+	for _, ev := range evs {
+		if ev.SrcIP == "" {
+			continue
+		}
+		key, label := keyFn(ev)
+		c, ok := counts[key]
+		if !ok {
+			c = &Count{Key: key, Label: label}
+			counts[key] = c
+			ips[key] = make(map[string]bool)
+		}
+		c.Events++
+		if !ips[key][ev.SrcIP] {
+			ips[key][ev.SrcIP] = true
+			c.UniqueIPs++
+		}
+	}
+
+	result := make([]Count, 0, len(counts))
+	for _, c := range counts {
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Events != result[j].Events {
+			return result[i].Events > result[j].Events
+		}
+		return result[i].Key < result[j].Key
+	})
+
+	return result
+}
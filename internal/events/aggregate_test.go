@@ -0,0 +1,28 @@
+package events_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/otori-lab/otori-cli/internal/events"
+)
+
+func TestCountByIP(t *testing.T) {
+	evs := []events.Event{
+		{SrcIP: "198.51.100.2"},
+		{SrcIP: "198.51.100.1"},
+		{SrcIP: "198.51.100.2"},
+		{Message: "sensor started"},
+		{SrcIP: "198.51.100.3"},
+	}
+
+	got := events.CountBy(evs, events.ByIP)
+	want := []events.Count{
+		{Key: "198.51.100.2", Events: 2, UniqueIPs: 1},
+		{Key: "198.51.100.1", Events: 1, UniqueIPs: 1},
+		{Key: "198.51.100.3", Events: 1, UniqueIPs: 1},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("by IP: %+v, want %+v", got, want)
+	}
+}
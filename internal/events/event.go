@@ -0,0 +1,87 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// Cowrie event identifiers used by otori
+const (
+	EventSessionConnect = "cowrie.session.connect"
+	EventSessionClosed  = "cowrie.session.closed"
+	EventLoginSuccess   = "cowrie.login.success"
+	EventLoginFailed    = "cowrie.login.failed"
+	EventCommandInput   = "cowrie.command.input"
+	EventFileDownload   = "cowrie.session.file_download"
+	EventFileUpload     = "cowrie.session.file_upload"
+	EventClientVersion  = "cowrie.client.version"
+	EventLogClosed      = "cowrie.log.closed"
+)
+
+// Event represents a single entry of Cowrie's JSON log (cowrie.json)
+type Event struct {
+	EventID   string    `json:"eventid"`
+	Timestamp time.Time `json:"timestamp"`
+	Session   string    `json:"session,omitempty"`
+	SrcIP     string    `json:"src_ip,omitempty"`
+	SrcPort   int       `json:"src_port,omitempty"`
+	DstPort   int       `json:"dst_port,omitempty"`
+	Sensor    string    `json:"sensor,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	Password  string    `json:"password,omitempty"`
+	Input     string    `json:"input,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	Filename  string    `json:"filename,omitempty"`
+	Shasum    string    `json:"shasum,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	TTYLog    string    `json:"ttylog,omitempty"`
+
+	// Fields added by otori (not written by Cowrie)
+	Profile     string `json:"profile,omitempty"`
+	Country     string `json:"country,omitempty"`      // ISO 3166 code
+	CountryName string `json:"country_name,omitempty"` // English name
+	ASN         uint   `json:"asn,omitempty"`
+	ASOrg       string `json:"as_org,omitempty"`
+}
+
+// ParseEvent decodes a single cowrie.json line
+func ParseEvent(line []byte) (Event, error) {
+	var ev Event
+	if err := json.Unmarshal(line, &ev); err != nil {
+		return ev, err
+	}
+	return ev, nil
+}
+
+// ReadEvents decodes every event of a cowrie.json stream.
+// Malformed lines are skipped: Cowrie may be writing the last line while we read.
+func ReadEvents(r io.Reader) ([]Event, error) {
+	var evs []Event
+	err := ScanEvents(r, func(ev Event) {
+		evs = append(evs, ev)
+	})
+	return evs, err
+}
+
+// ScanEvents calls fn for every event read from r until EOF
+func ScanEvents(r io.Reader, fn func(Event)) error {
+	scanner := bufio.NewScanner(r)
+	// Command inputs can be long, allow lines up to 1 MiB
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		ev, err := ParseEvent([]byte(line))
+		if err != nil {
+			continue
+		}
+		fn(ev)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,180 @@
+package events
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/otori-lab/otori-cli/internal/container"
+)
+
+// CowrieLogPath is the path of the JSON log inside the Cowrie container
+const CowrieLogPath = "/cowrie/cowrie-git/var/log/cowrie/cowrie.json"
+
+// ContainerName returns the container name of a profile (otori-{profile})
+func ContainerName(profileName string) string {
+	return "otori-" + profileName
+}
+
+// ReadProfileEvents reads all events logged by a profile's container.
+// It uses "docker cp" so that logs of stopped containers can still be read.
+func ReadProfileEvents(profileName string) ([]Event, error) {
+	data, err := copyProfileLog(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	evs, err := ReadEvents(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	tagProfile(evs, profileName)
+	return evs, nil
+}
+
+// ReadProfileLog reads the complete lines logged by a profile's container and
+// returns their events with the byte offset following them, to follow the log
+// from there (see FollowProfileFrom)
+func ReadProfileLog(profileName string) ([]Event, int64, error) {
+	data, err := copyProfileLog(profileName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The last line may still be written by Cowrie: the follower reads it
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+	evs, err := ReadEvents(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+	tagProfile(evs, profileName)
+	return evs, int64(len(data)), nil
+}
+
+// logFile identifies the log of a running profile's container
+type logFile struct {
+	size  int64
+	inode uint64 // 0: unknown
+}
+
+// statProfileLog returns the size and inode of the log of a running profile's container
+func statProfileLog(profileName string) (logFile, error) {
+	output, err := container.Run(container.Docker("exec", ContainerName(profileName), "stat", "-c", "%s %i", CowrieLogPath))
+	if err != nil {
+		return logFile{}, fmt.Errorf("error reading logs of '%s': %w", profileName, err)
+	}
+	var log logFile
+	if _, err := fmt.Sscan(string(output), &log.size, &log.inode); err != nil {
+		return logFile{}, fmt.Errorf("error reading logs of '%s': unexpected stat output %q", profileName, output)
+	}
+	return log, nil
+}
+
+// copyProfileLog returns the content of the log of a profile's container
+func copyProfileLog(profileName string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := container.Docker("cp", ContainerName(profileName)+":"+CowrieLogPath, "-")
+	cmd.Stderr = &stderr
+	output, err := container.Run(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("error reading logs of '%s': %s", profileName, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	// docker cp writes a tar archive containing the single log file
+	tr := tar.NewReader(bytes.NewReader(output))
+	if _, err := tr.Next(); err != nil {
+		return nil, fmt.Errorf("error reading log archive: %w", err)
+	}
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return nil, fmt.Errorf("error reading log archive: %w", err)
+	}
+	return data, nil
+}
+
+// ReadFileEvents reads events from a local cowrie.json file
+func ReadFileEvents(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening log file: %w", err)
+	}
+	defer f.Close()
+
+	return ReadEvents(f)
+}
+
+// FollowProfile streams new events of a running profile container until ctx is done
+func FollowProfile(ctx context.Context, profileName string, fn func(Event)) error {
+	return followProfile(ctx, profileName, []string{"-n", "0"}, 0, func(ev Event, _ int64) {
+		fn(ev)
+	})
+}
+
+// FollowProfileFrom streams the events of a running profile container logged
+// from a byte offset (see ReadProfileLog) until ctx is done. fn receives each
+// event with the offset following its line.
+func FollowProfileFrom(ctx context.Context, profileName string, offset int64, fn func(ev Event, end int64)) error {
+	return followProfile(ctx, profileName, []string{"-c", fmt.Sprintf("+%d", offset+1)}, offset, fn)
+}
+
+// followProfile runs tail -F in the container of a profile, with the arguments
+// choosing where it starts, and decodes every complete line
+func followProfile(ctx context.Context, profileName string, from []string, offset int64, fn func(Event, int64)) error {
+	args := append([]string{"exec", ContainerName(profileName), "tail"}, from...)
+	cmd := container.Docker(append(args, "-F", CowrieLogPath)...)
+	cmd.Context = ctx
+
+	// The output is decoded while the command runs
+	stdout, pw := io.Pipe()
+	cmd.Stdout = pw
+	done := make(chan error, 1)
+	go func() {
+		_, err := container.Run(cmd)
+		pw.Close()
+		done <- err
+	}()
+
+	var readErr error
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			// A partial last line is read again from the same offset next time
+			if err != io.EOF {
+				readErr = err
+			}
+			break
+		}
+		offset += int64(len(line))
+		if ev, err := ParseEvent(bytes.TrimSpace(line)); err == nil {
+			ev.Profile = profileName
+			fn(ev, offset)
+		}
+	}
+
+	// Drain in case the reader stopped early, then wait for the process
+	io.Copy(io.Discard, stdout)
+	waitErr := <-done
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	if readErr != nil {
+		return readErr
+	}
+	if waitErr != nil {
+		return fmt.Errorf("log stream of '%s' ended: %w", profileName, waitErr)
+	}
+	return nil
+}
+
+// tagProfile sets the profile name on every event
+func tagProfile(evs []Event, profileName string) {
+	for i := range evs {
+		evs[i].Profile = profileName
+	}
+}
@@ -0,0 +1,41 @@
+package events_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/otori-lab/otori-cli/internal/container/containertest"
+	"github.com/otori-lab/otori-cli/internal/events"
+)
+
+func TestFollowProfileFromFake(t *testing.T) {
+	fake := containertest.NewFake()
+	t.Cleanup(fake.Install())
+
+	// The partial last line is left to the next follow
+	first, second := loginLine("root"), loginLine("admin")
+	fake.SetOutput("exec otori-web tail", []byte(first+second+`{"eventid": "cowrie.login`))
+
+	var usernames []string
+	var ends []int64
+	err := events.FollowProfileFrom(context.Background(), "web", 100, func(ev events.Event, end int64) {
+		usernames = append(usernames, ev.Username)
+		ends = append(ends, end)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(usernames, []string{"root", "admin"}) {
+		t.Errorf("usernames = %q", usernames)
+	}
+	if want := []int64{100 + int64(len(first)), 100 + int64(len(first)+len(second))}; !slices.Equal(ends, want) {
+		t.Errorf("ends = %v, want %v", ends, want)
+	}
+
+	want := "exec otori-web tail -c +101 -F " + events.CowrieLogPath
+	if got := strings.Join(fake.Commands(), "\n"); got != want {
+		t.Errorf("commands:\n%s\nwant %s", got, want)
+	}
+}
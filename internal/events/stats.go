@@ -0,0 +1,271 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// recentEvents is the number of events kept for drill-down views
+const recentEvents = 50
+
+// Stats holds live counters of a honeypot
+type Stats struct {
+	Connections     int            `json:"connections"`
+	LoginAttempts   int            `json:"login_attempts"`
+	LoginSuccesses  int            `json:"login_successes"`
+	Commands        int            `json:"commands"`
+	Downloads       int            `json:"downloads"`
+	UniqueIPs       int            `json:"unique_ips"`
+	LastEventID     string         `json:"last_event_id,omitempty"`
+	LastEventAt     time.Time      `json:"last_event_at,omitempty"`
+	LastTTYLog      string         `json:"last_ttylog,omitempty"`
+	Lag             time.Duration  `json:"lag"` // delay between Cowrie logging the last live event and otori reading it
+	AttemptsByUser  map[string]int `json:"attempts_by_user,omitempty"`
+	SuccessesByUser map[string]int `json:"successes_by_user,omitempty"`
+	Recent          []Event        `json:"-"`
+
+	ips map[string]bool
+}
+
+// Add updates the counters with a new event
+func (s *Stats) Add(ev Event) {
+	switch ev.EventID {
+	case EventSessionConnect:
+		s.Connections++
+	case EventLoginSuccess:
+		s.LoginAttempts++
+		s.LoginSuccesses++
+		s.countUser(&s.AttemptsByUser, ev.Username)
+		s.countUser(&s.SuccessesByUser, ev.Username)
+	case EventLoginFailed:
+		s.LoginAttempts++
+		s.countUser(&s.AttemptsByUser, ev.Username)
+	case EventCommandInput:
+		s.Commands++
+	case EventFileDownload:
+		s.Downloads++
+	case EventLogClosed:
+		if ev.TTYLog != "" {
+			s.LastTTYLog = ev.TTYLog
+		}
+	}
+
+	if ev.SrcIP != "" {
+		if s.ips == nil {
+			s.ips = make(map[string]bool)
+		}
+		if !s.ips[ev.SrcIP] {
+			s.ips[ev.SrcIP] = true
+			s.UniqueIPs++
+		}
+	}
+
+	if !ev.Timestamp.Before(s.LastEventAt) {
+		s.LastEventID = ev.EventID
+		s.LastEventAt = ev.Timestamp
+	}
+
+	s.Recent = append(s.Recent, ev)
+	if len(s.Recent) > recentEvents {
+		s.Recent = s.Recent[len(s.Recent)-recentEvents:]
+	}
+}
+
+// countUser increments the counter of a username
+func (s *Stats) countUser(counts *map[string]int, username string) {
+	if *counts == nil {
+		*counts = make(map[string]int)
+	}
+	(*counts)[username]++
+}
+
+// snapshot returns a copy that can be read without holding the tracker lock
+func (s *Stats) snapshot() Stats {
+	c := *s
+	c.Recent = append([]Event(nil), s.Recent...)
+	c.AttemptsByUser = copyCounts(s.AttemptsByUser)
+	c.SuccessesByUser = copyCounts(s.SuccessesByUser)
+	c.ips = nil
+	return c
+}
+
+// copyCounts duplicates a counter map
+func copyCounts(counts map[string]int) map[string]int {
+	if counts == nil {
+		return nil
+	}
+	c := make(map[string]int, len(counts))
+	for k, v := range counts {
+		c[k] = v
+	}
+	return c
+}
+
+// Delays before following a profile again after its follower failed,
+// doubled after each failure
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// Tracker maintains live Stats for several profiles.
+// Each watched profile loads its log history once, then follows new events
+// from the byte offset already read. A log truncated or replaced meanwhile
+// (rotation, container recreated) is loaded again from the start.
+type Tracker struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stats    map[string]*Stats
+	offsets  map[string]int64   // bytes of the log counted in stats
+	logs     map[string]logFile // log file the offset refers to
+	watching map[string]bool
+	failures map[string]int       // followers ended in a row without reading anything
+	retryAt  map[string]time.Time // no follower before this time
+	onEvent  func(Event)
+	running  sync.WaitGroup // followers, waited for by Stop
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Tracker{
+		ctx:      ctx,
+		cancel:   cancel,
+		stats:    make(map[string]*Stats),
+		offsets:  make(map[string]int64),
+		logs:     make(map[string]logFile),
+		watching: make(map[string]bool),
+		failures: make(map[string]int),
+		retryAt:  make(map[string]time.Time),
+	}
+}
+
+// OnEvent registers a callback invoked for every event added to the tracker
+func (t *Tracker) OnEvent(fn func(Event)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onEvent = fn
+}
+
+// Watch starts following a profile if it is not already followed.
+// When the stream ends (container stopped), the profile can be watched again
+// and resumes where it stopped; after a failure, only once a backoff delay expired.
+func (t *Tracker) Watch(profileName string) {
+	t.mu.Lock()
+	if t.watching[profileName] || t.ctx.Err() != nil || time.Now().Before(t.retryAt[profileName]) {
+		t.mu.Unlock()
+		return
+	}
+	t.watching[profileName] = true
+	offset, loaded := t.offsets[profileName]
+	t.running.Add(1)
+	t.mu.Unlock()
+
+	go func() {
+		defer t.running.Done()
+		read := false
+		defer func() { t.followEnded(profileName, read) }()
+
+		// Counters start from the full history, read once, and again when
+		// the log was truncated or replaced since
+		if loaded && t.logChanged(profileName, offset) {
+			loaded = false
+		}
+		if !loaded {
+			history, end, err := ReadProfileLog(profileName)
+			if err != nil {
+				return
+			}
+			log, _ := statProfileLog(profileName)
+			stats := &Stats{}
+			for _, ev := range history {
+				stats.Add(ev)
+			}
+			t.mu.Lock()
+			t.stats[profileName] = stats
+			t.offsets[profileName] = end
+			t.logs[profileName] = log
+			t.mu.Unlock()
+			offset, read = end, true
+		}
+
+		FollowProfileFrom(t.ctx, profileName, offset, func(ev Event, end int64) {
+			read = true
+			t.addLive(ev, end)
+		})
+	}()
+}
+
+// logChanged returns true if the log of a profile is shorter than the offset
+// already read, or is another file than the one it was read from
+func (t *Tracker) logChanged(profileName string, offset int64) bool {
+	current, err := statProfileLog(profileName)
+	if err != nil {
+		// The follower fails the same way
+		return false
+	}
+	t.mu.Lock()
+	previous := t.logs[profileName]
+	t.mu.Unlock()
+	return current.size < offset || (previous.inode != 0 && current.inode != previous.inode)
+}
+
+// followEnded lets a profile be watched again, after a delay if nothing was read
+func (t *Tracker) followEnded(profileName string, read bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.watching, profileName)
+	if read {
+		delete(t.failures, profileName)
+		delete(t.retryAt, profileName)
+		return
+	}
+	t.failures[profileName]++
+	delay := minRetryDelay << min(t.failures[profileName]-1, 6)
+	t.retryAt[profileName] = time.Now().Add(min(delay, maxRetryDelay))
+}
+
+// addLive records an event received from the live stream, followed by end bytes of the log
+func (t *Tracker) addLive(ev Event, end int64) {
+	t.mu.Lock()
+	stats, ok := t.stats[ev.Profile]
+	if !ok {
+		stats = &Stats{}
+		t.stats[ev.Profile] = stats
+	}
+	stats.Add(ev)
+	t.offsets[ev.Profile] = end
+	if !ev.Timestamp.IsZero() {
+		stats.Lag = time.Since(ev.Timestamp)
+	}
+	fn := t.onEvent
+	t.mu.Unlock()
+
+	if fn != nil {
+		fn(ev)
+	}
+}
+
+// Snapshot returns the current counters of a profile
+func (t *Tracker) Snapshot(profileName string) (Stats, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats, ok := t.stats[profileName]
+	if !ok {
+		return Stats{}, false
+	}
+	return stats.snapshot(), true
+}
+
+// Stop ends every follower and waits for them
+func (t *Tracker) Stop() {
+	// Under the lock, Watch starts no follower after this
+	t.mu.Lock()
+	t.cancel()
+	t.mu.Unlock()
+	t.running.Wait()
+}
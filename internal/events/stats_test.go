@@ -0,0 +1,220 @@
+package events_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/otori-lab/otori-cli/internal/events"
+)
+
+// fakeRuntime installs a container CLI serving the log file of every container:
+// "cp" archives it, "exec ... stat" describes it, "exec ... tail" follows it,
+// once the follow file exists.
+// It returns the log path, the follow switch and the file recording the calls.
+func fakeRuntime(t *testing.T) (string, string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "cowrie.json")
+	follow := filepath.Join(dir, "follow")
+	calls := filepath.Join(dir, "calls")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %[1]s
+case "$1" in
+cp) exec tar -cf - -C %[2]s cowrie.json ;;
+exec) case "$3" in
+	stat) exec stat -c "$5" %[4]s ;;
+	tail) [ -e %[3]s ] || exit 1; exec tail "$4" "$5" -F %[4]s ;;
+	esac ;;
+esac
+exit 1
+`, calls, dir, follow, log)
+	runtime := filepath.Join(dir, "docker")
+	if err := os.WriteFile(runtime, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := container.Runtime()
+	container.SetRuntime(runtime)
+	t.Cleanup(func() { container.SetRuntime(previous) })
+	return log, follow, calls
+}
+
+// loginLine is a cowrie.json line of a failed login
+func loginLine(username string) string {
+	return `{"eventid": "cowrie.login.failed", "timestamp": "2025-03-02T10:00:00Z", "src_ip": "198.51.100.1", "username": "` + username + `"}` + "\n"
+}
+
+// appendLog appends to the log file
+func appendLog(t *testing.T, path, data string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitFor polls a condition for a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+	}
+}
+
+func TestTrackerFollowsFromOffset(t *testing.T) {
+	log, follow, calls := fakeRuntime(t)
+	history := loginLine("root") + loginLine("admin")
+	third := loginLine("test")
+	appendLog(t, log, history+third[:20])
+
+	tracker := events.NewTracker()
+	defer tracker.Stop()
+
+	// History read, following fails: the next watch resumes without reading it again
+	tracker.Watch("web")
+	waitFor(t, "the follower", func() bool {
+		data, _ := os.ReadFile(calls)
+		return strings.Contains(string(data), "exec")
+	})
+	if stats, ok := tracker.Snapshot("web"); !ok || stats.LoginAttempts != 2 {
+		t.Fatalf("history: %+v", stats)
+	}
+
+	// Lines written meanwhile, the partial one included, are counted once
+	appendLog(t, log, third[20:]+loginLine("guest"))
+	if err := os.WriteFile(follow, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the live events", func() bool {
+		tracker.Watch("web")
+		stats, _ := tracker.Snapshot("web")
+		return stats.LoginAttempts >= 4
+	})
+	appendLog(t, log, loginLine("root"))
+	waitFor(t, "a live event", func() bool {
+		stats, _ := tracker.Snapshot("web")
+		return stats.LoginAttempts >= 5
+	})
+
+	stats, _ := tracker.Snapshot("web")
+	if stats.LoginAttempts != 5 || stats.AttemptsByUser["root"] != 2 || stats.AttemptsByUser["test"] != 1 {
+		t.Errorf("stats: %+v", stats)
+	}
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "cp "); n != 1 {
+		t.Errorf("history read %d times:\n%s", n, data)
+	}
+	if want := fmt.Sprintf("tail -c +%d -F", len(history)+1); !strings.Contains(string(data), want) {
+		t.Errorf("not followed from the offset read (%s):\n%s", want, data)
+	}
+}
+
+func TestTrackerLogReplaced(t *testing.T) {
+	log, _, calls := fakeRuntime(t)
+	appendLog(t, log, loginLine("root")+loginLine("admin")+loginLine("test"))
+
+	tracker := events.NewTracker()
+	defer tracker.Stop()
+	tracker.Watch("web")
+	waitFor(t, "the history", func() bool {
+		stats, _ := tracker.Snapshot("web")
+		return stats.LoginAttempts == 3
+	})
+
+	// Truncated: shorter than the offset read
+	if err := os.WriteFile(log, []byte(loginLine("guest")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the truncated log", func() bool {
+		tracker.Watch("web")
+		stats, _ := tracker.Snapshot("web")
+		return stats.LoginAttempts == 1 && stats.AttemptsByUser["guest"] == 1
+	})
+
+	// Rotated: another file, longer than the offset read
+	rotated := log + ".new"
+	appendLog(t, rotated, loginLine("oracle")+loginLine("oracle"))
+	if err := os.Rename(rotated, log); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the rotated log", func() bool {
+		tracker.Watch("web")
+		stats, _ := tracker.Snapshot("web")
+		return stats.LoginAttempts == 2 && stats.AttemptsByUser["oracle"] == 2
+	})
+
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "cp "); n != 3 {
+		t.Errorf("history read %d times, want 3:\n%s", n, data)
+	}
+}
+
+func TestTrackerBackoff(t *testing.T) {
+	_, _, calls := fakeRuntime(t)
+
+	// No log: every attempt fails, the next one waits
+	tracker := events.NewTracker()
+	defer tracker.Stop()
+	tracker.Watch("web")
+	waitFor(t, "the first attempt", func() bool {
+		data, _ := os.ReadFile(calls)
+		return strings.Count(string(data), "cp ") == 1
+	})
+	time.Sleep(100 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		tracker.Watch("web")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	data, _ := os.ReadFile(calls)
+	if n := strings.Count(string(data), "cp "); n != 1 {
+		t.Errorf("history read %d times within the backoff delay:\n%s", n, data)
+	}
+}
+
+func TestStatsAdd(t *testing.T) {
+	var stats events.Stats
+	for _, ev := range []events.Event{
+		{EventID: events.EventSessionConnect, SrcIP: "198.51.100.1"},
+		{EventID: events.EventLoginFailed, SrcIP: "198.51.100.1", Username: "root"},
+		{EventID: events.EventLoginSuccess, SrcIP: "198.51.100.1", Username: "root"},
+		{EventID: events.EventCommandInput, SrcIP: "198.51.100.1", Input: "uname -a"},
+		{EventID: events.EventFileDownload, SrcIP: "198.51.100.1"},
+		{EventID: events.EventSessionConnect, SrcIP: "198.51.100.2"},
+		{EventID: events.EventLoginFailed, SrcIP: "198.51.100.2", Username: "admin"},
+		{EventID: events.EventLogClosed, SrcIP: "198.51.100.2", TTYLog: "var/lib/cowrie/tty/abc"},
+	} {
+		stats.Add(ev)
+	}
+
+	if stats.Connections != 2 || stats.LoginAttempts != 3 || stats.LoginSuccesses != 1 ||
+		stats.Commands != 1 || stats.Downloads != 1 || stats.UniqueIPs != 2 {
+		t.Errorf("counters: %+v", stats)
+	}
+	if stats.AttemptsByUser["root"] != 2 || stats.AttemptsByUser["admin"] != 1 || stats.SuccessesByUser["root"] != 1 {
+		t.Errorf("by username: %v, %v", stats.AttemptsByUser, stats.SuccessesByUser)
+	}
+	if stats.LastTTYLog != "var/lib/cowrie/tty/abc" || len(stats.Recent) != 8 {
+		t.Errorf("last session: %q, %d recent events", stats.LastTTYLog, len(stats.Recent))
+	}
+}
@@ -0,0 +1,161 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/events"
+)
+
+// Info holds the enrichment data found for an IP address
+type Info struct {
+	Country     string `json:"country,omitempty"`
+	CountryName string `json:"country_name,omitempty"`
+	ASN         uint   `json:"asn,omitempty"`
+	ASOrg       string `json:"as_org,omitempty"`
+}
+
+// record matches the fields of GeoLite2/GeoIP2 Country, City and ASN databases
+type record struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	ASN   uint   `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+// DB is a set of local MaxMind-format databases.
+// A nil or empty DB is valid: lookups simply return no data.
+type DB struct {
+	readers []*maxminddb.Reader
+	files   []string
+	cache   map[string]Info
+}
+
+// GetGeoIPDir returns the directory scanned for .mmdb files (~/.otori/geoip)
+func GetGeoIPDir() string {
+	return filepath.Join(config.GetOtoriDir(), "geoip")
+}
+
+// Open loads every .mmdb file found in dir.
+// A missing directory is not an error, enrichment is then disabled.
+func Open(dir string) (*DB, error) {
+	db := &DB{cache: make(map[string]Info)}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return db, nil
+		}
+		return db, fmt.Errorf("error reading geoip directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".mmdb") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		reader, err := maxminddb.Open(path)
+		if err != nil {
+			db.Close()
+			return &DB{cache: make(map[string]Info)}, fmt.Errorf("error opening %s: %w", name, err)
+		}
+		db.readers = append(db.readers, reader)
+		db.files = append(db.files, path)
+	}
+
+	return db, nil
+}
+
+// OpenDefault loads the databases from ~/.otori/geoip
+func OpenDefault() (*DB, error) {
+	return Open(GetGeoIPDir())
+}
+
+// Close releases the underlying database files
+func (db *DB) Close() {
+	if db == nil {
+		return
+	}
+	for _, r := range db.readers {
+		r.Close()
+	}
+	db.readers = nil
+}
+
+// Available returns true if at least one database is loaded
+func (db *DB) Available() bool {
+	return db != nil && len(db.readers) > 0
+}
+
+// Files returns the paths of the loaded databases
+func (db *DB) Files() []string {
+	if db == nil {
+		return nil
+	}
+	return db.files
+}
+
+// Lookup returns the enrichment data for an IP address.
+// Data from several databases (e.g. Country + ASN) is merged.
+func (db *DB) Lookup(ipStr string) Info {
+	if !db.Available() {
+		return Info{}
+	}
+	if info, ok := db.cache[ipStr]; ok {
+		return info
+	}
+
+	var info Info
+	ip := net.ParseIP(ipStr)
+	if ip != nil {
+		for _, r := range db.readers {
+			var rec record
+			if err := r.Lookup(ip, &rec); err != nil {
+				continue
+			}
+			if info.Country == "" && rec.Country.ISOCode != "" {
+				info.Country = rec.Country.ISOCode
+				info.CountryName = rec.Country.Names["en"]
+			}
+			if info.ASN == 0 && rec.ASN != 0 {
+				info.ASN = rec.ASN
+				info.ASOrg = rec.ASOrg
+			}
+		}
+	}
+
+	db.cache[ipStr] = info
+	return info
+}
+
+// Annotate fills the GeoIP fields of every event in place
+func (db *DB) Annotate(evs []events.Event) {
+	for i := range evs {
+		db.AnnotateEvent(&evs[i])
+	}
+}
+
+// AnnotateEvent fills the GeoIP fields of a single event
+func (db *DB) AnnotateEvent(ev *events.Event) {
+	if !db.Available() || ev.SrcIP == "" {
+		return
+	}
+	info := db.Lookup(ev.SrcIP)
+	ev.Country = info.Country
+	ev.CountryName = info.CountryName
+	ev.ASN = info.ASN
+	ev.ASOrg = info.ASOrg
+}
@@ -0,0 +1,115 @@
+package geoip_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/otori-lab/otori-cli/internal/events"
+	"github.com/otori-lab/otori-cli/internal/geoip"
+)
+
+// openTestdata opens the databases of testdata, written with github.com/maxmind/mmdbwriter:
+//   - Test-Country.mmdb: 198.51.100.0/24 FR France, 203.0.113.0/24 DE Germany
+//   - Test-ASN.mmdb: 198.51.100.0/24 AS64500 Example Transit, 192.0.2.0/24 AS64501 Example Hosting
+func openTestdata(t *testing.T) *geoip.DB {
+	t.Helper()
+
+	db, err := geoip.Open("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+func TestLookup(t *testing.T) {
+	db := openTestdata(t)
+	if !db.Available() || len(db.Files()) != 2 {
+		t.Fatalf("databases: %v", db.Files())
+	}
+
+	for _, tt := range []struct {
+		ip   string
+		info geoip.Info
+	}{
+		// Country and ASN merged from both databases
+		{"198.51.100.7", geoip.Info{Country: "FR", CountryName: "France", ASN: 64500, ASOrg: "Example Transit"}},
+		{"203.0.113.5", geoip.Info{Country: "DE", CountryName: "Germany"}},
+		{"192.0.2.1", geoip.Info{ASN: 64501, ASOrg: "Example Hosting"}},
+		{"10.0.0.1", geoip.Info{}},
+		{"not-an-ip", geoip.Info{}},
+	} {
+		if info := db.Lookup(tt.ip); info != tt.info {
+			t.Errorf("%s: %+v, want %+v", tt.ip, info, tt.info)
+		}
+	}
+}
+
+func TestWithoutDatabase(t *testing.T) {
+	// A missing directory disables enrichment
+	db, err := geoip.Open(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Available() {
+		t.Error("databases found in a missing directory")
+	}
+
+	var nilDB *geoip.DB
+	for _, db := range []*geoip.DB{db, nilDB} {
+		if info := db.Lookup("198.51.100.7"); info != (geoip.Info{}) {
+			t.Errorf("lookup without database: %+v", info)
+		}
+		evs := []events.Event{{SrcIP: "198.51.100.7"}}
+		db.Annotate(evs)
+		if evs[0].Country != "" || evs[0].ASN != 0 {
+			t.Errorf("event annotated without database: %+v", evs[0])
+		}
+		db.Close()
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "broken.mmdb"), []byte("not a database"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := geoip.Open(dir)
+	if err == nil {
+		t.Error("invalid database accepted")
+	}
+	if db.Available() {
+		t.Error("databases available after an error")
+	}
+}
+
+func TestCountByCountryAndASN(t *testing.T) {
+	db := openTestdata(t)
+	evs := []events.Event{
+		{EventID: events.EventLoginFailed, SrcIP: "198.51.100.7"},
+		{EventID: events.EventLoginFailed, SrcIP: "198.51.100.7"},
+		{EventID: events.EventLoginFailed, SrcIP: "198.51.100.8"},
+		{EventID: events.EventLoginFailed, SrcIP: "203.0.113.5"},
+		{EventID: events.EventLoginFailed, SrcIP: "192.0.2.1"},
+		{EventID: events.EventLogClosed},
+	}
+	db.Annotate(evs)
+
+	if got, want := events.CountBy(evs, events.ByCountry), []events.Count{
+		{Key: "FR", Label: "France", Events: 3, UniqueIPs: 2},
+		{Key: "??", Label: "unknown", Events: 1, UniqueIPs: 1},
+		{Key: "DE", Label: "Germany", Events: 1, UniqueIPs: 1},
+	}; !slices.Equal(got, want) {
+		t.Errorf("by country: %+v, want %+v", got, want)
+	}
+	if got, want := events.CountBy(evs, events.ByASN), []events.Count{
+		{Key: "AS64500", Label: "Example Transit", Events: 3, UniqueIPs: 2},
+		{Key: "AS64501", Label: "Example Hosting", Events: 1, UniqueIPs: 1},
+		{Key: "AS?", Label: "unknown", Events: 1, UniqueIPs: 1},
+	}; !slices.Equal(got, want) {
+		t.Errorf("by ASN: %+v, want %+v", got, want)
+	}
+}
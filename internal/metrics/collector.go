@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/otori-lab/otori-cli/internal/events"
+)
+
+// OtherUsername groups the usernames beyond the cardinality limit
+const OtherUsername = "_other"
+
+// Collector builds metrics from the container runtime and the Cowrie event stream
+type Collector struct {
+	tracker      *events.Tracker
+	maxUsernames int
+
+	mu      sync.Mutex
+	labeled map[string]map[string]bool // usernames with their own label, by metric and profile
+}
+
+// NewCollector creates a collector. maxUsernames limits the number of distinct
+// username label values per profile (attackers try thousands of them).
+func NewCollector(maxUsernames int) *Collector {
+	return &Collector{
+		tracker:      events.NewTracker(),
+		maxUsernames: maxUsernames,
+		labeled:      make(map[string]map[string]bool),
+	}
+}
+
+// Stop ends the event followers
+func (c *Collector) Stop() {
+	c.tracker.Stop()
+}
+
+// Gather returns the current metric families.
+// Running profiles are followed on first sight so their counters start filling.
+func (c *Collector) Gather() []*Family {
+	runtimeUp := &Family{Name: "otori_container_runtime_up", Type: TypeGauge,
+		Help: "Whether the container runtime could be queried (1) or not (0)."}
+	up := &Family{Name: "otori_honeypot_up", Type: TypeGauge,
+		Help: "Whether the honeypot container is running and healthy."}
+	uptime := &Family{Name: "otori_honeypot_uptime_seconds", Type: TypeGauge,
+		Help: "Seconds since the honeypot container started."}
+	restarts := &Family{Name: "otori_honeypot_restarts_total", Type: TypeCounter,
+		Help: "Number of times the container runtime restarted the honeypot."}
+	drift := &Family{Name: "otori_honeypot_config_drift", Type: TypeGauge,
+		Help: "Whether the profile on disk differs from the deployed configuration."}
+	sessions := &Family{Name: "otori_sessions_total", Type: TypeCounter,
+		Help: "Number of attacker sessions (connections)."}
+	attempts := &Family{Name: "otori_login_attempts_total", Type: TypeCounter,
+		Help: "Number of login attempts by username."}
+	successes := &Family{Name: "otori_login_successes_total", Type: TypeCounter,
+		Help: "Number of successful logins by username."}
+	commands := &Family{Name: "otori_commands_total", Type: TypeCounter,
+		Help: "Number of commands typed by attackers."}
+	downloads := &Family{Name: "otori_downloads_total", Type: TypeCounter,
+		Help: "Number of files downloaded by attackers."}
+	uniqueIPs := &Family{Name: "otori_unique_ips", Type: TypeGauge,
+		Help: "Number of distinct source IPs seen."}
+	lastEvent := &Family{Name: "otori_last_event_timestamp_seconds", Type: TypeGauge,
+		Help: "Unix time of the last Cowrie event."}
+	lag := &Family{Name: "otori_collector_lag_seconds", Type: TypeGauge,
+		Help: "Delay between Cowrie logging the last live event and the exporter reading it."}
+
+	profiles, _ := config.ListConfigs()
+	containers, err := container.ListContainers(profiles)
+	if err != nil {
+		runtimeUp.Add(0)
+	} else {
+		runtimeUp.Add(1)
+	}
+
+	byProfile := make(map[string]container.Container)
+	for _, ct := range containers {
+		byProfile[ct.Profile()] = ct
+	}
+
+	for _, profileName := range profiles {
+		label := Label{"profile", profileName}
+		ct, deployed := byProfile[profileName]
+
+		running := deployed && ct.State == "running"
+		if running && ct.Health != "unhealthy" {
+			up.Add(1, label)
+		} else {
+			up.Add(0, label)
+		}
+
+		if !deployed {
+			continue
+		}
+
+		restarts.Add(float64(ct.RestartCount), label)
+		if running && !ct.StartedAt.IsZero() {
+			uptime.Add(time.Since(ct.StartedAt).Seconds(), label)
+			c.tracker.Watch(profileName)
+		}
+
+		if hash := ct.Labels[container.LabelConfigHash]; hash != "" {
+			if cfg, err := config.ReadEffectiveConfig(profileName); err == nil && config.ConfigHash(cfg) != hash {
+				drift.Add(1, label)
+			} else {
+				drift.Add(0, label)
+			}
+		}
+
+		stats, ok := c.tracker.Snapshot(profileName)
+		if !ok {
+			continue
+		}
+		sessions.Add(float64(stats.Connections), label)
+		commands.Add(float64(stats.Commands), label)
+		downloads.Add(float64(stats.Downloads), label)
+		uniqueIPs.Add(float64(stats.UniqueIPs), label)
+		lag.Add(stats.Lag.Seconds(), label)
+		if !stats.LastEventAt.IsZero() {
+			lastEvent.Add(float64(stats.LastEventAt.Unix()), label)
+		}
+		for user, n := range c.limitUsernames(attempts.Name+"|"+profileName, stats.AttemptsByUser) {
+			attempts.Add(float64(n), label, Label{"username", user})
+		}
+		for user, n := range c.limitUsernames(successes.Name+"|"+profileName, stats.SuccessesByUser) {
+			successes.Add(float64(n), label, Label{"username", user})
+		}
+	}
+
+	return []*Family{
+		runtimeUp, up, uptime, restarts, drift,
+		sessions, attempts, successes, commands, downloads,
+		uniqueIPs, lastEvent, lag,
+	}
+}
+
+// limitUsernames gives their own label to the first usernames seen for a
+// metric (key) and sums the others into OtherUsername. Labels are sticky: a
+// username keeps its label or stays in OtherUsername for good, so that no
+// counter ever goes down between two scrapes (which Prometheus reads as a reset).
+func (c *Collector) limitUsernames(key string, counts map[string]int) map[string]int {
+	if c.maxUsernames <= 0 {
+		return counts
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	labeled, ok := c.labeled[key]
+	if !ok {
+		labeled = make(map[string]bool)
+		c.labeled[key] = labeled
+	}
+
+	// The free labels go to the most used of the new usernames
+	var users []string
+	for user := range counts {
+		if !labeled[user] {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if counts[users[i]] != counts[users[j]] {
+			return counts[users[i]] > counts[users[j]]
+		}
+		return users[i] < users[j]
+	})
+	for _, user := range users {
+		if len(labeled) >= c.maxUsernames {
+			break
+		}
+		labeled[user] = true
+	}
+
+	limited := make(map[string]int, len(labeled)+1)
+	for user, n := range counts {
+		if labeled[user] {
+			limited[user] = n
+		} else {
+			limited[OtherUsername] += n
+		}
+	}
+	return limited
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import "testing"
+
+func TestLimitUsernamesNeverGoesDown(t *testing.T) {
+	c := NewCollector(2)
+
+	// Cumulative counts of successive scrapes, the ranking of usernames changes
+	scrapes := []map[string]int{
+		{"root": 1},
+		{"root": 1, "admin": 3, "test": 5},
+		{"root": 1, "admin": 3, "test": 9, "guest": 20},
+		{"root": 30, "admin": 3, "test": 9, "guest": 40, "oracle": 1},
+		{"root": 30, "admin": 3, "test": 9, "guest": 40, "oracle": 100},
+	}
+
+	previous := map[string]int{}
+	for i, counts := range scrapes {
+		limited := c.limitUsernames("otori_login_attempts_total|web", counts)
+
+		if len(limited) > 3 {
+			t.Errorf("scrape %d: %d labels for 2 usernames and %s", i, len(limited), OtherUsername)
+		}
+		if total, want := sum(limited), sum(counts); total != want {
+			t.Errorf("scrape %d: %d attempts, want %d", i, total, want)
+		}
+		for user, n := range previous {
+			if limited[user] < n {
+				t.Errorf("scrape %d: counter of %s went down from %d to %d", i, user, n, limited[user])
+			}
+		}
+		previous = limited
+	}
+
+	// The first usernames seen keep their label, the most used new one got the free label
+	if _, ok := previous["root"]; !ok {
+		t.Errorf("root lost its label: %v", previous)
+	}
+	if _, ok := previous["test"]; !ok {
+		t.Errorf("test has no label: %v", previous)
+	}
+	if previous[OtherUsername] != 3+40+100 {
+		t.Errorf("_other: %v", previous)
+	}
+
+	// Each metric has its own labels
+	if limited := c.limitUsernames("otori_login_successes_total|web", map[string]int{"guest": 1}); limited["guest"] != 1 {
+		t.Errorf("successes: %v", limited)
+	}
+}
+
+func sum(counts map[string]int) int {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
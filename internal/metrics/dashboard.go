@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"encoding/json"
+)
+
+// panel describes a Grafana panel of the generated dashboard
+type panel struct {
+	title   string
+	kind    string // stat, timeseries, table
+	queries []query
+	unit    string
+	width   int
+}
+
+// query is a PromQL target of a panel and the legend of its series
+type query struct {
+	expr   string
+	legend string
+}
+
+// byProfile is the single query of a panel, one series per profile
+func byProfile(expr string) []query {
+	return []query{{expr, "{{profile}}"}}
+}
+
+// dashboardPanels lists the panels of the example dashboard, in display order
+var dashboardPanels = []panel{
+	{"Honeypots up", "stat", byProfile(`sum(otori_honeypot_up{profile=~"$profile"})`), "none", 6},
+	{"Config drift", "stat", byProfile(`sum(otori_honeypot_config_drift{profile=~"$profile"})`), "none", 6},
+	{"Unique attacker IPs", "stat", byProfile(`sum(otori_unique_ips{profile=~"$profile"})`), "none", 6},
+	{"Collector lag", "stat", byProfile(`max(otori_collector_lag_seconds{profile=~"$profile"})`), "s", 6},
+	{"Sessions / 5m", "timeseries", byProfile(`sum by (profile) (increase(otori_sessions_total{profile=~"$profile"}[5m]))`), "none", 12},
+	{"Login attempts / 5m", "timeseries", byProfile(`sum by (profile) (increase(otori_login_attempts_total{profile=~"$profile"}[5m]))`), "none", 12},
+	{"Top usernames (attempts)", "table", byProfile(`topk(15, sum by (username) (otori_login_attempts_total{profile=~"$profile"}))`), "none", 8},
+	{"Successful logins by username", "table", byProfile(`topk(15, sum by (username) (otori_login_successes_total{profile=~"$profile"}))`), "none", 8},
+	{"Commands & downloads / 5m", "timeseries", []query{
+		{`sum by (profile) (increase(otori_commands_total{profile=~"$profile"}[5m]))`, "{{profile}} commands"},
+		{`sum by (profile) (increase(otori_downloads_total{profile=~"$profile"}[5m]))`, "{{profile}} downloads"},
+	}, "none", 8},
+	{"Uptime", "timeseries", byProfile(`otori_honeypot_uptime_seconds{profile=~"$profile"}`), "s", 12},
+	{"Restarts", "timeseries", byProfile(`otori_honeypot_restarts_total{profile=~"$profile"}`), "none", 12},
+}
+
+// Dashboard returns an example Grafana dashboard (JSON model) for the exporter metrics.
+// It can be imported from Grafana's "Import dashboard" screen.
+func Dashboard() ([]byte, error) {
+	datasource := map[string]string{"type": "prometheus", "uid": "${DS_PROMETHEUS}"}
+
+	var panels []map[string]interface{}
+	x, y, rowHeight := 0, 0, 0
+	for i, p := range dashboardPanels {
+		height := 8
+		if p.kind == "stat" {
+			height = 4
+		}
+		if x+p.width > 24 {
+			x = 0
+			y += rowHeight
+			rowHeight = 0
+		}
+
+		var targets []map[string]interface{}
+		for j, q := range p.queries {
+			targets = append(targets, map[string]interface{}{
+				"refId":        string(rune('A' + j)),
+				"datasource":   datasource,
+				"expr":         q.expr,
+				"instant":      p.kind == "table",
+				"legendFormat": q.legend,
+			})
+		}
+
+		panels = append(panels, map[string]interface{}{
+			"id":         i + 1,
+			"title":      p.title,
+			"type":       p.kind,
+			"datasource": datasource,
+			"gridPos":    map[string]int{"x": x, "y": y, "w": p.width, "h": height},
+			"fieldConfig": map[string]interface{}{
+				"defaults":  map[string]interface{}{"unit": p.unit},
+				"overrides": []interface{}{},
+			},
+			"targets": targets,
+		})
+
+		x += p.width
+		if height > rowHeight {
+			rowHeight = height
+		}
+	}
+
+	dashboard := map[string]interface{}{
+		"__inputs": []map[string]string{{
+			"name":     "DS_PROMETHEUS",
+			"label":    "Prometheus",
+			"type":     "datasource",
+			"pluginId": "prometheus",
+		}},
+		"title":         "Otori honeypots",
+		"uid":           "otori-honeypots",
+		"tags":          []string{"otori", "honeypot", "cowrie"},
+		"timezone":      "browser",
+		"schemaVersion": 39,
+		"refresh":       "30s",
+		"time":          map[string]string{"from": "now-24h", "to": "now"},
+		"templating": map[string]interface{}{
+			"list": []map[string]interface{}{{
+				"name":       "profile",
+				"label":      "Profile",
+				"type":       "query",
+				"datasource": datasource,
+				"query":      "label_values(otori_honeypot_up, profile)",
+				"multi":      true,
+				"includeAll": true,
+				"allValue":   ".*",
+				"current":    map[string]interface{}{"text": "All", "value": "$__all"},
+				"refresh":    2,
+			}},
+		},
+		"panels": panels,
+	}
+
+	return json.MarshalIndent(dashboard, "", "  ")
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDashboardTargets(t *testing.T) {
+	data, err := Dashboard()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dashboard struct {
+		Panels []struct {
+			Title   string `json:"title"`
+			Targets []struct {
+				RefID string `json:"refId"`
+				Expr  string `json:"expr"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}
+	if err := json.Unmarshal(data, &dashboard); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range dashboard.Panels {
+		if p.Title != "Commands & downloads / 5m" {
+			continue
+		}
+		// "a or b" drops the series of b whose labels are in a
+		if len(p.Targets) != 2 || p.Targets[1].RefID != "B" || !strings.Contains(p.Targets[1].Expr, "otori_downloads_total") {
+			t.Errorf("targets of %q: %+v", p.Title, p.Targets)
+		}
+		for _, target := range p.Targets {
+			if strings.Contains(target.Expr, " or ") {
+				t.Errorf("target %s merges two series with or: %s", target.RefID, target.Expr)
+			}
+		}
+		return
+	}
+	t.Error("no commands and downloads panel")
+}
@@ -2,18 +2,103 @@ package models
 
 // Config représente la configuration du profil Otori
 type Config struct {
-	Type        string   `json:"type"`        // classique ou IA
-	ServerName  string   `json:"serverName"`  // obligatoire
-	ProfileName string   `json:"profileName"` // default si non spécifié
-	Company     string   `json:"company"`     // optionnel
-	Users       []string `json:"users"`       // optionnel
-	CreatedAt   string   `json:"createdAt"`   // timestamp de création
+	SchemaVersion   int                          `json:"schemaVersion" yaml:"schemaVersion"`                         // version du format JSON
+	Extends         string                       `json:"extends,omitempty" yaml:"extends,omitempty"`                 // template hérité (optionnel)
+	Type            string                       `json:"type" yaml:"type"`                                           // classique ou IA
+	ServerName      string                       `json:"serverName" yaml:"serverName"`                               // obligatoire
+	ProfileName     string                       `json:"profileName" yaml:"profileName"`                             // default si non spécifié
+	Company         string                       `json:"company" yaml:"company"`                                     // optionnel
+	Users           []string                     `json:"users" yaml:"users"`                                         // optionnel
+	Passwords       map[string][]string          `json:"passwords,omitempty" yaml:"passwords,omitempty"`             // utilisateur -> mots de passe acceptés (défaut: *)
+	Persona         *Persona                     `json:"persona,omitempty" yaml:"persona,omitempty"`                 // système simulé (optionnel)
+	Ports           *Ports                       `json:"ports,omitempty" yaml:"ports,omitempty"`                     // ports exposés sur l'hôte (optionnel)
+	Image           string                       `json:"image,omitempty" yaml:"image,omitempty"`                     // image Cowrie, ex: cowrie/cowrie:2.5.0@sha256:... (défaut: réglage image)
+	Baked           bool                         `json:"baked,omitempty" yaml:"baked,omitempty"`                     // déploie l'image construite par 'otori build' au lieu de monter les fichiers
+	Sinks           []Sink                       `json:"sinks,omitempty" yaml:"sinks,omitempty"`                     // sorties Cowrie supplémentaires
+	BaitFiles       []BaitFile                   `json:"baitFiles,omitempty" yaml:"baitFiles,omitempty"`             // fichiers appâts du honeyfs
+	Commands        []Command                    `json:"commands,omitempty" yaml:"commands,omitempty"`               // sorties de commandes simulées (txtcmds)
+	CowrieOverrides map[string]map[string]string `json:"cowrieOverrides,omitempty" yaml:"cowrieOverrides,omitempty"` // section -> clé -> valeur de cowrie.cfg
+	Seed            string                       `json:"seed,omitempty" yaml:"seed,omitempty"`                       // graine des valeurs générées (tirée à la création)
+	CreatedAt       string                       `json:"createdAt" yaml:"createdAt"`                                 // timestamp de création
 }
 
+// Persona décrit le système simulé (section [shell] et bannière SSH de cowrie.cfg)
+type Persona struct {
+	OperatingSystem string     `json:"operatingSystem,omitempty" yaml:"operatingSystem,omitempty"` // ex: GNU/Linux
+	KernelVersion   string     `json:"kernelVersion,omitempty" yaml:"kernelVersion,omitempty"`     // ex: 5.15.0-91-generic
+	KernelBuild     string     `json:"kernelBuild,omitempty" yaml:"kernelBuild,omitempty"`         // ex: #101-Ubuntu SMP
+	Hardware        string     `json:"hardware,omitempty" yaml:"hardware,omitempty"`               // ex: x86_64
+	SSHVersion      string     `json:"sshVersion,omitempty" yaml:"sshVersion,omitempty"`           // bannière SSH annoncée
+	Services        []Service  `json:"services,omitempty" yaml:"services,omitempty"`               // services simulés (défaut: sshd)
+	Neighbors       []Neighbor `json:"neighbors,omitempty" yaml:"neighbors,omitempty"`             // hôtes voisins du réseau local
+}
+
+// Service est un service simulé : processus, socket en écoute et fichiers de configuration
+type Service struct {
+	Name string `json:"name" yaml:"name"`                     // nginx, postgres, sshd ou docker
+	Port int    `json:"port,omitempty" yaml:"port,omitempty"` // port d'écoute (0 = port par défaut du service)
+}
+
+// Neighbor est un hôte voisin, visible dans /etc/hosts et /proc/net/arp
+type Neighbor struct {
+	Name string `json:"name" yaml:"name"`                 // nom court, complété par le domaine de l'entreprise
+	IP   string `json:"ip,omitempty" yaml:"ip,omitempty"` // adresse IPv4 (défaut: dérivée du nom dans le réseau du serveur)
+}
+
+// Ports sont les ports de l'hôte publiés vers le conteneur (0 = valeur par défaut)
+type Ports struct {
+	SSH    int `json:"ssh,omitempty" yaml:"ssh,omitempty"`       // défaut 2222
+	Telnet int `json:"telnet,omitempty" yaml:"telnet,omitempty"` // défaut 2223
+}
+
+// Sink est une sortie Cowrie (plugin output_<type>) et ses options
+type Sink struct {
+	Type    string            `json:"type" yaml:"type"`                           // ex: syslog, splunk, elasticsearch
+	Options map[string]string `json:"options,omitempty" yaml:"options,omitempty"` // clés de la section [output_<type>]
+}
+
+// BaitFile est un fichier appât écrit dans le honeyfs
+type BaitFile struct {
+	Path    string `json:"path" yaml:"path"`       // chemin absolu dans le honeypot
+	Content string `json:"content" yaml:"content"` // contenu du fichier
+}
+
+// Command est la sortie texte d'une commande simulée (txtcmds de Cowrie)
+type Command struct {
+	Path   string `json:"path" yaml:"path"`     // chemin absolu de la commande, ex: /usr/bin/lscpu
+	Output string `json:"output" yaml:"output"` // texte affiché, quels que soient les arguments
+}
+
+// CurrentSchemaVersion est la version du format JSON écrit par cette version d'otori
+const CurrentSchemaVersion = 2
+
+// Default host ports of a honeypot
+const (
+	DefaultSSHPort    = 2222
+	DefaultTelnetPort = 2223
+)
+
 // NewConfig crée une nouvelle configuration
 func NewConfig() *Config {
 	return &Config{
-		ProfileName: "default",
-		Users:       []string{},
+		SchemaVersion: CurrentSchemaVersion,
+		ProfileName:   "default",
+		Users:         []string{},
+	}
+}
+
+// SSHPort retourne le port SSH publié sur l'hôte
+func (c *Config) SSHPort() int {
+	if c.Ports != nil && c.Ports.SSH != 0 {
+		return c.Ports.SSH
+	}
+	return DefaultSSHPort
+}
+
+// TelnetPort retourne le port Telnet publié sur l'hôte
+func (c *Config) TelnetPort() int {
+	if c.Ports != nil && c.Ports.Telnet != 0 {
+		return c.Ports.Telnet
 	}
+	return DefaultTelnetPort
 }
@@ -0,0 +1,285 @@
+// Package service installs the honeypots of otori as systemd units or Podman
+// Quadlet files, so that an inventory of what runs at boot exists outside of
+// the restart policy of the container runtime
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/otori-lab/otori-cli/internal/version"
+)
+
+// Kinds of unit files
+const (
+	KindSystemd = "systemd" // .service unit running otori deploy/stop
+	KindQuadlet = "quadlet" // .container file, turned into a unit by Podman
+)
+
+// Scopes of the units
+const (
+	ScopeSystem = "system" // system manager, started at boot
+	ScopeUser   = "user"   // user manager (systemctl --user)
+)
+
+// headerGenerated marks the unit files written by otori
+const headerGenerated = "# Generated by Otori CLI"
+
+// systemDirs are the directories of the system units, by kind
+var systemDirs = map[string]string{
+	KindSystemd: "/etc/systemd/system",
+	KindQuadlet: "/etc/containers/systemd",
+}
+
+// SetSystemDirs makes otori use other directories for the system units
+// (tests) and returns a function restoring the previous ones
+func SetSystemDirs(systemd, quadlet string) func() {
+	previous := systemDirs
+	systemDirs = map[string]string{KindSystemd: systemd, KindQuadlet: quadlet}
+	return func() { systemDirs = previous }
+}
+
+// Unit is an otori unit file
+type Unit struct {
+	Profile string `json:"profile"`
+	Name    string `json:"unit"` // otori-<profile>.service
+	Kind    string `json:"kind"`
+	Scope   string `json:"scope"`
+	Path    string `json:"path"`
+}
+
+// Options are the parts of a unit file that do not come from the profile
+type Options struct {
+	Kind       string
+	Scope      string
+	Executable string // absolute path of otori
+	OtoriHome  string // otori directory, the profiles are read from there
+	Runtime    string // docker or podman
+	ProfileDir string // directory of the profile files (mounted by Quadlet)
+}
+
+// UnitName returns the name of the systemd unit of a profile.
+// Quadlet generates the same name from otori-<profile>.container.
+func UnitName(profile string) string {
+	return "otori-" + profile + ".service"
+}
+
+// FileName returns the name of the unit file of a profile
+func FileName(profile, kind string) string {
+	if kind == KindQuadlet {
+		return "otori-" + profile + ".container"
+	}
+	return UnitName(profile)
+}
+
+// Dir returns the directory of the unit files of a kind and scope.
+// User units live in $XDG_CONFIG_HOME (~/.config).
+func Dir(kind, scope string) (string, error) {
+	if scope == ScopeSystem {
+		return systemDirs[kind], nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("no user config directory for user units: %w", err)
+	}
+	if kind == KindQuadlet {
+		return filepath.Join(configDir, "containers", "systemd"), nil
+	}
+	return filepath.Join(configDir, "systemd", "user"), nil
+}
+
+// Systemctl returns a systemctl command for the manager of a scope
+func Systemctl(scope string, args ...string) container.Command {
+	if scope == ScopeUser {
+		args = append([]string{"--user"}, args...)
+	}
+	return container.Command{Name: "systemctl", Args: args}
+}
+
+// SystemdTemplate is the template for the systemd unit of a profile
+const SystemdTemplate = `# systemd unit for Otori honeypot
+` + headerGenerated + `
+# Profile: %s
+
+[Unit]
+Description=Otori honeypot %s (Cowrie)
+Documentation=https://github.com/otori-lab/otori-cli
+Wants=network-online.target
+After=network-online.target%s
+%s
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+Environment=%s
+Environment=%s
+ExecStart=%s deploy -p %s
+ExecReload=%s deploy -p %s
+ExecStop=%s stop -p %s
+TimeoutStartSec=300
+
+[Install]
+WantedBy=%s
+`
+
+// QuadletTemplate is the template for the Podman Quadlet file of a profile
+const QuadletTemplate = `# Podman Quadlet for Otori honeypot
+` + headerGenerated + `
+# Profile: %s
+
+[Unit]
+Description=Otori honeypot %s (Cowrie)
+Documentation=https://github.com/otori-lab/otori-cli
+Wants=network-online.target
+After=network-online.target
+
+[Container]
+ContainerName=otori-%s
+Image=%s
+Label=otori.profile=%s
+Label=otori.config-hash=%s
+Label=otori.version=%s
+Label=otori.image=%s
+PublishPort=%d:2222
+PublishPort=%d:2223
+%sVolume=otori-%s-logs:/cowrie/cowrie-git/var/log/cowrie
+Volume=otori-%s-downloads:/cowrie/cowrie-git/var/lib/cowrie/downloads
+Environment=%s
+
+[Service]
+Restart=always
+TimeoutStartSec=300
+
+[Install]
+WantedBy=%s
+`
+
+// Render returns the unit file of a profile from its effective configuration
+func Render(cfg *models.Config, opts Options) string {
+	wantedBy := "multi-user.target"
+	if opts.Scope == ScopeUser {
+		wantedBy = "default.target"
+	}
+
+	if opts.Kind == KindQuadlet {
+		name, reference := config.DeployImage(cfg)
+		mounts := ""
+		if !cfg.Baked {
+			for _, mount := range [][2]string{
+				{"cowrie.cfg", "/cowrie/cowrie-git/etc/cowrie.cfg"},
+				{"userdb.txt", "/cowrie/cowrie-git/etc/userdb.txt"},
+				{"honeyfs", "/cowrie/cowrie-git/honeyfs"},
+				{"txtcmds", "/cowrie/cowrie-git/txtcmds"},
+			} {
+				mounts += "Volume=" + filepath.Join(opts.ProfileDir, mount[0]) + ":" + mount[1] + ":ro\n"
+			}
+		}
+		return fmt.Sprintf(QuadletTemplate,
+			cfg.ProfileName,
+			cfg.ProfileName,
+			cfg.ProfileName,
+			name,
+			cfg.ProfileName,
+			config.ConfigHash(cfg),
+			version.Version,
+			reference,
+			cfg.SSHPort(),
+			cfg.TelnetPort(),
+			mounts,
+			cfg.ProfileName,
+			cfg.ProfileName,
+			quote("COWRIE_HOSTNAME="+cfg.ServerName),
+			wantedBy,
+		)
+	}
+
+	// The docker daemon is only visible to the system manager
+	after, requires := "", ""
+	if opts.Scope == ScopeSystem && opts.Runtime == "docker" {
+		after, requires = " docker.service", "Requires=docker.service\n"
+	}
+	exe := quote(opts.Executable)
+	return fmt.Sprintf(SystemdTemplate,
+		cfg.ProfileName,
+		cfg.ProfileName,
+		after,
+		requires,
+		quote("OTORI_HOME="+opts.OtoriHome),
+		quote(config.SettingEnv("runtime")+"="+opts.Runtime),
+		exe, cfg.ProfileName,
+		exe, cfg.ProfileName,
+		exe, cfg.ProfileName,
+		wantedBy,
+	)
+}
+
+// quote quotes a word of a unit file when it holds spaces
+func quote(s string) string {
+	if !strings.ContainsAny(s, " \t\"\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// List returns the otori unit files of every kind and scope, by profile
+func List() []Unit {
+	var units []Unit
+	for _, scope := range []string{ScopeSystem, ScopeUser} {
+		for _, kind := range []string{KindSystemd, KindQuadlet} {
+			dir, err := Dir(kind, scope)
+			if err != nil {
+				continue
+			}
+			ext := filepath.Ext(FileName("", kind))
+			paths, _ := filepath.Glob(filepath.Join(dir, "otori-*"+ext))
+			for _, path := range paths {
+				profile, ok := readProfile(path)
+				if !ok {
+					continue
+				}
+				units = append(units, Unit{Profile: profile, Name: UnitName(profile), Kind: kind, Scope: scope, Path: path})
+			}
+		}
+	}
+	sort.SliceStable(units, func(i, j int) bool { return units[i].Profile < units[j].Profile })
+	return units
+}
+
+// Find returns the unit files of a profile, in one scope or in both ("" scope)
+func Find(profile, scope string) []Unit {
+	var found []Unit
+	for _, unit := range List() {
+		if unit.Profile == profile && (scope == "" || unit.Scope == scope) {
+			found = append(found, unit)
+		}
+	}
+	return found
+}
+
+// readProfile returns the profile of a unit file written by otori
+func readProfile(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	generated := false
+	scanner := bufio.NewScanner(f)
+	for i := 0; i < 3 && scanner.Scan(); i++ {
+		line := scanner.Text()
+		if line == headerGenerated {
+			generated = true
+		}
+		if profile, ok := strings.CutPrefix(line, "# Profile: "); ok && generated {
+			return profile, true
+		}
+	}
+	return "", false
+}
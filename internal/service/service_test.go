@@ -0,0 +1,96 @@
+package service_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otori-lab/otori-cli/internal/models"
+	"github.com/otori-lab/otori-cli/internal/service"
+	"github.com/otori-lab/otori-cli/internal/testutil"
+)
+
+// web is the profile rendered by the golden tests
+var web = &models.Config{
+	SchemaVersion: models.CurrentSchemaVersion,
+	Type:          "classic",
+	ServerName:    "web01",
+	ProfileName:   "web",
+	Users:         []string{},
+	Ports:         &models.Ports{SSH: 2022},
+	Image:         "cowrie/cowrie:2.5.0@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+	Seed:          "5eed5eed5eed5eed",
+	CreatedAt:     "2025-03-02T10:00:00Z",
+}
+
+func TestRender(t *testing.T) {
+	baked := *web
+	baked.Baked = true
+
+	cases := map[string]struct {
+		cfg  *models.Config
+		opts service.Options
+	}{
+		"systemd-system.service":   {web, service.Options{Kind: service.KindSystemd, Scope: service.ScopeSystem, Runtime: "docker"}},
+		"systemd-user.service":     {web, service.Options{Kind: service.KindSystemd, Scope: service.ScopeUser, Runtime: "podman"}},
+		"quadlet-system.container": {web, service.Options{Kind: service.KindQuadlet, Scope: service.ScopeSystem, Runtime: "podman"}},
+		"quadlet-baked.container":  {&baked, service.Options{Kind: service.KindQuadlet, Scope: service.ScopeUser, Runtime: "podman"}},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tc.opts.Executable = "/usr/local/bin/otori"
+			tc.opts.OtoriHome = "/var/lib/otori home"
+			tc.opts.ProfileDir = "/var/lib/otori home/profiles/web"
+			testutil.Golden(t, name, []byte(service.Render(tc.cfg, tc.opts)))
+		})
+	}
+}
+
+func TestRenderQuotesHostname(t *testing.T) {
+	cfg := *web
+	cfg.ServerName = `web 01 "prod"`
+	unit := service.Render(&cfg, service.Options{Kind: service.KindQuadlet, Scope: service.ScopeUser, Runtime: "podman"})
+	if want := `Environment="COWRIE_HOSTNAME=web 01 \"prod\""` + "\n"; !strings.Contains(unit, want) {
+		t.Errorf("quadlet file without %q:\n%s", want, unit)
+	}
+}
+
+func TestList(t *testing.T) {
+	system := t.TempDir()
+	t.Cleanup(service.SetSystemDirs(system, filepath.Join(system, "containers")))
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	opts := service.Options{Kind: service.KindQuadlet, Scope: service.ScopeUser, Runtime: "podman"}
+	userDir, err := service.Dir(opts.Kind, opts.Scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(system, service.FileName("web", service.KindSystemd)):  service.Render(web, service.Options{Kind: service.KindSystemd, Scope: service.ScopeSystem}),
+		filepath.Join(userDir, service.FileName("web", service.KindQuadlet)): service.Render(web, opts),
+		// Units otori did not write are left alone
+		filepath.Join(system, "otori-other.service"): "[Unit]\nDescription=Not otori\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	units := service.List()
+	if len(units) != 2 || units[0].Scope != service.ScopeSystem || units[1].Kind != service.KindQuadlet {
+		t.Fatalf("units: %+v", units)
+	}
+	for _, unit := range units {
+		if unit.Profile != "web" || unit.Name != "otori-web.service" {
+			t.Errorf("unit: %+v", unit)
+		}
+	}
+	if found := service.Find("web", service.ScopeUser); len(found) != 1 || found[0].Path != filepath.Join(userDir, "otori-web.container") {
+		t.Errorf("user units of web: %+v", found)
+	}
+}
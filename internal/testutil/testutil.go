@@ -0,0 +1,120 @@
+// Package testutil holds the helpers shared by the tests of otori
+package testutil
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/otori-lab/otori-cli/internal/config"
+)
+
+// update rewrites the golden files instead of comparing them (make golden)
+var update = flag.Bool("update", false, "rewrite the golden files of testdata/golden")
+
+// Golden compares got with the golden file testdata/golden/<name> of the package under test
+func Golden(t testing.TB, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("missing golden file (run 'make golden'): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file (run 'make golden' if the change is intended)\n%s",
+			name, firstDifference(want, got))
+	}
+}
+
+// firstDifference describes the first line differing between two texts
+func firstDifference(want, got []byte) string {
+	wantLines := bytes.Split(want, []byte("\n"))
+	gotLines := bytes.Split(got, []byte("\n"))
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g []byte
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if !bytes.Equal(w, g) {
+			return fmt.Sprintf("line %d:\n  want: %q\n  got:  %q", i+1, w, g)
+		}
+	}
+	return "no differing line"
+}
+
+// DumpDir returns the files of a directory as a single text, each file
+// preceded by a "=== path" header, for golden comparisons of generated trees
+func DumpDir(t testing.TB, dir string) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&out, "=== %s\n", filepath.ToSlash(rel))
+		out.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			out.WriteString("\n")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+// OtoriHome makes otori use an empty temporary config directory holding
+// the base honeyfs of the repository (like 'make install') and default settings,
+// until the end of the test
+func OtoriHome(t testing.TB) string {
+	t.Helper()
+
+	home := t.TempDir()
+	if err := os.CopyFS(filepath.Join(home, "cowrie-honeyfs-base"), os.DirFS(repoPath("cowrie-honeyfs-base"))); err != nil {
+		t.Fatalf("error copying the base honeyfs: %v", err)
+	}
+
+	config.SetOtoriDir(home)
+	t.Cleanup(func() { config.SetOtoriDir("") })
+
+	// Settings of the environment running the tests don't apply
+	for _, key := range config.SettingKeys() {
+		t.Setenv(config.SettingEnv(key), "")
+	}
+	return home
+}
+
+// repoPath returns the path of a file at the root of the repository
+func repoPath(name string) string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", name)
+}
@@ -81,8 +81,15 @@ func createModel(mode string, cfg *models.Config) Model {
 		}
 	}
 
+	// Keep fields not edited by the form (template, cowrie overrides...)
+	base := models.NewConfig()
+	if cfg != nil {
+		copied := *cfg
+		base = &copied
+	}
+
 	model := Model{
-		config: models.NewConfig(),
+		config: base,
 		fields: []Field{
 			{
 				name:      "type",
@@ -481,7 +488,9 @@ func cleanUser(user string) string {
 
 // GetConfig returns the filled configuration
 func (m Model) GetConfig() *models.Config {
-	cfg := models.NewConfig()
+	copied := *m.config
+	cfg := &copied
+	cfg.Users = nil
 
 	for _, field := range m.fields {
 		switch field.name {
@@ -492,7 +501,7 @@ func (m Model) GetConfig() *models.Config {
 		case "profileName":
 			cfg.ProfileName = field.value
 			if cfg.ProfileName == "" {
-				cfg.ProfileName = "default"
+				cfg.ProfileName = config.DefaultProfile()
 			}
 		case "company":
 			cfg.Company = field.value
@@ -1,13 +1,19 @@
 package tui
 
 import (
+	"bytes"
 	"fmt"
-	"os/exec"
+	"io"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/otori-lab/otori-cli/internal/config"
+	"github.com/otori-lab/otori-cli/internal/container"
+	"github.com/otori-lab/otori-cli/internal/events"
 	"github.com/otori-lab/otori-cli/internal/ui"
 )
 
@@ -22,19 +28,50 @@ const (
 
 // Honeypot represents a honeypot instance
 type Honeypot struct {
-	Name       string         `json:"name"`
-	Profile    string         `json:"profile"`
-	Type       string         `json:"type"`
-	Status     HoneypotStatus `json:"status"`
-	Uptime     string         `json:"uptime,omitempty"`
-	LastError  string         `json:"last_error,omitempty"`
-	ServerName string         `json:"server_name"`
-	Port       int            `json:"port"`
+	Name         string         `json:"name"`
+	Profile      string         `json:"profile"`
+	Type         string         `json:"type"`
+	Status       HoneypotStatus `json:"status"`
+	Uptime       string         `json:"uptime,omitempty"`
+	LastError    string         `json:"last_error,omitempty"`
+	ServerName   string         `json:"server_name"`
+	Port         int            `json:"port"`
+	State        string         `json:"state,omitempty"`  // raw container state
+	Health       string         `json:"health,omitempty"` // container health check
+	ExitCode     int            `json:"exit_code,omitempty"`
+	RestartCount int            `json:"restart_count,omitempty"`
+	ConfigHash   string         `json:"config_hash,omitempty"` // hash of the deployed config
+	Drift        bool           `json:"drift,omitempty"`       // profile changed since deploy
+	Version      string         `json:"otori_version,omitempty"`
+	Image        string         `json:"image,omitempty"`    // image reference of the profile at deploy time
+	ImageID      string         `json:"image_id,omitempty"` // ID of the image the container runs
 }
 
+// ImageVersion returns the image of a honeypot as "repository:tag (short ID)"
+func (hp Honeypot) ImageVersion() string {
+	image := hp.Image
+	if ref, err := container.ParseImageRef(image); err == nil {
+		image = ref.Tagged()
+	}
+	if hp.ImageID == "" {
+		return image
+	}
+	return fmt.Sprintf("%s (%s)", image, container.ShortID(hp.ImageID))
+}
+
+// refreshInterval is the delay between two polls of the container runtime
+const refreshInterval = 3 * time.Second
+
 // StatusModel represents the TUI model for status display
 type StatusModel struct {
+	fetch     func() []Honeypot
+	tracker   *events.Tracker
 	honeypots []Honeypot
+	loaded    bool
+	selected  int
+	detail    bool
+	logs      string
+	message   string
 	blinkOn   bool
 	quitting  bool
 }
@@ -42,17 +79,34 @@ type StatusModel struct {
 // tickMsg is sent periodically for animations
 type tickMsg time.Time
 
-// NewStatusModel creates a new status model
-func NewStatusModel(honeypots []Honeypot) StatusModel {
+// refreshMsg is sent periodically to poll the container runtime
+type refreshMsg time.Time
+
+// honeypotsMsg carries the result of a poll
+type honeypotsMsg []Honeypot
+
+// logsMsg carries the container logs of the drill-down view
+type logsMsg string
+
+// actionDoneMsg is sent when a restart/stop/replay action finishes
+type actionDoneMsg struct {
+	text string
+	err  error
+}
+
+// NewStatusModel creates a new status model.
+// fetch is called periodically to refresh the list of honeypots.
+func NewStatusModel(fetch func() []Honeypot) StatusModel {
 	return StatusModel{
-		honeypots: honeypots,
-		blinkOn:   true,
+		fetch:   fetch,
+		tracker: events.NewTracker(),
+		blinkOn: true,
 	}
 }
 
 // Init initializes the model
 func (m StatusModel) Init() tea.Cmd {
-	return tickCmd()
+	return tea.Batch(tickCmd(), m.pollCmd())
 }
 
 // tickCmd returns a command that sends a tick every 800ms
@@ -62,24 +116,200 @@ func tickCmd() tea.Cmd {
 	})
 }
 
+// refreshCmd schedules the next poll
+func refreshCmd() tea.Cmd {
+	return tea.Tick(refreshInterval, func(t time.Time) tea.Msg {
+		return refreshMsg(t)
+	})
+}
+
+// pollCmd fetches the honeypots in the background
+func (m StatusModel) pollCmd() tea.Cmd {
+	fetch := m.fetch
+	return func() tea.Msg {
+		return honeypotsMsg(fetch())
+	}
+}
+
+// logsCmd fetches the last container log lines of a honeypot
+func logsCmd(containerName string) tea.Cmd {
+	return func() tea.Msg {
+		var output bytes.Buffer
+		cmd := container.Docker("logs", "--tail", "15", containerName)
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		_, err := container.Run(cmd)
+		if err != nil && output.Len() == 0 {
+			return logsMsg("(no container logs)")
+		}
+		return logsMsg(strings.TrimRight(output.String(), "\n"))
+	}
+}
+
 // Update handles messages
 func (m StatusModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.detail {
+			return m.updateDetail(msg)
+		}
 		switch msg.String() {
 		case "q", "ctrl+c", "esc":
 			m.quitting = true
+			m.tracker.Stop()
 			return m, tea.Quit
+		case "up", "k", "shift+tab":
+			if m.selected > 0 {
+				m.selected--
+			}
+		case "down", "j", "tab":
+			if m.selected < len(m.honeypots)-1 {
+				m.selected++
+			}
+		case "enter":
+			if hp, ok := m.current(); ok {
+				m.detail = true
+				m.logs = ""
+				m.message = ""
+				return m, logsCmd(hp.Name)
+			}
 		}
 
 	case tickMsg:
 		m.blinkOn = !m.blinkOn
 		return m, tickCmd()
+
+	case refreshMsg:
+		cmds := []tea.Cmd{m.pollCmd()}
+		if hp, ok := m.current(); ok && m.detail {
+			cmds = append(cmds, logsCmd(hp.Name))
+		}
+		return m, tea.Batch(cmds...)
+
+	case honeypotsMsg:
+		m.honeypots = msg
+		m.loaded = true
+		if m.selected >= len(m.honeypots) {
+			m.selected = len(m.honeypots) - 1
+		}
+		if m.selected < 0 {
+			m.selected = 0
+		}
+		if len(m.honeypots) == 0 {
+			m.detail = false
+		}
+		for _, hp := range m.honeypots {
+			if hp.Status == StatusActive {
+				m.tracker.Watch(hp.Profile)
+			}
+		}
+		return m, refreshCmd()
+
+	case logsMsg:
+		m.logs = string(msg)
+
+	case actionDoneMsg:
+		if msg.err != nil {
+			m.message = fmt.Sprintf("✗ %s: %v", msg.text, msg.err)
+		} else {
+			m.message = "✓ " + msg.text
+		}
+		return m, m.pollCmd()
+	}
+
+	return m, nil
+}
+
+// updateDetail handles keys in the drill-down view
+func (m StatusModel) updateDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	hp, ok := m.current()
+	if !ok {
+		m.detail = false
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		m.quitting = true
+		m.tracker.Stop()
+		return m, tea.Quit
+	case "esc", "backspace", "left", "h":
+		m.detail = false
+		m.message = ""
+	case "r":
+		m.message = "Restarting " + hp.Name + "..."
+		return m, composeCmd(hp.Profile, "restarted "+hp.Name, "restart")
+	case "s":
+		m.message = "Stopping " + hp.Name + "..."
+		return m, composeCmd(hp.Profile, "stopped "+hp.Name, "down")
+	case "p":
+		stats, _ := m.tracker.Snapshot(hp.Profile)
+		if stats.LastTTYLog == "" {
+			m.message = "✗ No recorded session to replay yet"
+			return m, nil
+		}
+		return m, replayCmd(hp.Name, stats.LastTTYLog)
 	}
 
 	return m, nil
 }
 
+// composeCmd runs a docker compose command in the profile directory,
+// under the lock of the profile like deploy and stop
+func composeCmd(profileName, text string, args ...string) tea.Cmd {
+	return func() tea.Msg {
+		lock, err := config.LockProfile(profileName)
+		if err != nil {
+			return actionDoneMsg{text: text, err: err}
+		}
+		defer lock.Unlock()
+
+		var output bytes.Buffer
+		cmd := container.Docker(append([]string{"compose"}, args...)...)
+		cmd.Dir = filepath.Join(config.GetConfigDir(), profileName)
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		if _, err := container.Run(cmd); err != nil {
+			return actionDoneMsg{text: text, err: fmt.Errorf("%v: %s", err, strings.TrimSpace(output.String()))}
+		}
+		return actionDoneMsg{text: text}
+	}
+}
+
+// replayCmd plays back a recorded session with Cowrie's playlog, suspending the TUI
+func replayCmd(containerName, ttyLog string) tea.Cmd {
+	cmd := container.Docker("exec", "-it",
+		"-e", "PYTHONPATH=/cowrie/cowrie-git/src",
+		"-w", "/cowrie/cowrie-git",
+		containerName,
+		"/cowrie/cowrie-env/bin/python3", "-m", "cowrie.scripts.playlog", ttyLog)
+	return tea.Exec(&terminalCmd{cmd: cmd}, func(err error) tea.Msg {
+		return actionDoneMsg{text: "replayed " + ttyLog, err: err}
+	})
+}
+
+// terminalCmd runs a container command on the terminal released by the TUI (tea.ExecCommand)
+type terminalCmd struct {
+	cmd container.Command
+}
+
+func (c *terminalCmd) Run() error {
+	_, err := container.Run(c.cmd)
+	return err
+}
+
+func (c *terminalCmd) SetStdin(r io.Reader)  { c.cmd.Stdin = r }
+func (c *terminalCmd) SetStdout(w io.Writer) { c.cmd.Stdout = w }
+func (c *terminalCmd) SetStderr(w io.Writer) { c.cmd.Stderr = w }
+
+// current returns the selected honeypot
+func (m StatusModel) current() (Honeypot, bool) {
+	if m.selected < 0 || m.selected >= len(m.honeypots) {
+		return Honeypot{}, false
+	}
+	return m.honeypots[m.selected], true
+}
+
 // View renders the status display
 func (m StatusModel) View() string {
 	if m.quitting {
@@ -92,6 +322,13 @@ func (m StatusModel) View() string {
 	sb.WriteString(ui.GetLogo())
 	sb.WriteString("\n")
 
+	if m.detail {
+		if hp, ok := m.current(); ok {
+			sb.WriteString(m.renderDetail(hp))
+			return sb.String()
+		}
+	}
+
 	// Title
 	titleStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("206")).
@@ -100,7 +337,13 @@ func (m StatusModel) View() string {
 	sb.WriteString(titleStyle.Render("Honeypot Status"))
 	sb.WriteString("\n\n")
 
-	if len(m.honeypots) == 0 {
+	if !m.loaded {
+		loadingStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Italic(true)
+		sb.WriteString(loadingStyle.Render("Loading..."))
+		sb.WriteString("\n")
+	} else if len(m.honeypots) == 0 {
 		noHoneypotStyle := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("240")).
 			Italic(true)
@@ -108,51 +351,51 @@ func (m StatusModel) View() string {
 		sb.WriteString("\n")
 	} else {
 		// Render each honeypot card
-		for _, hp := range m.honeypots {
-			sb.WriteString(m.renderCard(hp))
+		for i, hp := range m.honeypots {
+			sb.WriteString(m.renderCard(hp, i == m.selected))
 			sb.WriteString("\n")
 		}
 	}
 
+	if m.message != "" {
+		sb.WriteString("\n")
+		sb.WriteString(m.message)
+		sb.WriteString("\n")
+	}
+
 	// Instructions
 	sb.WriteString("\n")
 	instructionsStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240"))
-	sb.WriteString(instructionsStyle.Render("Press 'q' or ESC to quit"))
+	sb.WriteString(instructionsStyle.Render("↑↓ Select | Enter Details | q Quit"))
 	sb.WriteString("\n")
 
 	return sb.String()
 }
 
-// renderCard renders a single honeypot card
-func (m StatusModel) renderCard(hp Honeypot) string {
-	// Status indicator
-	var statusIndicator string
-	var statusColor lipgloss.Color
-	var statusText string
-
-	switch hp.Status {
+// statusDisplay returns the indicator, color and label of a status
+func (m StatusModel) statusDisplay(status HoneypotStatus) (string, lipgloss.Color, string) {
+	switch status {
 	case StatusActive:
+		indicator := "○"
 		if m.blinkOn {
-			statusIndicator = "●"
-		} else {
-			statusIndicator = "○"
+			indicator = "●"
 		}
-		statusColor = lipgloss.Color("48") // Green
-		statusText = "ACTIVE"
-	case StatusStopped:
-		statusIndicator = "●"
-		statusColor = lipgloss.Color("240") // Gray
-		statusText = "STOPPED"
+		return indicator, lipgloss.Color("48"), "ACTIVE" // Green
 	case StatusError:
+		indicator := "○"
 		if m.blinkOn {
-			statusIndicator = "●"
-		} else {
-			statusIndicator = "○"
+			indicator = "●"
 		}
-		statusColor = lipgloss.Color("196") // Red
-		statusText = "ERROR"
+		return indicator, lipgloss.Color("196"), "ERROR" // Red
+	default:
+		return "●", lipgloss.Color("240"), "STOPPED" // Gray
 	}
+}
+
+// renderCard renders a single honeypot card
+func (m StatusModel) renderCard(hp Honeypot, selected bool) string {
+	statusIndicator, statusColor, statusText := m.statusDisplay(hp.Status)
 
 	indicatorStyle := lipgloss.NewStyle().
 		Foreground(statusColor).
@@ -169,6 +412,12 @@ func (m StatusModel) renderCard(hp Honeypot) string {
 		Padding(0, 2).
 		Width(50)
 
+	if selected {
+		cardStyle = cardStyle.
+			Border(lipgloss.ThickBorder()).
+			BorderForeground(lipgloss.Color("206"))
+	}
+
 	labelStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240"))
 
@@ -204,6 +453,12 @@ func (m StatusModel) renderCard(hp Honeypot) string {
 	content.WriteString(valueStyle.Render(fmt.Sprintf("%d", hp.Port)))
 	content.WriteString("\n")
 
+	if hp.Image != "" {
+		content.WriteString(labelStyle.Render("Image:       "))
+		content.WriteString(valueStyle.Render(hp.ImageVersion()))
+		content.WriteString("\n")
+	}
+
 	if hp.Status == StatusActive && hp.Uptime != "" {
 		content.WriteString(labelStyle.Render("Uptime:      "))
 		uptimeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("48"))
@@ -218,65 +473,231 @@ func (m StatusModel) renderCard(hp Honeypot) string {
 		content.WriteString("\n")
 	}
 
+	if hp.Drift {
+		content.WriteString(labelStyle.Render("Drift:       "))
+		driftStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+		content.WriteString(driftStyle.Render("profile changed since deploy"))
+		content.WriteString("\n")
+	}
+
+	// Live counters
+	if stats, ok := m.tracker.Snapshot(hp.Profile); ok {
+		content.WriteString(labelStyle.Render("Connections: "))
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%d (%d IPs)", stats.Connections, stats.UniqueIPs)))
+		content.WriteString("\n")
+
+		content.WriteString(labelStyle.Render("Logins:      "))
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%d / %d attempts", stats.LoginSuccesses, stats.LoginAttempts)))
+		content.WriteString("\n")
+
+		content.WriteString(labelStyle.Render("Last event:  "))
+		content.WriteString(valueStyle.Render(lastEvent(stats)))
+		content.WriteString("\n")
+	}
+
 	return cardStyle.Render(content.String())
 }
 
-// GetRunningHoneypots returns real honeypot data from Docker containers
-func GetRunningHoneypots() []Honeypot {
+// renderDetail renders the drill-down view of a honeypot
+func (m StatusModel) renderDetail(hp Honeypot) string {
+	var sb strings.Builder
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("206")).
+		Bold(true)
+
+	labelStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240"))
+
+	sb.WriteString(m.renderCard(hp, false))
+	sb.WriteString("\n\n")
+
+	// Recent events
+	sb.WriteString(titleStyle.Render("Recent events"))
+	sb.WriteString("\n")
+	stats, _ := m.tracker.Snapshot(hp.Profile)
+	if len(stats.Recent) == 0 {
+		sb.WriteString(labelStyle.Render("  (no events yet)"))
+		sb.WriteString("\n")
+	} else {
+		recent := stats.Recent
+		if len(recent) > 10 {
+			recent = recent[len(recent)-10:]
+		}
+		for i := len(recent) - 1; i >= 0; i-- {
+			sb.WriteString("  ")
+			sb.WriteString(formatEvent(recent[i]))
+			sb.WriteString("\n")
+		}
+	}
+	sb.WriteString("\n")
+
+	// Container logs
+	sb.WriteString(titleStyle.Render("Container logs"))
+	sb.WriteString("\n")
+	if m.logs == "" {
+		sb.WriteString(labelStyle.Render("  Loading..."))
+		sb.WriteString("\n")
+	} else {
+		for _, line := range strings.Split(m.logs, "\n") {
+			sb.WriteString(labelStyle.Render("  " + line))
+			sb.WriteString("\n")
+		}
+	}
+
+	if m.message != "" {
+		sb.WriteString("\n")
+		sb.WriteString(m.message)
+		sb.WriteString("\n")
+	}
+
+	// Instructions
+	sb.WriteString("\n")
+	instructionsStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240"))
+	sb.WriteString(instructionsStyle.Render("r Restart | s Stop | p Replay last session | ESC Back | q Quit"))
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// lastEvent describes the most recent event of a honeypot
+func lastEvent(stats events.Stats) string {
+	if stats.LastEventAt.IsZero() {
+		return "-"
+	}
+	ago := time.Since(stats.LastEventAt).Round(time.Second)
+	return fmt.Sprintf("%s (%s ago)", strings.TrimPrefix(stats.LastEventID, "cowrie."), ago)
+}
+
+// formatEvent renders an event on a single line
+func formatEvent(ev events.Event) string {
+	detail := ""
+	switch ev.EventID {
+	case events.EventLoginSuccess, events.EventLoginFailed:
+		detail = ev.Username + ":" + ev.Password
+	case events.EventCommandInput:
+		detail = ev.Input
+	case events.EventFileDownload:
+		detail = ev.URL
+	}
+	return fmt.Sprintf("%s  %-15s  %-22s %s",
+		ev.Timestamp.Local().Format("15:04:05"),
+		ev.SrcIP,
+		strings.TrimPrefix(ev.EventID, "cowrie."),
+		detail)
+}
+
+// GetHoneypots returns every otori container (running or not) joined with its profile.
+// Containers are identified by the labels written at deploy time.
+func GetHoneypots() []Honeypot {
 	var honeypots []Honeypot
 
-	// Run docker ps to get RUNNING otori containers only (no -a flag)
-	cmd := exec.Command("docker", "ps", "--filter", "name=otori-", "--format", "{{.Names}}|{{.Status}}|{{.Ports}}")
-	output, err := cmd.Output()
+	profiles, _ := config.ListConfigs()
+	containers, err := container.ListContainers(profiles)
 	if err != nil {
 		return honeypots
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
+	for _, c := range containers {
+		honeypots = append(honeypots, honeypotFromContainer(c))
+	}
 
-		parts := strings.Split(line, "|")
-		if len(parts) < 2 {
-			continue
-		}
+	sort.Slice(honeypots, func(i, j int) bool {
+		return honeypots[i].Profile < honeypots[j].Profile
+	})
 
-		containerName := parts[0]
-		statusStr := parts[1]
-
-		// Extract profile name from container name (otori-{profile})
-		profileName := strings.TrimPrefix(containerName, "otori-")
-
-		// Determine status
-		var status HoneypotStatus
-		var uptime string
-		if strings.Contains(statusStr, "Up") {
-			status = StatusActive
-			// Extract uptime from status string (e.g., "Up 2 hours")
-			uptime = strings.TrimPrefix(statusStr, "Up ")
-		} else if strings.Contains(statusStr, "Exited") {
-			status = StatusStopped
+	return honeypots
+}
+
+// honeypotFromContainer builds the status of a container from its state and profile
+func honeypotFromContainer(c container.Container) Honeypot {
+	profileName := c.Profile()
+
+	hp := Honeypot{
+		Name:         c.Name,
+		Profile:      profileName,
+		State:        c.State,
+		Health:       c.Health,
+		ExitCode:     c.ExitCode,
+		RestartCount: c.RestartCount,
+		ConfigHash:   c.Labels[container.LabelConfigHash],
+		Version:      c.Labels[container.LabelVersion],
+		Image:        c.Image,
+		ImageID:      c.ImageID,
+		Port:         2222,
+	}
+	// The label keeps the pinned digest dropped from the compose image name
+	if image := c.Labels[container.LabelImage]; image != "" {
+		hp.Image = image
+	}
+	// The host port of SSH, not the first published one (telnet may sort first)
+	if port := c.HostPort(container.CowrieSSHPort); port != 0 {
+		hp.Port = port
+	}
+
+	// Join with the profile configuration
+	cfg, err := config.ReadEffectiveConfig(profileName)
+	if err != nil {
+		hp.Type = "unknown"
+		hp.ServerName = "-"
+		hp.LastError = "profile not found on disk"
+	} else {
+		hp.Type = cfg.Type
+		hp.ServerName = cfg.ServerName
+		hp.Drift = hp.ConfigHash != "" && hp.ConfigHash != config.ConfigHash(cfg)
+	}
+
+	switch c.State {
+	case "running":
+		hp.Status = StatusActive
+		hp.Uptime = formatUptime(time.Since(c.StartedAt))
+		if c.Health == "unhealthy" {
+			hp.Status = StatusError
+			hp.LastError = "unhealthy"
+			if c.Error != "" {
+				hp.LastError += ": " + c.Error
+			}
+		}
+	case "restarting":
+		hp.Status = StatusError
+		hp.LastError = fmt.Sprintf("restarting (exit code %d, %d restarts)", c.ExitCode, c.RestartCount)
+	case "exited", "dead":
+		if c.ExitCode == 0 && c.Error == "" && c.State == "exited" {
+			hp.Status = StatusStopped
 		} else {
-			status = StatusError
+			hp.Status = StatusError
+			hp.LastError = fmt.Sprintf("%s with code %d", c.State, c.ExitCode)
+			if c.Error != "" {
+				hp.LastError += ": " + c.Error
+			}
 		}
+	default: // created, paused
+		hp.Status = StatusStopped
+	}
 
-		// Get port (default 2222)
-		port := 2222
-
-		honeypot := Honeypot{
-			Name:       containerName,
-			Profile:    profileName,
-			Type:       "classic",
-			Status:     status,
-			Uptime:     uptime,
-			ServerName: profileName,
-			Port:       port,
-		}
+	return hp
+}
 
-		honeypots = append(honeypots, honeypot)
+// formatUptime renders a duration as "3d 4h", "2h 13m", "5m 20s"
+func formatUptime(d time.Duration) string {
+	if d < 0 {
+		return ""
+	}
+	d = d.Round(time.Second)
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	seconds := int(d.Seconds()) % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
 	}
-
-	return honeypots
 }
@@ -0,0 +1,6 @@
+package version
+
+// Version is the otori version, set at build time with:
+//
+//	go build -ldflags "-X github.com/otori-lab/otori-cli/internal/version.Version=v1.2.3"
+var Version = "dev"